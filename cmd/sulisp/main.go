@@ -0,0 +1,212 @@
+// Command sulisp runs sulisp scripts.
+//
+// Usage:
+//
+//	sulisp run [-vm] [-strict] <file> [args...]  evaluate a script, args are available as *argv*
+//	sulisp check [-all] <file>...               parse, expand macros and check types without evaluation
+//	sulisp repl                                 start an interactive session
+//
+// In the strict mode evaluation of unbound names fails instead of returning null.
+// The result of the last form of a script is printed to stdout.
+// Parsing and evaluation errors are printed to stderr and give a non-zero exit code.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/ninedraft/sulisp/interpreter/astwalk"
+	"github.com/ninedraft/sulisp/interpreter/vm"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+	"github.com/ninedraft/sulisp/lexer"
+	"github.com/ninedraft/sulisp/parser"
+	"github.com/ninedraft/sulisp/repl"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const usage = `usage:
+	sulisp run [-vm] [-strict] <file> [args...]
+	sulisp check [-all] <file>...
+	sulisp repl
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "run":
+		return runScript(args, stdout, stderr)
+	case "check":
+		return check(args, stdout, stderr)
+	case "repl":
+		if err := repl.Run(stdout, stdin, nil); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n%s", cmd, usage)
+		return exitUsage
+	}
+}
+
+func runScript(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	useVM := flags.Bool("vm", false, "evaluate the script by the bytecode VM")
+	strict := flags.Bool("strict", false, "fail on evaluation of unbound names")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	file, argv := flags.Arg(0), flags.Args()[1:]
+
+	env := astwalk.DefaultEnv().SetStrict(*strict)
+	env.Assign("*argv*", argvArray(argv))
+
+	pkg, err := load(file, env)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	eval := astwalk.Eval
+	if *useVM {
+		eval = vm.Eval
+	}
+
+	result := eval(pkg, env)
+	if err, isErr := result.(*object.Error); isErr {
+		printError(stderr, err)
+		return exitError
+	}
+
+	if _, isNull := result.(object.Null); !isNull {
+		fmt.Fprintln(stdout, result.Inspect())
+	}
+
+	return exitOK
+}
+
+func check(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	all := flags.Bool("all", false, "report all type errors instead of the first one")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	code := exitOK
+
+	for _, file := range flags.Args() {
+		env := astwalk.DefaultEnv()
+		env.Assign("*argv*", argvArray(nil))
+
+		if err := checkFile(file, env, *all); err != nil {
+			printErrors(stderr, err)
+			code = exitError
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%s: ok\n", file)
+	}
+
+	return code
+}
+
+func checkFile(file string, env *object.Env, all bool) error {
+	pkg, err := load(file, env)
+	if err != nil {
+		return err
+	}
+
+	inferencer := astwalk.NewTypeInferencer(env)
+	if all {
+		inferencer.ReportAll()
+	}
+
+	return inferencer.Check(pkg)
+}
+
+// load parses the file and expands macros.
+func load(file string, env *object.Env) (ast.Node, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lex := lexer.NewLexer(file, bufio.NewReader(f))
+
+	pkg, err := parser.New(lex).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return astwalk.Expand(pkg, env)
+}
+
+// printError prints a runtime error with the source line of its position and the call stack.
+func printError(w io.Writer, err *object.Error) {
+	var errRuntime *object.RuntimeError
+	if !errors.As(err.Err, &errRuntime) {
+		fmt.Fprintln(w, err.Err)
+		return
+	}
+
+	source, _ := os.ReadFile(errRuntime.Pos.From.File)
+	fmt.Fprint(w, errRuntime.Pretty(source))
+}
+
+// printErrors prints joined errors one by one.
+func printErrors(w io.Writer, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			printErrors(w, err)
+		}
+		return
+	}
+
+	printError(w, &object.Error{Err: err})
+}
+
+// argvArray returns script arguments as an array of strings.
+func argvArray(args []string) *object.Array {
+	argv := &object.Array{}
+
+	for _, arg := range args {
+		argv.Append(object.PrimitiveOf(strconv.Quote(arg)))
+	}
+
+	return argv
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	script := writeScript(t, `
+		; counts arguments
+		(defn total (args acc)
+			(let ([head & tail] args)
+				(if (= (array) args)
+					acc
+					(recur tail (+ acc 1)))))
+		(array (total *argv* 0) *argv*)
+	`)
+
+	for _, args := range [][]string{
+		{"run", script, "a", "b"},
+		{"run", "-vm", script, "a", "b"},
+	} {
+		stdout, stderr, code := runCmd(t, args...)
+
+		assert.Equal(t, exitOK, code, "exit code, stderr: %s", stderr)
+		assert.Equal(t, "[2, [\"a\", \"b\"]]\n", stdout, "printed result")
+	}
+}
+
+func TestRun_Error(t *testing.T) {
+	script := writeScript(t, "(/ 1 0)")
+
+	_, stderr, code := runCmd(t, "run", script)
+
+	assert.Equal(t, exitError, code, "exit code")
+	assert.Contains(t, stderr, script+":", "error position")
+	assert.Contains(t, stderr, "arithmetic-error: /: division by zero", "error message")
+	assert.Contains(t, stderr, "\n  (/ 1 0)\n  ^^^^^^^\n", "source line")
+}
+
+func TestRun_Strict(t *testing.T) {
+	script := writeScript(t, "(let (a 1) (+ a1 2))")
+
+	for _, args := range [][]string{
+		{"run", "-strict", script},
+		{"run", "-strict", "-vm", script},
+	} {
+		_, stderr, code := runCmd(t, args...)
+
+		assert.Equal(t, exitError, code, "exit code")
+		assert.Contains(t, stderr, ":1:15: undefined-symbol: ", "error position")
+		assert.Contains(t, stderr, "undefined symbol a1, did you mean a?", "error message")
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	_, stderr, code := runCmd(t, "run")
+
+	assert.Equal(t, exitUsage, code, "exit code")
+	assert.Contains(t, stderr, "usage:")
+
+	_, _, code = runCmd(t, "eval")
+	assert.Equal(t, exitUsage, code, "exit code of an unknown command")
+}
+
+func TestCheck(t *testing.T) {
+	good := writeScript(t, `(if (< 1 2) "a" "b")`)
+	bad := writeScript(t, `(if 1 "a" "b")`)
+
+	stdout, _, code := runCmd(t, "check", good)
+	assert.Equal(t, exitOK, code, "exit code")
+	assert.Equal(t, good+": ok\n", stdout)
+
+	_, stderr, code := runCmd(t, "check", good, bad)
+	assert.Equal(t, exitError, code, "exit code")
+	assert.Contains(t, stderr, "if condition is not boolean")
+}
+
+func TestCheck_All(t *testing.T) {
+	bad := writeScript(t, "(if 1 \"a\" \"b\")\n(defn f [x :- string] (+ x 1))\n")
+
+	_, stderr, code := runCmd(t, "check", bad)
+	assert.Equal(t, exitError, code, "exit code")
+	assert.Contains(t, stderr, "if condition is not boolean")
+	assert.NotContains(t, stderr, "is not a number")
+
+	_, stderr, code = runCmd(t, "check", "-all", bad)
+	assert.Equal(t, exitError, code, "exit code")
+	assert.Contains(t, stderr, bad+":1:5: type-error: if condition is not boolean")
+	assert.Contains(t, stderr, bad+":2:26: type-error: + argument 0 is not a number: "+
+		"want (or integer float64) at "+bad+":2:23, got string at "+bad+":2:26")
+}
+
+func TestRepl(t *testing.T) {
+	stdout, _, code := runCmdInput(t, "(+ 1 2)\n:q\n", "repl")
+
+	assert.Equal(t, exitOK, code, "exit code")
+	assert.Contains(t, stdout, "3")
+	assert.Contains(t, stdout, "bye!")
+}
+
+func writeScript(t *testing.T, code string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "script.sl")
+	if err := os.WriteFile(file, []byte(code), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func runCmd(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	return runCmdInput(t, "", args...)
+}
+
+func runCmdInput(t *testing.T, input string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	code = run(args, strings.NewReader(input), out, errOut)
+
+	return out.String(), errOut.String(), code
+}
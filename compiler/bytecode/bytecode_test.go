@@ -0,0 +1,94 @@
+package bytecode_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ninedraft/sulisp/compiler/bytecode"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/lexer"
+	"github.com/ninedraft/sulisp/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMake(t *testing.T) {
+	ins := bytecode.Make(bytecode.OpLogical, 1, 2, 65534)
+	require.Equal(t, []byte{byte(bytecode.OpLogical), 1, 0, 2, 255, 254}, ins)
+
+	def, err := bytecode.Lookup(bytecode.OpLogical)
+	require.NoError(t, err)
+
+	operands, width := bytecode.ReadOperands(def, ins[1:])
+	require.Equal(t, []int{1, 2, 65534}, operands)
+	require.Equal(t, 5, width)
+}
+
+func TestCompile(t *testing.T) {
+	program := compile(t, `
+		(defn inc (x) (+ x 1))
+		(if (inc 1) "a")
+	`)
+
+	require.Equal(t, `0000 closure 0
+0003 dup
+0004 set-global 0
+0007 pop
+0008 get-global 0
+0011 apply 0 22
+0016 constant 1
+0019 call 1
+0022 jump-if-false 31
+0025 constant 2
+0028 jump 32
+0031 null
+0032 return
+`, program.Main.Instructions.String())
+
+	require.Equal(t, `0000 get-local 0
+0003 constant 0
+0006 arithmetic 0 2
+0010 return
+`, program.Functions[0].Instructions.String())
+}
+
+func TestCompile_Locals(t *testing.T) {
+	program := compile(t, `
+		(let (x 1)
+			(loop (i x)
+				(if (< i 10) (recur (+ i 1)) i)))
+	`)
+
+	main := program.Main
+	require.Equal(t, 2, main.NumLocals, "let and loop bindings must get own slots")
+
+	i := strings.Index(main.Instructions.String(), "jump 18")
+	require.Positive(t, i, "recur must jump to the start of the loop body:\n%s", main.Instructions)
+}
+
+func TestCompile_Forms(t *testing.T) {
+	program := compile(t, "(fn (x) `(a ~x))")
+
+	require.Len(t, program.Forms, 1)
+
+	form := program.Forms[0]
+	require.Equal(t, "quasiquote", form.Node.Name())
+	require.Equal(t, []bytecode.Local{{Name: "x", Depth: 0, Slot: 0}}, form.Locals)
+}
+
+func compile(t *testing.T, input string) *bytecode.Program {
+	t.Helper()
+
+	program, err := bytecode.Compile(parse(t, input))
+	require.NoError(t, err)
+
+	return program
+}
+
+func parse(t *testing.T, input string) ast.Node {
+	t.Helper()
+
+	pkg, err := parser.New(lexer.NewLexer(t.Name(), strings.NewReader(input))).Parse()
+	require.NoError(t, err)
+
+	return pkg
+}
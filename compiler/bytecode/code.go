@@ -0,0 +1,168 @@
+// Package bytecode lowers sulisp packages to a compact bytecode, which is executed by the vm package.
+//
+// An instruction is an operation code followed by big-endian operands.
+// Functions have their own instructions and local slots,
+// constants, global names, nested functions and forms are shared by the whole program.
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Op is an operation code.
+type Op byte
+
+const (
+	OpConstant    Op = iota // index: push a constant
+	OpNull                  // push null
+	OpTrue                  // push true
+	OpFalse                 // push false
+	OpPop                   // drop the top value
+	OpDup                   // duplicate the top value
+	OpGetGlobal             // name: push a value from the env, missing names are null
+	OpSetGlobal             // name: pop a value and assign it in the env
+	OpGetLocal              // slot: push a local of the current frame
+	OpSetLocal              // slot: pop a value into a local of the current frame, closures capturing it see the value
+	OpEnter                 // block: give locals of the block new cells, closures keep the previous ones
+	OpGetOuter              // depth, slot: push a local of an enclosing function
+	OpJump                  // address: jump unconditionally
+	OpJumpIfFalse           // address: pop a boolean and jump if it's false
+	OpLogical               // stop, index, address: pop an operand of and/or, push it and jump if it's equal to stop
+	OpArithmetic            // operator, count: pop operands and push the result of the operator
+	OpArray                 // count: pop values and push an array
+	OpMap                   // count: pop keys and values and push a map
+	OpUnpack                // count, rest: pop an array and push its elements in reverse order
+	OpClosure               // function: push a closure of a nested function
+	OpApply                 // form, address: if the top value is not a function, apply it to arguments of the form and jump
+	OpCall                  // count: pop arguments and a function, push the result
+	OpReturn                // return the top value from the current function
+	OpEval                  // form: push the result of the form evaluated by the tree-walking interpreter
+)
+
+// Operators of OpArithmetic.
+var Operators = []string{"+", "-", "*", "/", "mod", "rem"}
+
+// Definition describes an operation: its name and widths of operands in bytes.
+type Definition struct {
+	Name   string
+	Widths []int
+}
+
+var definitions = map[Op]*Definition{
+	OpConstant:    {"constant", []int{2}},
+	OpNull:        {"null", nil},
+	OpTrue:        {"true", nil},
+	OpFalse:       {"false", nil},
+	OpPop:         {"pop", nil},
+	OpDup:         {"dup", nil},
+	OpGetGlobal:   {"get-global", []int{2}},
+	OpSetGlobal:   {"set-global", []int{2}},
+	OpGetLocal:    {"get-local", []int{2}},
+	OpSetLocal:    {"set-local", []int{2}},
+	OpEnter:       {"enter", []int{2}},
+	OpGetOuter:    {"get-outer", []int{1, 2}},
+	OpJump:        {"jump", []int{2}},
+	OpJumpIfFalse: {"jump-if-false", []int{2}},
+	OpLogical:     {"logical", []int{1, 2, 2}},
+	OpArithmetic:  {"arithmetic", []int{1, 2}},
+	OpArray:       {"array", []int{2}},
+	OpMap:         {"map", []int{2}},
+	OpUnpack:      {"unpack", []int{2, 1}},
+	OpClosure:     {"closure", []int{2}},
+	OpApply:       {"apply", []int{2, 2}},
+	OpCall:        {"call", []int{2}},
+	OpReturn:      {"return", nil},
+	OpEval:        {"eval", []int{2}},
+}
+
+// Lookup returns a definition of the operation.
+func Lookup(op Op) (*Definition, error) {
+	def, ok := definitions[op]
+	if !ok {
+		return nil, fmt.Errorf("unknown operation %d", op)
+	}
+	return def, nil
+}
+
+// Instructions is an encoded sequence of instructions.
+type Instructions []byte
+
+// Make encodes an instruction.
+func Make(op Op, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return nil
+	}
+
+	size := 1
+	for _, width := range def.Widths {
+		size += width
+	}
+
+	instruction := make([]byte, 1, size)
+	instruction[0] = byte(op)
+
+	for i, operand := range operands {
+		switch def.Widths[i] {
+		case 1:
+			instruction = append(instruction, byte(operand))
+		case 2:
+			instruction = binary.BigEndian.AppendUint16(instruction, uint16(operand))
+		}
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes operands of the instruction and returns them with their total width.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.Widths))
+	offset := 0
+
+	for i, width := range def.Widths {
+		switch width {
+		case 1:
+			operands[i] = int(ins[offset])
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// String disassembles instructions, one per line:
+//
+//	0000 constant 1
+//	0003 jump-if-false 12
+func (ins Instructions) String() string {
+	str := &strings.Builder{}
+
+	for i := 0; i < len(ins); {
+		def, err := Lookup(Op(ins[i]))
+		if err != nil {
+			fmt.Fprintf(str, "%04d error: %s\n", i, err)
+			i++
+			continue
+		}
+
+		operands, width := ReadOperands(def, ins[i+1:])
+
+		fmt.Fprintf(str, "%04d %s", i, def.Name)
+		for _, operand := range operands {
+			fmt.Fprintf(str, " %d", operand)
+		}
+		str.WriteString("\n")
+
+		i += 1 + width
+	}
+
+	return str.String()
+}
@@ -0,0 +1,617 @@
+package bytecode
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ninedraft/sulisp/internal/seq"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// Compile lowers a node, usually a package, to a program.
+// Forms without bytecode counterparts, like quasiquotes, macro definitions and method calls,
+// are compiled to OpEval and evaluated by the tree-walking interpreter.
+func Compile(node ast.Node) (*Program, error) {
+	c := &compiler{
+		program: &Program{},
+		names:   map[string]int{},
+	}
+
+	c.fn = &funcState{fn: &Function{}}
+	c.program.Main = c.fn.fn
+
+	if pkg, isPkg := node.(*ast.Package); isPkg {
+		c.body(pkg.PosRange, pkg.Nodes)
+	} else {
+		c.compile(node)
+	}
+	c.emit(node.Pos(), OpReturn)
+
+	if len(c.errs) > 0 {
+		return nil, errors.Join(c.errs...)
+	}
+
+	return c.program, nil
+}
+
+type compiler struct {
+	program *Program
+	fn      *funcState
+	names   map[string]int
+	errs    []error
+}
+
+// funcState is a function being compiled.
+// Its top level is the main function, where names are assigned to globals.
+type funcState struct {
+	parent  *funcState
+	fn      *Function
+	blocks  []*block
+	targets []*recurTarget
+}
+
+// block is a lexical scope of locals: function parameters, let and loop bindings.
+// Every evaluation of a block binds its locals anew, like the interpreter creates a new env.
+type block struct {
+	slots map[string]int
+	order []string
+	index int // in Blocks of the function
+}
+
+func (c *compiler) newBlock() *block {
+	fn := c.fn.fn
+	fn.Blocks = append(fn.Blocks, nil)
+
+	return &block{slots: map[string]int{}, index: len(fn.Blocks) - 1}
+}
+
+// recurTarget is the innermost loop or fn, which a recur jumps to.
+type recurTarget struct {
+	name     string // fn name for arity errors, empty for loops
+	start    int
+	block    *block
+	bindings []ast.Node // loop binding targets or fn parameters
+	rest     *ast.Symbol
+}
+
+func (c *compiler) errorf(node ast.Node, msg string, args ...any) {
+	pos := node.Pos()
+	c.errs = append(c.errs, fmt.Errorf("%s-%s: "+msg, append([]any{pos.From, pos.To}, args...)...))
+}
+
+// emit appends an instruction to the current function and returns its offset.
+func (c *compiler) emit(pos ast.PosRange, op Op, operands ...int) int {
+	fn := c.fn.fn
+	offset := len(fn.Instructions)
+
+	if n := len(fn.Positions); n == 0 || fn.Positions[n-1].Pos != pos {
+		fn.Positions = append(fn.Positions, Position{Offset: offset, Pos: pos})
+	}
+
+	fn.Instructions = append(fn.Instructions, Make(op, operands...)...)
+	return offset
+}
+
+// patch replaces a 2 bytes operand at the offset with the current end of instructions.
+func (c *compiler) patch(offset int) {
+	ins := c.fn.fn.Instructions
+	copy(ins[offset:], Make(OpJump, len(ins))[1:])
+}
+
+func (c *compiler) here() int {
+	return len(c.fn.fn.Instructions)
+}
+
+func (c *compiler) constant(obj object.Object) int {
+	c.program.Constants = append(c.program.Constants, obj)
+	return len(c.program.Constants) - 1
+}
+
+func (c *compiler) name(name string) int {
+	i, ok := c.names[name]
+	if !ok {
+		i = len(c.program.Names)
+		c.program.Names = append(c.program.Names, name)
+		c.names[name] = i
+	}
+	return i
+}
+
+// compile emits instructions, which push exactly one value.
+func (c *compiler) compile(node ast.Node) {
+	pos := node.Pos()
+
+	switch node := node.(type) {
+	case *ast.Literal[int64]:
+		c.emit(pos, OpConstant, c.constant(object.PrimitiveOf(node.Value)))
+	case *ast.Literal[float64]:
+		c.emit(pos, OpConstant, c.constant(object.PrimitiveOf(node.Value)))
+	case *ast.Literal[string]:
+		c.emit(pos, OpConstant, c.constant(object.PrimitiveOf(node.Value)))
+	case *ast.Literal[bool]:
+		if node.Value {
+			c.emit(pos, OpTrue)
+		} else {
+			c.emit(pos, OpFalse)
+		}
+	case *ast.Keyword:
+		c.emit(pos, OpConstant, c.constant(object.Keyword(node.Value)))
+	case *ast.Symbol:
+		c.symbol(node)
+	case *ast.Vector:
+		for _, item := range node.Items {
+			c.compile(item)
+		}
+		c.emit(pos, OpArray, len(node.Items))
+	case *ast.Map:
+		for _, item := range node.Items {
+			c.compile(item)
+		}
+		c.emit(pos, OpMap, len(node.Items))
+	case *ast.Quote:
+		c.emit(pos, OpConstant, c.constant(&object.AST{Node: node.Node}))
+	case *ast.If:
+		c.ifElse(node)
+	case *ast.SpecialOp:
+		c.specialOp(node)
+	case *ast.Do:
+		c.body(pos, node.Body)
+	case *ast.Fn:
+		c.emit(pos, OpClosure, c.function(node))
+	case *ast.Defn:
+		c.emit(pos, OpClosure, c.function(node.Fn))
+		c.emit(pos, OpDup)
+		c.declare(pos, node.Fn.Ident.Value)
+	case *ast.Let:
+		c.let(node)
+	case *ast.Loop:
+		c.loop(node)
+	case *ast.Recur:
+		c.recur(node)
+	case *ast.Return:
+		c.ret(node)
+	case *ast.While:
+		c.while(node)
+	case *ast.SExp:
+		c.sexp(node)
+	default:
+		c.emit(pos, OpEval, c.form(node))
+	}
+}
+
+// body emits nodes one by one, keeping only the last value.
+func (c *compiler) body(pos ast.PosRange, nodes []ast.Node) {
+	if len(nodes) == 0 {
+		c.emit(pos, OpNull)
+		return
+	}
+
+	for i, node := range nodes {
+		if i > 0 {
+			c.emit(pos, OpPop)
+		}
+		c.compile(node)
+	}
+}
+
+func (c *compiler) symbol(symbol *ast.Symbol) {
+	local, ok := c.resolve(symbol.Value)
+
+	switch {
+	case !ok:
+		offset := c.emit(symbol.Pos(), OpGetGlobal, c.name(symbol.Value))
+		if locals := c.visible(); len(locals) > 0 {
+			if c.fn.fn.Scopes == nil {
+				c.fn.fn.Scopes = map[int][]Local{}
+			}
+			c.fn.fn.Scopes[offset] = locals
+		}
+	case local.Depth == 0:
+		c.emit(symbol.Pos(), OpGetLocal, local.Slot)
+	default:
+		c.emit(symbol.Pos(), OpGetOuter, local.Depth, local.Slot)
+	}
+}
+
+// resolve finds a local in blocks of the current function and enclosing ones.
+func (c *compiler) resolve(name string) (Local, bool) {
+	depth := 0
+	for fn := c.fn; fn != nil; fn = fn.parent {
+		for _, b := range slices.Backward(fn.blocks) {
+			if slot, ok := b.slots[name]; ok {
+				return Local{Name: name, Depth: depth, Slot: slot}, true
+			}
+		}
+		depth++
+	}
+
+	return Local{}, false
+}
+
+// declare pops a value into a name of the current block.
+// Names are assigned to globals at the top level of the main function.
+// Locals of the current function are assigned in place, so closures capturing them see new values.
+func (c *compiler) declare(pos ast.PosRange, name string) {
+	if len(c.fn.blocks) == 0 {
+		c.emit(pos, OpSetGlobal, c.name(name))
+		return
+	}
+
+	if local, ok := c.resolve(name); ok && local.Depth == 0 {
+		c.emit(pos, OpSetLocal, local.Slot)
+		return
+	}
+
+	c.emit(pos, OpSetLocal, c.slot(c.fn.blocks[len(c.fn.blocks)-1], name))
+}
+
+// slot returns a slot of the name in the block, allocating a new one if needed.
+func (c *compiler) slot(b *block, name string) int {
+	slot, ok := b.slots[name]
+	if !ok {
+		fn := c.fn.fn
+		slot = fn.NumLocals
+		fn.NumLocals++
+		fn.Blocks[b.index] = append(fn.Blocks[b.index], slot)
+		b.slots[name] = slot
+		b.order = append(b.order, name)
+	}
+	return slot
+}
+
+// bind pops a value into a binding target, destructuring arrays.
+// Names are declared in the block or in the current scope if the block is nil.
+func (c *compiler) bind(target ast.Node, b *block) {
+	switch target := target.(type) {
+	case *ast.Symbol:
+		if b == nil {
+			c.declare(target.Pos(), target.Value)
+			return
+		}
+		c.emit(target.Pos(), OpSetLocal, c.slot(b, target.Value))
+	case *ast.ArrayPattern:
+		hasRest := 0
+		if target.Rest != nil {
+			hasRest = 1
+		}
+
+		c.emit(target.Pos(), OpUnpack, len(target.Items), hasRest)
+
+		for _, item := range target.Items {
+			c.bind(item, b)
+		}
+
+		if target.Rest != nil {
+			c.bind(target.Rest, b)
+		}
+	default:
+		c.errorf(target, "unexpected binding target %s", target.Name())
+	}
+}
+
+func (c *compiler) ifElse(node *ast.If) {
+	pos := node.Pos()
+
+	c.compile(node.Cond)
+	toElse := c.emit(pos, OpJumpIfFalse, 0)
+
+	c.compile(node.Then)
+	toEnd := c.emit(pos, OpJump, 0)
+
+	c.patch(toElse + 1)
+	if node.Else != nil {
+		c.compile(node.Else)
+	} else {
+		c.emit(pos, OpNull)
+	}
+
+	c.patch(toEnd + 1)
+}
+
+func (c *compiler) specialOp(op *ast.SpecialOp) {
+	if op.Op == "and" || op.Op == "or" {
+		c.logical(op)
+		return
+	}
+
+	operator := slices.Index(Operators, op.Op)
+	if operator < 0 {
+		c.errorf(op, "unexpected operation %q", op.Op)
+		return
+	}
+
+	for _, item := range op.Items {
+		c.compile(item)
+	}
+
+	c.emit(op.Pos(), OpArithmetic, operator, len(op.Items))
+}
+
+// logical emits a short-circuit chain: (and a b) stops at the first false, (or a b) at the first true.
+func (c *compiler) logical(op *ast.SpecialOp) {
+	stop := 0
+	if op.Op == "or" {
+		stop = 1
+	}
+
+	var jumps []int
+	for i, item := range op.Items {
+		c.compile(item)
+		jumps = append(jumps, c.emit(item.Pos(), OpLogical, stop, i, 0))
+	}
+
+	if stop == 1 {
+		c.emit(op.Pos(), OpFalse)
+	} else {
+		c.emit(op.Pos(), OpTrue)
+	}
+
+	for _, jump := range jumps {
+		c.patch(jump + 4)
+	}
+}
+
+// function compiles a nested function and returns its index.
+func (c *compiler) function(node *ast.Fn) int {
+	fn := &Function{
+		Node:      node,
+		NumParams: len(node.Params),
+		HasRest:   node.Rest != nil,
+		HasSelf:   node.Ident != nil,
+	}
+
+	if node.Ident != nil {
+		fn.Name = node.Ident.Value
+	}
+
+	state := &funcState{parent: c.fn, fn: fn}
+	c.fn = state
+
+	params := c.newBlock()
+	state.blocks = append(state.blocks, params)
+
+	bindings := make([]ast.Node, 0, len(node.Params))
+	for _, param := range node.Params {
+		c.slot(params, param.Value)
+		bindings = append(bindings, param)
+	}
+
+	if node.Rest != nil {
+		c.slot(params, node.Rest.Value)
+	}
+
+	if node.Ident != nil {
+		// parameters shadow the name of the function
+		self := c.newBlock()
+		c.slot(self, node.Ident.Value)
+		state.blocks = append([]*block{self}, state.blocks...)
+	}
+
+	state.targets = append(state.targets, &recurTarget{
+		name:     fnName(fn),
+		block:    params,
+		bindings: bindings,
+		rest:     node.Rest,
+	})
+
+	c.body(node.Pos(), node.Body)
+	c.emit(node.Pos(), OpReturn)
+
+	c.fn = state.parent
+
+	c.program.Functions = append(c.program.Functions, fn)
+	return len(c.program.Functions) - 1
+}
+
+func fnName(fn *Function) string {
+	if fn.Name == "" {
+		return "anonymous function"
+	}
+	return fn.Name
+}
+
+// let binds values sequentially in a new block, so each binding can refer to the previous ones.
+func (c *compiler) let(let *ast.Let) {
+	b := c.newBlock()
+	c.fn.blocks = append(c.fn.blocks, b)
+	c.emit(let.Pos(), OpEnter, b.index)
+
+	for _, binding := range let.Bindings {
+		c.compile(binding.Value)
+		c.bind(binding.Target, b)
+	}
+
+	c.body(let.Pos(), let.Body)
+
+	c.fn.blocks = c.fn.blocks[:len(c.fn.blocks)-1]
+}
+
+func (c *compiler) loop(loop *ast.Loop) {
+	b := c.newBlock()
+	c.fn.blocks = append(c.fn.blocks, b)
+	c.emit(loop.Pos(), OpEnter, b.index)
+
+	targets := make([]ast.Node, 0, len(loop.Bindings))
+	for _, binding := range loop.Bindings {
+		c.compile(binding.Value)
+		c.bind(binding.Target, b)
+		targets = append(targets, binding.Target)
+	}
+
+	c.fn.targets = append(c.fn.targets, &recurTarget{
+		start:    c.here(),
+		block:    b,
+		bindings: targets,
+	})
+
+	c.body(loop.Pos(), loop.Body)
+
+	c.fn.targets = c.fn.targets[:len(c.fn.targets)-1]
+	c.fn.blocks = c.fn.blocks[:len(c.fn.blocks)-1]
+}
+
+// recur rebinds the innermost loop or fn and jumps to its start.
+// The parser guarantees that recur is in a tail position, so the stack has no pending values.
+// ret leaves the current function. The parser guarantees that return is in a body position,
+// so the value of the form is never used.
+func (c *compiler) ret(ret *ast.Return) {
+	if c.fn.parent == nil {
+		c.errorf(ret, "return outside of fn")
+		return
+	}
+
+	if ret.Value == nil {
+		c.emit(ret.Pos(), OpNull)
+	} else {
+		c.compile(ret.Value)
+	}
+
+	c.emit(ret.Pos(), OpReturn)
+}
+
+func (c *compiler) recur(recur *ast.Recur) {
+	if len(c.fn.targets) == 0 {
+		c.errorf(recur, "recur outside of loop or fn")
+		return
+	}
+
+	target := c.fn.targets[len(c.fn.targets)-1]
+	want := len(target.bindings)
+
+	switch {
+	case target.name == "" && len(recur.Args) != want:
+		c.errorf(recur, "recur expects %d arguments, got %d", want, len(recur.Args))
+		return
+	case target.name != "" && target.rest == nil && len(recur.Args) != want:
+		c.errorf(recur, "%s expects %d arguments, got %d", target.name, want, len(recur.Args))
+		return
+	case target.rest != nil && len(recur.Args) < want:
+		c.errorf(recur, "%s expects at least %d arguments, got %d", target.name, want, len(recur.Args))
+		return
+	}
+
+	for _, arg := range recur.Args {
+		c.compile(arg)
+	}
+
+	// closures created by the previous iteration keep its bindings
+	c.emit(recur.Pos(), OpEnter, target.block.index)
+
+	if target.rest != nil {
+		c.emit(recur.Pos(), OpArray, len(recur.Args)-want)
+		c.bind(target.rest, target.block)
+	}
+
+	for _, binding := range slices.Backward(target.bindings) {
+		c.bind(binding, target.block)
+	}
+
+	c.emit(recur.Pos(), OpJump, target.start)
+}
+
+func (c *compiler) while(while *ast.While) {
+	pos := while.Pos()
+	start := c.here()
+
+	c.compile(while.Cond)
+	toEnd := c.emit(while.Cond.Pos(), OpJumpIfFalse, 0)
+
+	c.body(pos, while.Body)
+	c.emit(pos, OpPop)
+	c.emit(pos, OpJump, start)
+
+	c.patch(toEnd + 1)
+	c.emit(pos, OpNull)
+}
+
+// sexp emits a call. The head decides how arguments are evaluated at runtime:
+// functions get compiled arguments, builtins and other values get forms by OpApply.
+func (c *compiler) sexp(sexp *ast.SExp) {
+	pos := sexp.Pos()
+
+	if len(sexp.Items) == 0 {
+		c.emit(pos, OpEval, c.form(sexp))
+		return
+	}
+
+	if head, ok := sexp.Items[0].(*ast.Symbol); ok {
+		_, isLocal := c.resolve(head.Value)
+
+		switch {
+		case isLocal:
+		case head.Value == "assign" && len(sexp.Items)%2 == 1:
+			c.assign(sexp)
+			return
+		case strings.HasPrefix(head.Value, ".") && len(head.Value) > 1:
+			// method calls are resolved by the interpreter
+			c.emit(pos, OpEval, c.form(sexp))
+			return
+		}
+	}
+
+	args := sexp.Items[1:]
+
+	c.compile(sexp.Items[0])
+	skip := c.emit(pos, OpApply, c.form(sexp), 0)
+
+	for _, arg := range args {
+		c.compile(arg)
+	}
+
+	c.emit(pos, OpCall, len(args))
+	c.patch(skip + 3)
+}
+
+// assign declares names in the current scope and pushes an array of assigned values.
+func (c *compiler) assign(sexp *ast.SExp) {
+	args := sexp.Items[1:]
+
+	for target := range seq.SlicePairs(args) {
+		if _, isSymbol := target.(*ast.Symbol); !isSymbol {
+			// the interpreter reports the error
+			c.emit(sexp.Pos(), OpEval, c.form(sexp))
+			return
+		}
+	}
+
+	for name, value := range seq.SlicePairs(args) {
+		c.compile(value)
+		c.emit(value.Pos(), OpDup)
+		c.declare(name.Pos(), name.(*ast.Symbol).Value)
+	}
+
+	c.emit(sexp.Pos(), OpArray, len(args)/2)
+}
+
+// form registers a node evaluated by the interpreter with all visible locals.
+func (c *compiler) form(node ast.Node) int {
+	form := &Form{Node: node, Locals: c.visible()}
+
+	c.program.Forms = append(c.program.Forms, form)
+	return len(c.program.Forms) - 1
+}
+
+// visible returns locals visible in the current block, inner ones shadow outer ones.
+func (c *compiler) visible() []Local {
+	var locals []Local
+	seen := map[string]bool{}
+
+	depth := 0
+	for fn := c.fn; fn != nil; fn = fn.parent {
+		for _, b := range slices.Backward(fn.blocks) {
+			for _, name := range b.order {
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				locals = append(locals, Local{Name: name, Depth: depth, Slot: b.slots[name]})
+			}
+		}
+		depth++
+	}
+
+	return locals
+}
@@ -0,0 +1,99 @@
+package bytecode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// Program is a compiled package.
+type Program struct {
+	Main      *Function
+	Constants []object.Object
+	Names     []string // names of globals
+	Functions []*Function
+	Forms     []*Form
+}
+
+// Function is a compiled function body.
+// Parameters occupy the first local slots, followed by the rest parameter
+// and the name of the function, if it has them.
+type Function struct {
+	Name         string // optional
+	Node         *ast.Fn
+	NumParams    int
+	HasRest      bool
+	HasSelf      bool // the function is bound to its name in the SelfSlot
+	NumLocals    int
+	Blocks       [][]int // slots of let, loop and parameters blocks, they get new cells on OpEnter
+	Instructions Instructions
+	Positions    []Position // sorted by offsets
+
+	// Scopes are locals visible at offsets of OpGetGlobal inside blocks,
+	// they're suggested for unbound names in the strict mode.
+	Scopes map[int][]Local
+}
+
+// Position maps instructions starting from the offset to a source range.
+type Position struct {
+	Offset int
+	Pos    ast.PosRange
+}
+
+// SelfSlot returns a slot of the function name.
+func (fn *Function) SelfSlot() int {
+	slot := fn.NumParams
+	if fn.HasRest {
+		slot++
+	}
+	return slot
+}
+
+// PosAt returns a source range of the instruction at the offset.
+func (fn *Function) PosAt(offset int) ast.PosRange {
+	i := sort.Search(len(fn.Positions), func(i int) bool {
+		return fn.Positions[i].Offset > offset
+	})
+
+	if i == 0 {
+		return ast.PosRange{}
+	}
+
+	return fn.Positions[i-1].Pos
+}
+
+// Form is a node, which is evaluated by the tree-walking interpreter.
+// Values of visible locals are bound in a child of the env.
+type Form struct {
+	Node   ast.Node
+	Locals []Local
+}
+
+// Local is a reference to a local slot of the current function or an enclosing one.
+type Local struct {
+	Name  string
+	Depth int // 0 is the current function
+	Slot  int
+}
+
+// String disassembles the program.
+func (program *Program) String() string {
+	str := &strings.Builder{}
+
+	str.WriteString("main:\n")
+	str.WriteString(program.Main.Instructions.String())
+
+	for i, fn := range program.Functions {
+		name := fn.Name
+		if name == "" {
+			name = "anonymous"
+		}
+		fmt.Fprintf(str, "function %d %s:\n", i, name)
+		str.WriteString(fn.Instructions.String())
+	}
+
+	return str.String()
+}
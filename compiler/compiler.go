@@ -1 +1,684 @@
+// Package compiler translates sulisp packages to Go source code.
+//
+// Generated code is dynamically typed: all values have type any,
+// arithmetic, comparisons and calls of unknown values are done by the rt package.
+// Top level forms are evaluated in order by the run function,
+// and main prints the result of the last one.
 package compiler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	goast "go/ast"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ninedraft/sulisp/internal/seq"
+	"github.com/ninedraft/sulisp/language/ast"
+)
+
+// RuntimePath is an import path of the runtime support library of generated code.
+const RuntimePath = "github.com/ninedraft/sulisp/compiler/rt"
+
+var errUnsupported = errors.New("unsupported form")
+
+// Compile writes a Go main package, which evaluates the sulisp package.
+func Compile(dst io.Writer, pkg *ast.Package) error {
+	file, err := Transpile(pkg)
+	if err != nil {
+		return err
+	}
+
+	// generated nodes have no positions, so declarations are separated manually
+	decls := file.Decls
+	file.Decls = decls[:1]
+
+	buf := &bytes.Buffer{}
+	fset := token.NewFileSet()
+
+	if err := printer.Fprint(buf, fset, file); err != nil {
+		return fmt.Errorf("printing Go code: %w", err)
+	}
+
+	for _, decl := range decls[1:] {
+		buf.WriteString("\n\n")
+		if err := printer.Fprint(buf, fset, decl); err != nil {
+			return fmt.Errorf("printing Go code: %w", err)
+		}
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting Go code: %w", err)
+	}
+
+	_, err = dst.Write(formatted)
+	return err
+}
+
+// Transpile translates the sulisp package to a Go file.
+func Transpile(pkg *ast.Package) (*goast.File, error) {
+	c := &compiler{
+		scope:  newScope(nil),
+		idents: map[string]string{},
+		taken:  map[string]bool{},
+	}
+
+	run := &goast.FuncDecl{
+		Name: goast.NewIdent("run"),
+		Type: &goast.FuncType{
+			Params:  &goast.FieldList{},
+			Results: anyResult(),
+		},
+		Body: &goast.BlockStmt{List: c.body(pkg.Nodes)},
+	}
+
+	if len(c.errs) > 0 {
+		return nil, errors.Join(c.errs...)
+	}
+
+	main := &goast.FuncDecl{
+		Name: goast.NewIdent("main"),
+		Type: &goast.FuncType{Params: &goast.FieldList{}},
+		Body: &goast.BlockStmt{List: []goast.Stmt{
+			&goast.ExprStmt{X: call(selector("fmt", "Println"), call(goast.NewIdent("run")))},
+		}},
+	}
+
+	imports := &goast.GenDecl{
+		Tok:   token.IMPORT,
+		Specs: []goast.Spec{importSpec("fmt")},
+	}
+
+	if c.usesRuntime {
+		imports.Lparen = 1
+		imports.Specs = append(imports.Specs, importSpec(RuntimePath))
+	}
+
+	return &goast.File{
+		Name:  goast.NewIdent("main"),
+		Decls: []goast.Decl{imports, main, run},
+	}, nil
+}
+
+type compiler struct {
+	scope       *scope
+	usesRuntime bool
+	errs        []error
+
+	idents map[string]string // Go identifiers of sulisp names
+	taken  map[string]bool   // Go identifiers given to names
+}
+
+// binding is a name declared in generated code.
+type binding struct {
+	ident string
+	fn    *ast.Fn // set for functions defined by defn, which can be called directly
+}
+
+type scope struct {
+	parent *scope
+	names  map[string]*binding
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		parent: parent,
+		names:  map[string]*binding{},
+	}
+}
+
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.parent {
+		if b, ok := s.names[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// ident returns the Go identifier of the name.
+// Names converted to the same identifier, like a-b and a_b, get numbered ones.
+func (c *compiler) ident(name string) string {
+	if ident, ok := c.idents[name]; ok {
+		return ident
+	}
+
+	base := goIdent(name)
+	ident := base
+	for i := 1; c.taken[ident]; i++ {
+		ident = base + "_" + strconv.Itoa(i)
+	}
+
+	c.idents[name] = ident
+	c.taken[ident] = true
+	return ident
+}
+
+func (c *compiler) errorf(node ast.Node, msg string, args ...any) goast.Expr {
+	pos := node.Pos()
+	c.errs = append(c.errs, fmt.Errorf("%s-%s: "+msg, append([]any{pos.From, pos.To}, args...)...))
+	return goast.NewIdent("nil")
+}
+
+// body compiles forms to statements, which return the value of the last form.
+func (c *compiler) body(nodes []ast.Node) []goast.Stmt {
+	var stmts []goast.Stmt
+
+	for i, node := range nodes {
+		last := i == len(nodes)-1
+
+		switch {
+		case isNode[*ast.Defn](node):
+			stmts = append(stmts, c.defn(node.(*ast.Defn))...)
+		case isCall(node, "assign") && c.scope.lookup("assign") == nil:
+			stmts = append(stmts, c.assign(node.(*ast.SExp), last)...)
+			continue
+		default:
+			if last {
+				stmts = append(stmts, &goast.ReturnStmt{Results: []goast.Expr{c.expr(node)}})
+			} else {
+				stmts = append(stmts, blank(c.expr(node)))
+			}
+			continue
+		}
+
+		if last {
+			// defn returns the defined function
+			ident := c.scope.lookup(node.(*ast.Defn).Fn.Ident.Value).ident
+			stmts = append(stmts, &goast.ReturnStmt{Results: []goast.Expr{goast.NewIdent(ident)}})
+		}
+	}
+
+	if len(nodes) == 0 {
+		stmts = append(stmts, &goast.ReturnStmt{Results: []goast.Expr{goast.NewIdent("nil")}})
+	}
+
+	return stmts
+}
+
+// (defn name (params) body...) declares a function variable first, so the function can call itself:
+//
+//	var name func(params any) any
+//	name = func(params any) any { ... }
+func (c *compiler) defn(defn *ast.Defn) []goast.Stmt {
+	name := defn.Fn.Ident.Value
+
+	if prev, ok := c.scope.names[name]; ok && (prev.fn == nil || !sameSignature(prev.fn, defn.Fn)) {
+		c.errorf(defn, "%w: redefinition of %s with a different signature", errUnsupported, name)
+		return nil
+	}
+
+	b, declared := c.scope.names[name]
+	if !declared {
+		b = &binding{ident: c.ident(name)}
+		c.scope.names[name] = b
+	}
+	b.fn = defn.Fn
+
+	var stmts []goast.Stmt
+	if !declared {
+		stmts = append(stmts, &goast.DeclStmt{Decl: &goast.GenDecl{
+			Tok: token.VAR,
+			Specs: []goast.Spec{&goast.ValueSpec{
+				Names: []*goast.Ident{goast.NewIdent(b.ident)},
+				Type:  c.funcType(defn.Fn),
+			}},
+		}})
+	}
+
+	stmts = append(stmts,
+		&goast.AssignStmt{
+			Lhs: []goast.Expr{goast.NewIdent(b.ident)},
+			Tok: token.ASSIGN,
+			Rhs: []goast.Expr{c.funcLit(defn.Fn)},
+		},
+		blank(goast.NewIdent(b.ident)),
+	)
+
+	return stmts
+}
+
+// (assign name value name value) declares new variables or assigns existing ones in the current scope.
+// As the last form of a body it returns an array of assigned values.
+func (c *compiler) assign(sexp *ast.SExp, last bool) []goast.Stmt {
+	args := sexp.Items[1:]
+	if len(args)%2 != 0 {
+		c.errorf(sexp, "assign requires an even number of arguments, got %d", len(args))
+		return nil
+	}
+
+	var stmts []goast.Stmt
+	var values []goast.Expr
+
+	for target, value := range seq.SlicePairs(args) {
+		symbol, ok := target.(*ast.Symbol)
+		if !ok {
+			c.errorf(target, "assign can only use symbols as names, got %s %q", target.Name(), target)
+			return nil
+		}
+
+		rhs := c.expr(value)
+
+		b, declared := c.scope.names[symbol.Value]
+		switch {
+		case declared && b.fn != nil:
+			c.errorf(target, "%w: assigning a value to function %s", errUnsupported, symbol.Value)
+			return nil
+		case declared:
+			stmts = append(stmts, &goast.AssignStmt{
+				Lhs: []goast.Expr{goast.NewIdent(b.ident)},
+				Tok: token.ASSIGN,
+				Rhs: []goast.Expr{rhs},
+			})
+		default:
+			b = &binding{ident: c.ident(symbol.Value)}
+			c.scope.names[symbol.Value] = b
+
+			stmts = append(stmts,
+				&goast.DeclStmt{Decl: &goast.GenDecl{
+					Tok: token.VAR,
+					Specs: []goast.Spec{&goast.ValueSpec{
+						Names:  []*goast.Ident{goast.NewIdent(b.ident)},
+						Type:   goast.NewIdent("any"),
+						Values: []goast.Expr{rhs},
+					}},
+				}},
+				blank(goast.NewIdent(b.ident)),
+			)
+		}
+
+		values = append(values, goast.NewIdent(b.ident))
+	}
+
+	if last {
+		stmts = append(stmts, &goast.ReturnStmt{Results: []goast.Expr{array(values)}})
+	}
+
+	return stmts
+}
+
+func (c *compiler) expr(node ast.Node) goast.Expr {
+	switch node := node.(type) {
+	case *ast.Literal[int64]:
+		return call(goast.NewIdent("int64"), &goast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(node.Value, 10)})
+	case *ast.Literal[float64]:
+		if math.IsInf(node.Value, 0) || math.IsNaN(node.Value) {
+			return c.errorf(node, "%w: float literal %v", errUnsupported, node.Value)
+		}
+		return call(goast.NewIdent("float64"), &goast.BasicLit{Kind: token.FLOAT, Value: strconv.FormatFloat(node.Value, 'g', -1, 64)})
+	case *ast.Literal[string]:
+		str, err := strconv.Unquote(node.Value)
+		if err != nil {
+			str = node.Value
+		}
+		return &goast.BasicLit{Kind: token.STRING, Value: strconv.Quote(str)}
+	case *ast.Literal[bool]:
+		return goast.NewIdent(strconv.FormatBool(node.Value))
+	case *ast.Symbol:
+		if b := c.scope.lookup(node.Value); b != nil {
+			return goast.NewIdent(b.ident)
+		}
+		if node.Value == "null" {
+			return goast.NewIdent("nil")
+		}
+		return c.errorf(node, "unbound symbol %s", node.Value)
+	case *ast.SpecialOp:
+		return c.specialOp(node)
+	case *ast.If:
+		return c.ifExpr(node)
+	case *ast.Fn:
+		return c.fn(node)
+	case *ast.Vector:
+		return array(c.exprs(node.Items))
+	case *ast.SExp:
+		return c.call(node)
+	default:
+		return c.errorf(node, "%w: %s %q", errUnsupported, node.Name(), node)
+	}
+}
+
+func (c *compiler) exprs(nodes []ast.Node) []goast.Expr {
+	exprs := make([]goast.Expr, 0, len(nodes))
+	for _, node := range nodes {
+		exprs = append(exprs, c.expr(node))
+	}
+	return exprs
+}
+
+func (c *compiler) specialOp(op *ast.SpecialOp) goast.Expr {
+	if op.Op == "and" || op.Op == "or" {
+		conds := make([]goast.Expr, 0, len(op.Items))
+		for _, item := range op.Items {
+			conds = append(conds, c.cond(item))
+		}
+		return c.logical(op.Op, conds)
+	}
+
+	args := c.exprs(op.Items)
+
+	switch op.Op {
+	case "+":
+		return c.runtime("Add", args...)
+	case "*":
+		return c.runtime("Mul", args...)
+	case "-":
+		if len(args) == 1 {
+			return c.runtime("Neg", args...)
+		}
+		return c.runtime("Sub", args...)
+	case "/":
+		return c.runtime("Div", args...)
+	case "mod":
+		return c.runtime("Mod", args...)
+	case "rem":
+		return c.runtime("Rem", args...)
+	default:
+		return c.errorf(op, "%w: operator %s", errUnsupported, op.Op)
+	}
+}
+
+// logical builds a short-circuit chain of conditions: a && b.
+func (c *compiler) logical(op string, args []goast.Expr) goast.Expr {
+	if len(args) == 0 {
+		return goast.NewIdent(strconv.FormatBool(op == "and"))
+	}
+
+	tok := token.LAND
+	if op == "or" {
+		tok = token.LOR
+	}
+
+	result := args[0]
+	for _, arg := range args[1:] {
+		result = &goast.BinaryExpr{X: result, Op: tok, Y: arg}
+	}
+
+	return result
+}
+
+// cond compiles a boolean expression, values of unknown types are checked by rt.Bool.
+func (c *compiler) cond(node ast.Node) goast.Expr {
+	expr := c.expr(node)
+
+	if c.isBoolean(node) {
+		return expr
+	}
+
+	return c.runtime("Bool", expr)
+}
+
+// isBoolean reports if the form is compiled to a Go expression of type bool.
+// Calls of comparisons are, unless the name is bound by the user.
+func (c *compiler) isBoolean(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.Literal[bool]:
+		return true
+	case *ast.SpecialOp:
+		return node.Op == "and" || node.Op == "or"
+	case *ast.SExp:
+		if len(node.Items) == 0 {
+			return false
+		}
+		head, ok := node.Items[0].(*ast.Symbol)
+		return ok && builtins[head.Value] != "" && c.scope.lookup(head.Value) == nil
+	default:
+		return false
+	}
+}
+
+// ifExpr is an immediately called closure, because if is an expression:
+//
+//	func() any {
+//		if rt.Bool(cond) {
+//			return then
+//		}
+//		return else
+//	}()
+func (c *compiler) ifExpr(node *ast.If) goast.Expr {
+	var elseExpr goast.Expr = goast.NewIdent("nil")
+	if node.Else != nil {
+		elseExpr = c.expr(node.Else)
+	}
+
+	return iife(
+		&goast.IfStmt{
+			Cond: c.cond(node.Cond),
+			Body: &goast.BlockStmt{List: []goast.Stmt{
+				&goast.ReturnStmt{Results: []goast.Expr{c.expr(node.Then)}},
+			}},
+		},
+		&goast.ReturnStmt{Results: []goast.Expr{elseExpr}},
+	)
+}
+
+// fn compiles a function literal, a named one is wrapped in a closure, so it can call itself.
+func (c *compiler) fn(fn *ast.Fn) goast.Expr {
+	if fn.Ident == nil {
+		return c.funcLit(fn)
+	}
+
+	outer := c.scope
+	c.scope = newScope(outer)
+	defer func() { c.scope = outer }()
+
+	b := &binding{ident: c.ident(fn.Ident.Value), fn: fn}
+	c.scope.names[fn.Ident.Value] = b
+
+	return &goast.CallExpr{Fun: &goast.FuncLit{
+		Type: &goast.FuncType{
+			Params:  &goast.FieldList{},
+			Results: &goast.FieldList{List: []*goast.Field{{Type: c.funcType(fn)}}},
+		},
+		Body: &goast.BlockStmt{List: []goast.Stmt{
+			&goast.DeclStmt{Decl: &goast.GenDecl{
+				Tok: token.VAR,
+				Specs: []goast.Spec{&goast.ValueSpec{
+					Names: []*goast.Ident{goast.NewIdent(b.ident)},
+					Type:  c.funcType(fn),
+				}},
+			}},
+			&goast.AssignStmt{
+				Lhs: []goast.Expr{goast.NewIdent(b.ident)},
+				Tok: token.ASSIGN,
+				Rhs: []goast.Expr{c.funcLit(fn)},
+			},
+			&goast.ReturnStmt{Results: []goast.Expr{goast.NewIdent(b.ident)}},
+		}},
+	}}
+}
+
+func (c *compiler) funcLit(fn *ast.Fn) *goast.FuncLit {
+	outer := c.scope
+	c.scope = newScope(outer)
+	defer func() { c.scope = outer }()
+
+	for _, param := range fn.Params {
+		c.scope.names[param.Value] = &binding{ident: c.ident(param.Value)}
+	}
+
+	if fn.Rest != nil {
+		c.scope.names[fn.Rest.Value] = &binding{ident: c.ident(fn.Rest.Value)}
+	}
+
+	return &goast.FuncLit{
+		Type: c.funcType(fn),
+		Body: &goast.BlockStmt{List: c.body(fn.Body)},
+	}
+}
+
+var builtins = map[string]string{
+	"<":    "Lt",
+	"<=":   "Le",
+	">":    "Gt",
+	">=":   "Ge",
+	"=":    "Eq",
+	"not=": "NotEq",
+	"not":  "Not",
+}
+
+func (c *compiler) call(sexp *ast.SExp) goast.Expr {
+	if len(sexp.Items) == 0 {
+		return c.errorf(sexp, "%w: empty list", errUnsupported)
+	}
+
+	head, args := sexp.Items[0], c.exprs(sexp.Items[1:])
+
+	symbol, isSymbol := head.(*ast.Symbol)
+	if !isSymbol {
+		return c.runtime("Call", append([]goast.Expr{c.expr(head)}, args...)...)
+	}
+
+	b := c.scope.lookup(symbol.Value)
+
+	switch {
+	case b != nil && b.fn != nil && arityMatches(b.fn, len(args)):
+		return call(goast.NewIdent(b.ident), args...)
+	case b != nil:
+		return c.runtime("Call", append([]goast.Expr{goast.NewIdent(b.ident)}, args...)...)
+	case symbol.Value == "array":
+		return array(args)
+	case builtins[symbol.Value] != "":
+		return c.runtime(builtins[symbol.Value], args...)
+	case symbol.Value == "assign":
+		return c.errorf(sexp, "%w: assign can be used only as a statement of a body", errUnsupported)
+	default:
+		return c.errorf(sexp, "unbound function %s", symbol.Value)
+	}
+}
+
+func (c *compiler) runtime(name string, args ...goast.Expr) goast.Expr {
+	c.usesRuntime = true
+	return call(selector("rt", name), args...)
+}
+
+func arityMatches(fn *ast.Fn, n int) bool {
+	if fn.Rest != nil {
+		return n >= len(fn.Params)
+	}
+	return n == len(fn.Params)
+}
+
+func sameSignature(a, b *ast.Fn) bool {
+	return len(a.Params) == len(b.Params) && (a.Rest == nil) == (b.Rest == nil)
+}
+
+// funcType returns func(params any, rest ...any) any.
+func (c *compiler) funcType(fn *ast.Fn) *goast.FuncType {
+	params := &goast.FieldList{}
+
+	if len(fn.Params) > 0 {
+		field := &goast.Field{Type: goast.NewIdent("any")}
+		for _, param := range fn.Params {
+			field.Names = append(field.Names, goast.NewIdent(c.ident(param.Value)))
+		}
+		params.List = append(params.List, field)
+	}
+
+	if fn.Rest != nil {
+		params.List = append(params.List, &goast.Field{
+			Names: []*goast.Ident{goast.NewIdent(c.ident(fn.Rest.Value))},
+			Type:  &goast.Ellipsis{Elt: goast.NewIdent("any")},
+		})
+	}
+
+	return &goast.FuncType{
+		Params:  params,
+		Results: anyResult(),
+	}
+}
+
+func anyResult() *goast.FieldList {
+	return &goast.FieldList{List: []*goast.Field{{Type: goast.NewIdent("any")}}}
+}
+
+func iife(stmts ...goast.Stmt) goast.Expr {
+	return &goast.CallExpr{Fun: &goast.FuncLit{
+		Type: &goast.FuncType{Params: &goast.FieldList{}, Results: anyResult()},
+		Body: &goast.BlockStmt{List: stmts},
+	}}
+}
+
+func array(items []goast.Expr) goast.Expr {
+	return &goast.CompositeLit{
+		Type: &goast.ArrayType{Elt: goast.NewIdent("any")},
+		Elts: items,
+	}
+}
+
+func call(fn goast.Expr, args ...goast.Expr) *goast.CallExpr {
+	return &goast.CallExpr{Fun: fn, Args: args}
+}
+
+func selector(pkg, name string) goast.Expr {
+	return &goast.SelectorExpr{X: goast.NewIdent(pkg), Sel: goast.NewIdent(name)}
+}
+
+// blank discards a value: _ = x, so unused variables don't break compilation.
+func blank(x goast.Expr) goast.Stmt {
+	return &goast.AssignStmt{
+		Lhs: []goast.Expr{goast.NewIdent("_")},
+		Tok: token.ASSIGN,
+		Rhs: []goast.Expr{x},
+	}
+}
+
+func importSpec(path string) goast.Spec {
+	return &goast.ImportSpec{Path: &goast.BasicLit{Kind: token.STRING, Value: strconv.Quote(path)}}
+}
+
+// names used by generated code
+var reserved = map[string]bool{
+	"_": true, "any": true, "nil": true, "true": true, "false": true,
+	"int64": true, "float64": true, "bool": true, "string": true,
+	"fmt": true, "rt": true, "main": true, "run": true,
+}
+
+// goIdent converts a sulisp name to a Go identifier: is-empty? becomes is_empty_p.
+func goIdent(name string) string {
+	str := &strings.Builder{}
+
+	for i, ru := range name {
+		switch {
+		case ru == '_' || unicode.IsLetter(ru) || (i > 0 && unicode.IsDigit(ru)):
+			str.WriteRune(ru)
+		case ru == '-':
+			str.WriteRune('_')
+		case ru == '?':
+			str.WriteString("_p")
+		case ru == '!':
+			str.WriteString("_x")
+		default:
+			fmt.Fprintf(str, "_%X_", ru)
+		}
+	}
+
+	ident := str.String()
+	if token.IsKeyword(ident) || reserved[ident] {
+		ident += "_"
+	}
+
+	return ident
+}
+
+func isNode[N ast.Node](node ast.Node) bool {
+	_, ok := node.(N)
+	return ok
+}
+
+func isCall(node ast.Node, name string) bool {
+	sexp, ok := node.(*ast.SExp)
+	if !ok || len(sexp.Items) == 0 {
+		return false
+	}
+
+	head, ok := sexp.Items[0].(*ast.Symbol)
+	return ok && head.Value == name
+}
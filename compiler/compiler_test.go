@@ -0,0 +1,152 @@
+package compiler_test
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	goast "go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ninedraft/sulisp/compiler"
+	"github.com/ninedraft/sulisp/lexer"
+	"github.com/ninedraft/sulisp/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestCompile_Golden(t *testing.T) {
+	t.Parallel()
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.sl"))
+	require.NoError(t, err)
+	require.NotEmpty(t, inputs)
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".sl")
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := compile(t, input)
+
+			golden := strings.TrimSuffix(input, ".sl") + ".golden"
+			if *update {
+				require.NoError(t, os.WriteFile(golden, got, 0o644))
+			}
+
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+
+			assert.Equal(t, string(want), string(got), "generated code")
+
+			typecheck(t, got)
+		})
+	}
+}
+
+func TestCompile_Run(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a Go binary")
+	}
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go tool is not available")
+	}
+
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, compile(t, filepath.Join("testdata", "functions.sl")), 0o644))
+
+	out, err := exec.Command("go", "run", file).CombinedOutput()
+	require.NoError(t, err, "%s", out)
+
+	assert.Equal(t, "[3628800 3 0 7]\n", string(out))
+}
+
+func TestCompile_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`(undefined 1)`,
+		`(+ x 1)`,
+		`(+ 1 (assign a 2))`,
+		`(defn f (x) x) (defn f (x y) x)`,
+	} {
+		lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
+		pkg, err := parser.New(lex).Parse()
+		require.NoError(t, err, input)
+
+		err = compiler.Compile(&bytes.Buffer{}, pkg)
+		assert.Error(t, err, input)
+	}
+}
+
+func compile(t *testing.T, filename string) []byte {
+	t.Helper()
+
+	file, err := os.Open(filename)
+	require.NoError(t, err)
+	defer file.Close()
+
+	pkg, err := parser.New(lexer.NewLexer(filename, bufio.NewReader(file))).Parse()
+	require.NoError(t, err, "parsing")
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, compiler.Compile(buf, pkg), "compiling")
+
+	return buf.Bytes()
+}
+
+// typecheck checks generated code with the runtime package loaded from the source.
+func typecheck(t *testing.T, src []byte) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+
+	file, err := goparser.ParseFile(fset, "main.go", src, 0)
+	require.NoError(t, err, "parsing generated code")
+
+	std := importer.ForCompiler(fset, "source", nil)
+
+	rtFiles, err := filepath.Glob(filepath.Join("rt", "*.go"))
+	require.NoError(t, err)
+
+	var rtAST []*goast.File
+	for _, name := range rtFiles {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := goparser.ParseFile(fset, name, nil, 0)
+		require.NoError(t, err)
+		rtAST = append(rtAST, f)
+	}
+
+	rt, err := (&types.Config{Importer: std}).Check(compiler.RuntimePath, fset, rtAST, nil)
+	require.NoError(t, err, "checking runtime")
+
+	conf := &types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+		if path == compiler.RuntimePath {
+			return rt, nil
+		}
+		return std.Import(path)
+	})}
+
+	_, err = conf.Check("main", fset, []*goast.File{file}, nil)
+	require.NoError(t, err, "checking generated code")
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (fn importerFunc) Import(path string) (*types.Package, error) { return fn(path) }
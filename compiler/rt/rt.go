@@ -0,0 +1,258 @@
+// Package rt is a runtime support library for Go code generated by the compiler.
+// Values of the generated code have type any: int64, float64, string, bool, []any, functions and nil.
+// Type errors are reported as panics.
+package rt
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Add sums numbers, the result is a float if any of arguments is a float.
+func Add(xs ...any) any {
+	return fold(xs, 0, func(a, b int64) int64 { return a + b }, func(a, b float64) float64 { return a + b })
+}
+
+// Mul multiplies numbers, the result is a float if any of arguments is a float.
+func Mul(xs ...any) any {
+	return fold(xs, 1, func(a, b int64) int64 { return a * b }, func(a, b float64) float64 { return a * b })
+}
+
+func Sub(a, b any) any {
+	return binary(a, b, func(a, b int64) int64 { return a - b }, func(a, b float64) float64 { return a - b })
+}
+
+func Div(a, b any) any {
+	return binary(a, b, func(a, b int64) int64 { return a / b }, func(a, b float64) float64 { return a / b })
+}
+
+// Rem returns the remainder of truncated division, the result has the sign of the dividend.
+func Rem(a, b any) any {
+	return binary(a, b, func(a, b int64) int64 { return a % b }, math.Mod)
+}
+
+// Mod returns the modulus of floored division, the result has the sign of the divisor.
+func Mod(a, b any) any {
+	return binary(a, b,
+		func(a, b int64) int64 {
+			m := a % b
+			if m != 0 && (m < 0) != (b < 0) {
+				m += b
+			}
+			return m
+		},
+		func(a, b float64) float64 {
+			m := math.Mod(a, b)
+			if m != 0 && (m < 0) != (b < 0) {
+				m += b
+			}
+			return m
+		})
+}
+
+func Neg(x any) any {
+	switch x := x.(type) {
+	case int64:
+		return -x
+	case float64:
+		return -x
+	default:
+		panic(typeError("-", x))
+	}
+}
+
+func fold(xs []any, zero int64, ints func(a, b int64) int64, floats func(a, b float64) float64) any {
+	var result any = zero
+	for _, x := range xs {
+		result = binary(result, x, ints, floats)
+	}
+	return result
+}
+
+func binary(a, b any, ints func(a, b int64) int64, floats func(a, b float64) float64) any {
+	x, xIsInt := a.(int64)
+	y, yIsInt := b.(int64)
+	if xIsInt && yIsInt {
+		return ints(x, y)
+	}
+
+	return floats(float(a), float(b))
+}
+
+func float(x any) float64 {
+	switch x := x.(type) {
+	case int64:
+		return float64(x)
+	case float64:
+		return x
+	default:
+		panic(typeError("arithmetic", x))
+	}
+}
+
+// Lt reports if arguments are in increasing order.
+func Lt(xs ...any) bool { return chain(xs, func(c int) bool { return c < 0 }) }
+
+// Le reports if arguments are in non-decreasing order.
+func Le(xs ...any) bool { return chain(xs, func(c int) bool { return c <= 0 }) }
+
+// Gt reports if arguments are in decreasing order.
+func Gt(xs ...any) bool { return chain(xs, func(c int) bool { return c > 0 }) }
+
+// Ge reports if arguments are in non-increasing order.
+func Ge(xs ...any) bool { return chain(xs, func(c int) bool { return c >= 0 }) }
+
+func chain(xs []any, ok func(c int) bool) bool {
+	for i := 1; i < len(xs); i++ {
+		if !ok(compare(xs[i-1], xs[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func compare(a, b any) int {
+	if a, ok := a.(string); ok {
+		if b, ok := b.(string); ok {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	x, xIsInt := a.(int64)
+	y, yIsInt := b.(int64)
+	if xIsInt && yIsInt {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	fx, fy := float(a), float(b)
+	switch {
+	case fx < fy:
+		return -1
+	case fx > fy:
+		return 1
+	}
+	return 0
+}
+
+// Eq reports if all arguments are structurally equal, numbers are compared numerically.
+func Eq(xs ...any) bool {
+	for i := 1; i < len(xs); i++ {
+		if !equal(xs[i-1], xs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func NotEq(xs ...any) bool { return !Eq(xs...) }
+
+func equal(a, b any) bool {
+	if isNumber(a) && isNumber(b) {
+		return compare(a, b) == 0
+	}
+
+	xs, xIsArray := a.([]any)
+	ys, yIsArray := b.([]any)
+	if xIsArray && yIsArray {
+		if len(xs) != len(ys) {
+			return false
+		}
+		for i := range xs {
+			if !equal(xs[i], ys[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if !reflect.TypeOf(a).Comparable() || reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+
+	return a == b
+}
+
+func isNumber(x any) bool {
+	switch x.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+// Bool checks that the value is a boolean.
+func Bool(x any) bool {
+	b, ok := x.(bool)
+	if !ok {
+		panic(typeError("condition", x))
+	}
+	return b
+}
+
+func Not(x any) bool { return !Bool(x) }
+
+// Call applies a function to arguments or gets an element of an array by index.
+func Call(fn any, args ...any) any {
+	switch fn := fn.(type) {
+	case []any:
+		if len(args) == 0 {
+			return fn
+		}
+		i, ok := args[0].(int64)
+		if !ok {
+			panic(typeError("array index", args[0]))
+		}
+		return fn[i]
+	case nil:
+		panic("call of null")
+	}
+
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		panic(typeError("call", fn))
+	}
+
+	in := make([]reflect.Value, 0, len(args))
+	for i, arg := range args {
+		t := anyType
+		if !value.Type().IsVariadic() || i < value.Type().NumIn()-1 {
+			t = value.Type().In(i)
+		}
+
+		if arg == nil {
+			in = append(in, reflect.Zero(t))
+			continue
+		}
+		in = append(in, reflect.ValueOf(arg))
+	}
+
+	out := value.Call(in)
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out[0].Interface()
+}
+
+var anyType = reflect.TypeFor[any]()
+
+func typeError(op string, x any) error {
+	return fmt.Errorf("%s: unexpected value %T %v", op, x, x)
+}
@@ -0,0 +1,271 @@
+// Package hamt implements a persistent hash map: a hash array mapped trie.
+//
+// Every level of the trie takes 5 bits of key hashes, nodes keep only present slots
+// and a bitmap of them. Updates copy only nodes on the path to the changed entry,
+// so versions of a map share the rest of their nodes.
+package hamt
+
+import (
+	"hash/maphash"
+	"iter"
+	"math/bits"
+)
+
+const (
+	levelBits = 5
+	mask      = 1<<levelBits - 1
+	hashBits  = 64
+)
+
+var seed = maphash.MakeSeed()
+
+// Map is an immutable map. The zero value is an empty map.
+// Iteration order depends on hashes of keys, which differ between runs.
+type Map[K comparable, V any] struct {
+	size int
+	root *node[K, V]
+}
+
+// node is a level of the trie. Entries of keys with equal hashes
+// are collected in the collisions of a node below all levels.
+type node[K comparable, V any] struct {
+	bitmap     uint32
+	slots      []slot[K, V]
+	collisions []entry[K, V]
+}
+
+// slot is a child node or an entry.
+type slot[K comparable, V any] struct {
+	child *node[K, V]
+	entry entry[K, V]
+}
+
+type entry[K comparable, V any] struct {
+	hash  uint64
+	key   K
+	value V
+}
+
+func hashOf[K comparable](key K) uint64 {
+	return maphash.Comparable(seed, key)
+}
+
+func (m Map[K, V]) Len() int {
+	return m.size
+}
+
+// Get returns the value of the key.
+func (m Map[K, V]) Get(key K) (V, bool) {
+	hash := hashOf(key)
+
+	n := m.root
+	for shift := 0; n != nil; shift += levelBits {
+		if shift >= hashBits {
+			for _, e := range n.collisions {
+				if e.key == key {
+					return e.value, true
+				}
+			}
+			break
+		}
+
+		bit, i := n.index(hash, shift)
+		if n.bitmap&bit == 0 {
+			break
+		}
+
+		s := n.slots[i]
+		if s.child == nil {
+			if s.entry.key == key {
+				return s.entry.value, true
+			}
+			break
+		}
+
+		n = s.child
+	}
+
+	var empty V
+	return empty, false
+}
+
+// Set returns a map with the key bound to the value.
+func (m Map[K, V]) Set(key K, value V) Map[K, V] {
+	root := m.root
+	if root == nil {
+		root = &node[K, V]{}
+	}
+
+	root, added := root.set(0, entry[K, V]{hash: hashOf(key), key: key, value: value})
+	if added {
+		m.size++
+	}
+	m.root = root
+
+	return m
+}
+
+// Delete returns a map without the key.
+func (m Map[K, V]) Delete(key K) Map[K, V] {
+	if m.root == nil {
+		return m
+	}
+
+	root, removed := m.root.delete(0, hashOf(key), key)
+	if !removed {
+		return m
+	}
+
+	m.size--
+	m.root = root
+	if m.size == 0 {
+		m.root = nil
+	}
+
+	return m
+}
+
+// All iterates over keys and values.
+func (m Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.root != nil {
+			m.root.all(yield)
+		}
+	}
+}
+
+// index returns the bit of the hash at the level and the slot index of it.
+func (n *node[K, V]) index(hash uint64, shift int) (uint32, int) {
+	bit := uint32(1) << ((hash >> shift) & mask)
+	return bit, bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+func (n *node[K, V]) set(shift int, e entry[K, V]) (*node[K, V], bool) {
+	if shift >= hashBits {
+		collisions := append([]entry[K, V](nil), n.collisions...)
+		for i := range collisions {
+			if collisions[i].key == e.key {
+				collisions[i] = e
+				return &node[K, V]{collisions: collisions}, false
+			}
+		}
+
+		return &node[K, V]{collisions: append(collisions, e)}, true
+	}
+
+	bit, i := n.index(e.hash, shift)
+
+	if n.bitmap&bit == 0 {
+		slots := make([]slot[K, V], 0, len(n.slots)+1)
+		slots = append(slots, n.slots[:i]...)
+		slots = append(slots, slot[K, V]{entry: e})
+		slots = append(slots, n.slots[i:]...)
+
+		return &node[K, V]{bitmap: n.bitmap | bit, slots: slots}, true
+	}
+
+	s := n.slots[i]
+	added := true
+
+	switch {
+	case s.child != nil:
+		s.child, added = s.child.set(shift+levelBits, e)
+	case s.entry.key == e.key:
+		s.entry = e
+		added = false
+	default:
+		// both entries move to the next level
+		child, _ := (&node[K, V]{}).set(shift+levelBits, s.entry)
+		s.child, _ = child.set(shift+levelBits, e)
+		s.entry = entry[K, V]{}
+	}
+
+	return n.with(i, s), added
+}
+
+func (n *node[K, V]) delete(shift int, hash uint64, key K) (*node[K, V], bool) {
+	if shift >= hashBits {
+		for i, e := range n.collisions {
+			if e.key == key {
+				collisions := append(n.collisions[:i:i], n.collisions[i+1:]...)
+				return &node[K, V]{collisions: collisions}, true
+			}
+		}
+
+		return n, false
+	}
+
+	bit, i := n.index(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+
+	s := n.slots[i]
+
+	if s.child == nil {
+		if s.entry.key != key {
+			return n, false
+		}
+
+		slots := append(n.slots[:i:i], n.slots[i+1:]...)
+		return &node[K, V]{bitmap: n.bitmap &^ bit, slots: slots}, true
+	}
+
+	child, removed := s.child.delete(shift+levelBits, hash, key)
+	if !removed {
+		return n, false
+	}
+
+	// children with a single entry collapse into it
+	switch e, single := child.single(); {
+	case single:
+		s = slot[K, V]{entry: e}
+	default:
+		s.child = child
+	}
+
+	return n.with(i, s), true
+}
+
+// single returns the entry of a node without other entries and children.
+func (n *node[K, V]) single() (entry[K, V], bool) {
+	switch {
+	case len(n.collisions) == 1:
+		return n.collisions[0], true
+	case len(n.slots) == 1 && n.slots[0].child == nil:
+		return n.slots[0].entry, true
+	default:
+		return entry[K, V]{}, false
+	}
+}
+
+// with returns a copy of the node with the slot replaced.
+func (n *node[K, V]) with(i int, s slot[K, V]) *node[K, V] {
+	slots := append([]slot[K, V](nil), n.slots...)
+	slots[i] = s
+
+	return &node[K, V]{bitmap: n.bitmap, slots: slots}
+}
+
+func (n *node[K, V]) all(yield func(K, V) bool) bool {
+	for _, e := range n.collisions {
+		if !yield(e.key, e.value) {
+			return false
+		}
+	}
+
+	for _, s := range n.slots {
+		if s.child != nil {
+			if !s.child.all(yield) {
+				return false
+			}
+			continue
+		}
+
+		if !yield(s.entry.key, s.entry.value) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,108 @@
+package hamt
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMapSetGet(test *testing.T) {
+	const size = 5000
+
+	var m Map[string, int]
+	for i := range size {
+		m = m.Set(strconv.Itoa(i), i)
+	}
+	m = m.Set("0", -1)
+
+	if m.Len() != size {
+		test.Fatalf("got length %d, want %d", m.Len(), size)
+	}
+
+	for i := 1; i < size; i++ {
+		if got, ok := m.Get(strconv.Itoa(i)); !ok || got != i {
+			test.Fatalf("value of %d is %d, %v", i, got, ok)
+		}
+	}
+
+	if got, _ := m.Get("0"); got != -1 {
+		test.Errorf("replaced value is %d", got)
+	}
+
+	if _, ok := m.Get("missing"); ok {
+		test.Errorf("missing key is found")
+	}
+
+	seen := map[string]bool{}
+	for key := range m.All() {
+		seen[key] = true
+	}
+	if len(seen) != size {
+		test.Errorf("iterated over %d keys, want %d", len(seen), size)
+	}
+}
+
+func TestMapDelete(test *testing.T) {
+	const size = 3000
+
+	var m Map[int, int]
+	for i := range size {
+		m = m.Set(i, i)
+	}
+
+	full := m
+	for i := 0; i < size; i += 2 {
+		m = m.Delete(i)
+	}
+	m = m.Delete(-1)
+
+	if m.Len() != size/2 {
+		test.Fatalf("got length %d, want %d", m.Len(), size/2)
+	}
+
+	for i := range size {
+		_, ok := m.Get(i)
+		if ok != (i%2 == 1) {
+			test.Fatalf("key %d is found: %v", i, ok)
+		}
+
+		if got, ok := full.Get(i); !ok || got != i {
+			test.Fatalf("key %d is deleted from the previous version", i)
+		}
+	}
+
+	for i := 1; i < size; i += 2 {
+		m = m.Delete(i)
+	}
+	if m.Len() != 0 || m.root != nil {
+		test.Errorf("map isn't empty: %d", m.Len())
+	}
+}
+
+func TestMapCollisions(test *testing.T) {
+	// keys with equal hashes end up in a collision node
+	root := &node[string, int]{}
+	for i, key := range []string{"a", "b", "c"} {
+		root, _ = root.set(0, entry[string, int]{hash: 42, key: key, value: i})
+	}
+	root, added := root.set(0, entry[string, int]{hash: 42, key: "b", value: 10})
+	if added {
+		test.Errorf("replaced entry is added")
+	}
+
+	got := map[string]int{}
+	root.all(func(key string, value int) bool {
+		got[key] = value
+		return true
+	})
+	if len(got) != 3 || got["b"] != 10 {
+		test.Fatalf("entries %v", got)
+	}
+
+	root, _ = root.delete(0, 42, "a")
+	root, _ = root.delete(0, 42, "c")
+
+	e, single := root.single()
+	if !single || e.key != "b" || e.value != 10 {
+		test.Errorf("collisions aren't collapsed: %v %v", e, single)
+	}
+}
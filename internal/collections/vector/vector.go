@@ -0,0 +1,245 @@
+// Package vector implements a persistent vector: a 32-way trie of elements with a tail.
+//
+// Updates copy only nodes on the path to the changed element,
+// so versions of a vector share the rest of their nodes.
+// The last up to 32 elements are kept in the tail, which makes appends cheap.
+package vector
+
+import "iter"
+
+const (
+	bits  = 5
+	width = 1 << bits
+	mask  = width - 1
+)
+
+// node is a branch of the trie with children or a leaf with values.
+type node[E any] struct {
+	children []*node[E]
+	values   []E
+}
+
+// Vector is an immutable sequence of elements. The zero value is an empty vector.
+type Vector[E any] struct {
+	size  int
+	shift int      // index bits of the root level, 0 for vectors without a trie
+	root  *node[E] // nil for vectors fitting into the tail
+	tail  []E
+}
+
+// Of returns a vector of the values.
+func Of[E any](values ...E) Vector[E] {
+	return Vector[E]{}.Append(values...)
+}
+
+func (v Vector[E]) Len() int {
+	return v.size
+}
+
+// At returns the element by the index.
+func (v Vector[E]) At(i int) (E, bool) {
+	if i < 0 || i >= v.size {
+		var empty E
+		return empty, false
+	}
+
+	return v.leaf(i)[i&mask], true
+}
+
+// tailOffset returns the index of the first element of the tail.
+func (v Vector[E]) tailOffset() int {
+	if v.size < width {
+		return 0
+	}
+	return (v.size - 1) >> bits << bits
+}
+
+// leaf returns values of the leaf containing the index.
+func (v Vector[E]) leaf(i int) []E {
+	if i >= v.tailOffset() {
+		return v.tail
+	}
+
+	n := v.root
+	for level := v.shift; level > 0; level -= bits {
+		n = n.children[(i>>level)&mask]
+	}
+
+	return n.values
+}
+
+// Append returns a vector with the values added to the end.
+func (v Vector[E]) Append(values ...E) Vector[E] {
+	for len(values) > 0 {
+		if v.size-v.tailOffset() == width && v.size > 0 {
+			v = v.pushLeaf(values[0])
+			values = values[1:]
+			continue
+		}
+
+		// values are copied into the tail by chunks
+		n := min(width-len(v.tail), len(values))
+		tail := make([]E, len(v.tail), len(v.tail)+n)
+		copy(tail, v.tail)
+
+		v.tail = append(tail, values[:n]...)
+		v.size += n
+		values = values[n:]
+	}
+
+	return v
+}
+
+// pushLeaf moves the full tail into the trie and starts a new tail with the value.
+func (v Vector[E]) pushLeaf(value E) Vector[E] {
+	leaf := &node[E]{values: v.tail}
+
+	switch {
+	case v.root == nil:
+		v.root = &node[E]{children: []*node[E]{leaf}}
+		v.shift = bits
+	case v.size>>bits > 1<<v.shift:
+		// the root is full, the trie grows by a level
+		v.root = &node[E]{children: []*node[E]{v.root, newPath(v.shift, leaf)}}
+		v.shift += bits
+	default:
+		v.root = v.pushTail(v.shift, v.root, leaf)
+	}
+
+	v.tail = []E{value}
+	v.size++
+	return v
+}
+
+// pushTail returns a copy of the branch at the level with the leaf added.
+func (v Vector[E]) pushTail(level int, parent, leaf *node[E]) *node[E] {
+	i := ((v.size - 1) >> level) & mask
+	branch := &node[E]{children: append([]*node[E](nil), parent.children...)}
+
+	var child *node[E]
+	switch {
+	case level == bits:
+		child = leaf
+	case i < len(parent.children):
+		child = v.pushTail(level-bits, parent.children[i], leaf)
+	default:
+		child = newPath(level-bits, leaf)
+	}
+
+	if i < len(branch.children) {
+		branch.children[i] = child
+	} else {
+		branch.children = append(branch.children, child)
+	}
+
+	return branch
+}
+
+// newPath returns branches from the level down to the leaf.
+func newPath[E any](level int, leaf *node[E]) *node[E] {
+	if level == 0 {
+		return leaf
+	}
+	return &node[E]{children: []*node[E]{newPath(level-bits, leaf)}}
+}
+
+// Set returns a vector with the element at the index replaced by the value.
+// Indexes out of range give the vector as is.
+func (v Vector[E]) Set(i int, value E) Vector[E] {
+	switch {
+	case i < 0 || i >= v.size:
+		return v
+	case i >= v.tailOffset():
+		tail := append([]E(nil), v.tail...)
+		tail[i&mask] = value
+		v.tail = tail
+	default:
+		v.root = set(v.shift, v.root, i, value)
+	}
+
+	return v
+}
+
+func set[E any](level int, n *node[E], i int, value E) *node[E] {
+	if level == 0 {
+		values := append([]E(nil), n.values...)
+		values[i&mask] = value
+		return &node[E]{values: values}
+	}
+
+	children := append([]*node[E](nil), n.children...)
+	j := (i >> level) & mask
+	children[j] = set(level-bits, n.children[j], i, value)
+
+	return &node[E]{children: children}
+}
+
+// Pop returns a vector without the last element.
+func (v Vector[E]) Pop() Vector[E] {
+	switch {
+	case v.size <= 1:
+		return Vector[E]{}
+	case v.size-v.tailOffset() > 1:
+		v.tail = v.tail[: len(v.tail)-1 : len(v.tail)-1]
+		v.size--
+		return v
+	}
+
+	// the last leaf of the trie becomes the tail
+	tail := v.leaf(v.size - 2)
+	root := v.popTail(v.shift, v.root)
+
+	switch {
+	case root == nil:
+		v.shift = 0
+	case v.shift > bits && len(root.children) == 1:
+		root = root.children[0]
+		v.shift -= bits
+	}
+
+	v.root = root
+	v.tail = tail
+	v.size--
+	return v
+}
+
+// popTail returns a copy of the branch without the last leaf, or nil if it becomes empty.
+func (v Vector[E]) popTail(level int, n *node[E]) *node[E] {
+	if n == nil {
+		return nil
+	}
+
+	i := ((v.size - 2) >> level) & mask
+
+	if level > bits {
+		child := v.popTail(level-bits, n.children[i])
+		if child == nil && i == 0 {
+			return nil
+		}
+
+		children := append([]*node[E](nil), n.children[:i]...)
+		if child != nil {
+			children = append(children, child)
+		}
+		return &node[E]{children: children}
+	}
+
+	if i == 0 {
+		return nil
+	}
+
+	return &node[E]{children: n.children[:i:i]}
+}
+
+// All iterates over indexes and elements in order.
+func (v Vector[E]) All() iter.Seq2[int, E] {
+	return func(yield func(int, E) bool) {
+		for i := 0; i < v.size; i += width {
+			for j, value := range v.leaf(i) {
+				if !yield(i+j, value) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package vector_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ninedraft/sulisp/internal/collections/vector"
+)
+
+// sizes cover a tail only, one and several trie levels
+var sizes = []int{0, 1, 31, 32, 33, 64, 1024, 1025, 1056, 1057, 32*32*32 + 33}
+
+func TestVectorAppendAt(test *testing.T) {
+	for _, size := range sizes {
+		var v vector.Vector[int]
+		for i := range size {
+			v = v.Append(i)
+		}
+
+		if v.Len() != size {
+			test.Fatalf("size %d: got length %d", size, v.Len())
+		}
+
+		for i := range size {
+			if got, ok := v.At(i); !ok || got != i {
+				test.Fatalf("size %d: element %d is %d, %v", size, i, got, ok)
+			}
+		}
+
+		if _, ok := v.At(size); ok {
+			test.Errorf("size %d: element out of range is found", size)
+		}
+	}
+}
+
+func TestVectorAppendMany(test *testing.T) {
+	for _, size := range sizes {
+		values := make([]int, size)
+		for i := range values {
+			values[i] = i
+		}
+
+		// appends of chunks fill tails of different lengths
+		v := vector.Of(values[:size/3]...).Append(values[size/3:]...)
+		if v.Len() != size {
+			test.Fatalf("size %d: got length %d", size, v.Len())
+		}
+
+		for i := range size {
+			if got, _ := v.At(i); got != i {
+				test.Fatalf("size %d: element %d is %d", size, i, got)
+			}
+		}
+	}
+}
+
+func TestVectorAll(test *testing.T) {
+	for _, size := range sizes {
+		want := make([]int, size)
+		for i := range want {
+			want[i] = i * 2
+		}
+
+		var got []int
+		for i, value := range vector.Of(want...).All() {
+			if value != want[i] {
+				test.Fatalf("size %d: element %d is %d, want %d", size, i, value, want[i])
+			}
+			got = append(got, value)
+		}
+
+		if !slices.Equal(want, got) {
+			test.Errorf("size %d: got %v", size, got)
+		}
+	}
+}
+
+func TestVectorPop(test *testing.T) {
+	for _, size := range sizes {
+		v := vector.Of(make([]int, size)...)
+		for i := range size {
+			v = v.Set(i, i)
+		}
+
+		for n := size; n > 0; n-- {
+			if got, ok := v.At(n - 1); !ok || got != n-1 {
+				test.Fatalf("size %d: last element of %d is %d, %v", size, n, got, ok)
+			}
+			v = v.Pop()
+			if v.Len() != n-1 {
+				test.Fatalf("size %d: popped length %d, want %d", size, v.Len(), n-1)
+			}
+		}
+
+		// a popped vector can grow again
+		v = v.Append(1).Append(2)
+		if got, _ := v.At(1); got != 2 {
+			test.Errorf("size %d: appended after pops %d", size, got)
+		}
+	}
+}
+
+func TestVectorPersistence(test *testing.T) {
+	const size = 2000
+
+	base := vector.Of(make([]int, size)...)
+	changed := base.Set(0, 1).Set(size-1, 2).Append(3)
+	popped := base.Pop()
+	grown := popped.Append(4)
+
+	for i := range size {
+		if got, _ := base.At(i); got != 0 {
+			test.Fatalf("base element %d is changed to %d", i, got)
+		}
+	}
+
+	if base.Len() != size || popped.Len() != size-1 || grown.Len() != size {
+		test.Errorf("lengths: base %d, popped %d, grown %d", base.Len(), popped.Len(), grown.Len())
+	}
+
+	first, _ := changed.At(0)
+	last, _ := changed.At(size - 1)
+	appended, _ := changed.At(size)
+	if first != 1 || last != 2 || appended != 3 {
+		test.Errorf("changed elements: %d %d %d", first, last, appended)
+	}
+
+	if got, _ := grown.At(size - 1); got != 4 {
+		test.Errorf("grown last element %d", got)
+	}
+}
@@ -0,0 +1,180 @@
+package astwalk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+var errDivByZero = object.WithKind(object.ErrArithmetic, errors.New("division by zero"))
+
+// evalArithmetic evaluates operands of an arithmetic operator and applies it.
+func evalArithmetic(op *ast.SpecialOp, env *object.Env, eval object.Eval) object.Object {
+	args := make([]object.Object, 0, len(op.Items))
+
+	for i, a := range op.Items {
+		arg := eval(a, env)
+		if err := asError(arg); err != nil {
+			return fmtError(a.Pos(), "%w", withContext(err, "%s: argument %d", op.Op, i))
+		}
+
+		args = append(args, arg)
+	}
+
+	result, err := Arithmetic(op.Op, args)
+	if err != nil {
+		return fmtError(op.Pos(), "%s: %w", op.Op, err)
+	}
+
+	return result
+}
+
+// Arithmetic applies an arithmetic operator to evaluated operands.
+// Integer operands produce an integer, any float operand turns the whole operation into a float one.
+func Arithmetic(op string, args []object.Object) (object.Object, error) {
+	switch op {
+	case "+":
+		return fold(args, 0, func(a, b int64) int64 { return a + b }, func(a, b float64) float64 { return a + b })
+	case "*":
+		return fold(args, 1, func(a, b int64) int64 { return a * b }, func(a, b float64) float64 { return a * b })
+	case "-":
+		if len(args) == 1 {
+			return negate(args[0])
+		}
+		return binary(args, subInts, subFloats)
+	case "/":
+		return binary(args, divInts, divFloats)
+	case "mod":
+		return binary(args, modInts, modFloats)
+	case "rem":
+		return binary(args, remInts, remFloats)
+	default:
+		return nil, fmt.Errorf("unexpected operation %q", op)
+	}
+}
+
+// fold applies a variadic operator, like + or *.
+func fold(args []object.Object, init int64,
+	ints func(a, b int64) int64,
+	floats func(a, b float64) float64,
+) (object.Object, error) {
+	xf, xi := float64(init), init
+	hasFloats := false
+
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case *object.Primitive[int64]:
+			xf = floats(xf, float64(arg.Value))
+			xi = ints(xi, arg.Value)
+		case *object.Primitive[float64]:
+			hasFloats = true
+			xf = floats(xf, arg.Value)
+			xi = ints(xi, int64(arg.Value))
+		default:
+			return nil, object.WithKind(object.ErrType, fmt.Errorf("unexpected argument %d type %s %q", i, arg.Kind(), arg.Inspect()))
+		}
+	}
+
+	if hasFloats {
+		return object.PrimitiveOf(xf), nil
+	}
+
+	return object.PrimitiveOf(xi), nil
+}
+
+// binary applies a binary operator.
+func binary(args []object.Object,
+	ints func(a, b int64) (int64, error),
+	floats func(a, b float64) float64,
+) (object.Object, error) {
+	if len(args) != 2 {
+		return nil, object.WithKind(object.ErrArity, fmt.Errorf("want 2 operands, got %d", len(args)))
+	}
+
+	var xi [2]int64
+	var xf [2]float64
+	hasFloats := false
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case *object.Primitive[int64]:
+			xi[i], xf[i] = arg.Value, float64(arg.Value)
+		case *object.Primitive[float64]:
+			hasFloats = true
+			xi[i], xf[i] = int64(arg.Value), arg.Value
+		default:
+			return nil, object.WithKind(object.ErrType, fmt.Errorf("unexpected argument %d type %s %q", i, arg.Kind(), arg.Inspect()))
+		}
+	}
+
+	if hasFloats {
+		return object.PrimitiveOf(floats(xf[0], xf[1])), nil
+	}
+
+	x, err := ints(xi[0], xi[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return object.PrimitiveOf(x), nil
+}
+
+func negate(arg object.Object) (object.Object, error) {
+	switch arg := arg.(type) {
+	case *object.Primitive[int64]:
+		return object.PrimitiveOf(-arg.Value), nil
+	case *object.Primitive[float64]:
+		return object.PrimitiveOf(-arg.Value), nil
+	default:
+		return nil, object.WithKind(object.ErrType, fmt.Errorf("unexpected argument type %s %q", arg.Kind(), arg.Inspect()))
+	}
+}
+
+func subInts(a, b int64) (int64, error) { return a - b, nil }
+
+func subFloats(a, b float64) float64 { return a - b }
+
+func divInts(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, errDivByZero
+	}
+	return a / b, nil
+}
+
+func divFloats(a, b float64) float64 { return a / b }
+
+// remInts returns the remainder of truncated division, the result has the sign of the dividend.
+func remInts(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, errDivByZero
+	}
+	return a % b, nil
+}
+
+func remFloats(a, b float64) float64 { return math.Mod(a, b) }
+
+// modInts returns the modulus of floored division, the result has the sign of the divisor.
+func modInts(a, b int64) (int64, error) {
+	m, err := remInts(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	if m != 0 && (m < 0) != (b < 0) {
+		m += b
+	}
+
+	return m, nil
+}
+
+func modFloats(a, b float64) float64 {
+	m := math.Mod(a, b)
+
+	if m != 0 && (m < 0) != (b < 0) {
+		m += b
+	}
+
+	return m
+}
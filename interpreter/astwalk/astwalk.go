@@ -1,6 +1,7 @@
 package astwalk
 
 import (
+	"errors"
 	"fmt"
 	"iter"
 
@@ -15,24 +16,55 @@ var (
 	False = object.PrimitiveOf(false)
 )
 
+// DefaultEnv returns an env with builtins.
+// Modules required from the env are cached by a loader shared by all of them.
 func DefaultEnv() *object.Env {
+	return newEnv(newModules())
+}
+
+func newEnv(mods *modules) *object.Env {
 	env := object.NewEnv()
 
+	env.Assign("null", Null)
 	env.Assign("type-of", newBuiltin(Infer, object.TypeFor(object.ObjType)))
 	env.Assign("assign", newBuiltin(assign, object.TypeFor(TypeArray, TypeAny)))
 	env.Assign("apply", newBuiltin(builtinApply, object.TypeFor(TypeAny)))
 	env.Assign("namespace", newBuiltin(createNamespace, object.TypeFor(object.ObjNamespace)))
-	env.Assign(">", newBuiltin(gt, object.TypeFor(object.ObjBool)))
+
+	env.Assign("go-value", newBuiltin(builtinGoValue, object.TypeFor(object.ObjGoValue)))
+	env.Assign("from-go", newBuiltin(builtinFromGo, object.TypeFor(TypeAny)))
+
+	env.Assign("not", newBuiltin(not, object.FuncType(object.TypeFor(TypeBool), *object.TypeFor(TypeBool))))
+	env.Assign("gensym", newBuiltin(builtinGensym, object.TypeFor(object.ObjAST)))
+	env.Assign("macroexpand", newBuiltin(macroexpand(false), object.TypeFor(object.ObjAST)))
+	env.Assign("macroexpand-1", newBuiltin(macroexpand(true), object.TypeFor(object.ObjAST)))
+
+	for name, fn := range comparisons {
+		env.Assign(name, newBuiltin(fn, object.TypeFor(TypeBool)))
+	}
+
+	for name, builtin := range mapBuiltins {
+		env.Assign(name, builtin)
+	}
+
+	for name, builtin := range collectionBuiltins {
+		env.Assign(name, builtin)
+	}
+
+	for name, builtin := range exceptionBuiltins {
+		env.Assign(name, builtin)
+	}
+
+	env.Assign("require", newBuiltin(mods.require, object.TypeFor(object.ObjNamespace)))
+	env.Assign("load", newBuiltin(mods.load, object.TypeFor(TypeAny)))
 
 	env.Assign("array", newBuiltin(func(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
 		elements, err := seq.CollectErr(resolveMany(sexp.Items, env, eval))
 		if err != nil {
-			return fmtError(sexp.PosRange, "array values: %w", err)
+			return fmtError(sexp.PosRange, "%w", withContext(err, "array values"))
 		}
 
-		return &object.Array{
-			Elements: elements,
-		}
+		return object.NewArray(elements...)
 	}, object.TypeFor(TypeArray)))
 	return env
 }
@@ -59,9 +91,7 @@ func createNamespace(sexp *ast.SExp, env *object.Env, eval object.Eval) object.O
 func assign(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
 	args := sexp.Items
 	if len(args)%2 != 0 {
-		return &object.Error{
-			Err: fmt.Errorf("assign requires an even number of arguments, got %d", len(args)),
-		}
+		return fmtError(sexp.Pos(), "assign requires an even number of arguments, got %d", len(args))
 	}
 
 	values := &object.Array{}
@@ -69,14 +99,12 @@ func assign(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
 	for k, v := range seq.SlicePairs(args) {
 		symbol, isSymbol := k.(*ast.Symbol)
 		if !isSymbol {
-			return &object.Error{
-				Err: fmt.Errorf("%s: assign can only use symbols as names, got %s %q as an argument", k.Pos().From, k.Name(), k.String()),
-			}
+			return fmtError(k.Pos(), "assign can only use symbols as names, got %s %q as an argument", k.Name(), k.String())
 		}
 
 		value := eval(v, env)
 
-		values.Elements = append(values.Elements, value)
+		values.Append(value)
 
 		env.Assign(symbol.Value, value)
 	}
@@ -103,7 +131,20 @@ func Eval(node ast.Node, env *object.Env) object.Object {
 		if ok {
 			return o
 		}
+		if env.IsStrict() {
+			return fmtError(node.PosRange, "%w", env.Undefined(node.Value))
+		}
 		return Null
+	case *ast.Keyword:
+		return object.Keyword(node.Value)
+	case *ast.Vector:
+		return evalVector(node, env, Eval)
+	case *ast.Map:
+		return evalMap(node, env, Eval)
+	case *ast.ImportGo:
+		return evalImportGo(node, env)
+	case *ast.DotSelector:
+		return evalDotSelector(node, env, Eval)
 	case *ast.If:
 		return evalIf(node, env, Eval)
 	case *ast.SpecialOp:
@@ -111,22 +152,58 @@ func Eval(node ast.Node, env *object.Env) object.Object {
 	case *ast.Package:
 		var result object.Object
 		for _, n := range node.Nodes {
+			if err := CheckCalls(n, env); err != nil {
+				return fmtError(n.Pos(), "%w", err)
+			}
+
 			result = Eval(n, env)
-			if err, isErr := result.(*object.Error); isErr {
-				return &object.Error{
-					Err: fmt.Errorf("%s: %w", n.Pos().From, err.Err),
-				}
+			switch r := result.(type) {
+			case *object.Error:
+				return fmtError(n.Pos(), "%w", r.Err)
+			case *object.Return:
+				return fmtError(n.Pos(), "%w", errReturnOutside)
 			}
 		}
 
 		return result
+	case *ast.Fn:
+		return evalFn(node, env)
+	case *ast.Defn:
+		fn := evalFn(node.Fn, env)
+		env.Assign(node.Fn.Ident.Value, fn)
+		return fn
+	case *ast.Defmacro:
+		macro := &object.Macro{Fn: evalFn(node.Fn, env)}
+		env.Assign(node.Fn.Ident.Value, macro)
+		return macro
+	case *ast.Do:
+		return evalBody(node.Body, env, Eval)
+	case *ast.Let:
+		return evalLet(node, env, Eval)
+	case *ast.Loop:
+		return evalLoop(node, env, Eval)
+	case *ast.Recur:
+		return evalRecur(node, env, Eval)
+	case *ast.Return:
+		return evalReturn(node, env, Eval)
+	case *ast.While:
+		return evalWhile(node, env, Eval)
+	case *ast.Try:
+		return evalTry(node, env, Eval)
+	case *ast.Quote:
+		return &object.AST{Node: node.Node}
+	case *ast.Quasiquote:
+		return evalQuasiquote(node, env, Eval)
+	case *ast.Unquote:
+		return fmtError(node.Pos(), "%s outside of quasiquote", node.Name())
 	case *ast.SExp:
-		return apply(node.Items[0], node.Items[1:], env, Eval)
+		if len(node.Items) == 0 {
+			return fmtError(node.Pos(), "empty list can't be evaluated")
+		}
+		return apply(node.PosRange, node.Items[0], node.Items[1:], env, Eval)
 	}
 
-	return &object.Error{
-		Err: fmt.Errorf("unexpected node %s", node.Name()),
-	}
+	return fmtError(node.Pos(), "unexpected node %s", node.Name())
 }
 
 func builtinApply(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
@@ -134,13 +211,46 @@ func builtinApply(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Obje
 		return fmtError(sexp.Pos(), "apply got %d arguments, but expects 2: a function and an array", len(sexp.Items))
 	}
 
-	return apply(sexp.Items[0], sexp.Items[1:], env, eval)
+	return apply(sexp.Pos(), sexp.Items[0], sexp.Items[1:], env, eval)
 }
 
-func apply(fn ast.Node, args []ast.Node, env *object.Env, eval object.Eval) object.Object {
-	head := Eval(fn, env)
+func apply(pos ast.PosRange, fn ast.Node, args []ast.Node, env *object.Env, eval object.Eval) object.Object {
+	if method, ok := methodName(fn, env); ok {
+		return evalMethodCall(pos, method, args, env, eval)
+	}
 
+	return applyValue(pos, fn, Eval(fn, env), args, env, eval)
+}
+
+// Apply calls an already evaluated head of the form (fn args...) with unevaluated arguments.
+// Builtins and macros get argument forms as is, functions get evaluated arguments.
+func Apply(pos ast.PosRange, fn ast.Node, head object.Object, args []ast.Node, env *object.Env) object.Object {
+	return applyValue(pos, fn, head, args, env, Eval)
+}
+
+func applyValue(pos ast.PosRange, fn ast.Node, head object.Object, args []ast.Node, env *object.Env, eval object.Eval) object.Object {
 	switch head := head.(type) {
+	case *object.Error:
+		return head
+	case *object.Macro:
+		return applyMacro(&ast.SExp{
+			PosRange: pos,
+			Items:    append([]ast.Node{fn}, args...),
+		}, head, env)
+	case *object.Function:
+		values, err := seq.CollectErr(resolveMany(args, env, eval))
+		if err != nil {
+			return fmtError(pos, "%w", withContext(err, "function arguments"))
+		}
+
+		return call(pos, head, values, eval)
+	case object.Callable:
+		values, err := seq.CollectErr(resolveMany(args, env, eval))
+		if err != nil {
+			return fmtError(pos, "%w", withContext(err, "function arguments"))
+		}
+
+		return head.Call(pos, values)
 	case *object.Builtin:
 		return head.Fn(&ast.SExp{
 			PosRange: fn.Pos(),
@@ -157,6 +267,10 @@ func apply(fn ast.Node, args []ast.Node, env *object.Env, eval object.Eval) obje
 		}
 		o, _ := head.Env.LookUp(key.Value)
 		return o
+	case *object.Map, *object.Set, object.Keyword:
+		return applyLookup(pos, head, args, env, eval)
+	case *object.GoValue:
+		return applyGoValue(pos, head, args, env, eval)
 	case *object.Array:
 		if len(args) == 0 {
 			return head
@@ -168,21 +282,112 @@ func apply(fn ast.Node, args []ast.Node, env *object.Env, eval object.Eval) obje
 
 		idx, ok := index.(*object.Primitive[int64])
 		if !ok {
-			return fmtError(args[0].Pos(), "evaluating array index: want a int64, got %s", index.Kind())
+			return fmtError(args[0].Pos(), "evaluating array index: %w",
+				object.WithKind(object.ErrType, fmt.Errorf("want a int64, got %s", index.Kind())))
 		}
 
-		if idx.Value < 0 || idx.Value >= int64(len(head.Elements)) {
-			return fmtError(args[0].Pos(), "array index %d is out of bounds 0..%d", idx.Value, len(head.Elements))
+		element, ok := head.At(int(idx.Value))
+		if !ok {
+			return fmtError(args[0].Pos(), "%w", errOutOfBounds(idx.Value, head.Len()))
 		}
 
-		return head.Elements[idx.Value]
+		return element
+	}
+
+	return fmtError(pos, "unexpected apply argument %s %s", head.Kind(), head.Inspect())
+}
+
+func evalFn(node *ast.Fn, env *object.Env) *object.Function {
+	fn := &object.Function{
+		Parameters: node.Params,
+		Rest:       node.Rest,
+		Body:       node.Body,
+		Env:        env,
 	}
 
-	return &object.Error{
-		Err: fmt.Errorf("unexpected apply argument %T %s", head, head.Inspect()),
+	if node.Ident != nil {
+		fn.Name = node.Ident.Value
+		fn.Env = env.Child().Assign(fn.Name, fn)
+	}
+
+	return fn
+}
+
+// Call calls a function with evaluated arguments.
+func Call(pos ast.PosRange, fn *object.Function, args []object.Object) object.Object {
+	return call(pos, fn, args, Eval)
+}
+
+// call binds arguments in a child of the function closure env and evaluates the body.
+// A recur in the tail position of the body restarts the call with new arguments
+// without growing the Go stack.
+func call(pos ast.PosRange, fn *object.Function, args []object.Object, eval object.Eval) object.Object {
+	for {
+		if err := checkArity(fn, len(args)); err != nil {
+			return fmtError(pos, "%w", err)
+		}
+
+		scope := fn.Env.Child()
+
+		for i, param := range fn.Parameters {
+			scope.Assign(param.Value, args[i])
+		}
+
+		if fn.Rest != nil {
+			scope.Assign(fn.Rest.Value, object.NewArray(args[len(fn.Parameters):]...))
+		}
+
+		result := evalBody(fn.Body, scope, eval)
+
+		switch r := result.(type) {
+		case *object.Error:
+			return r.WithFrame(fn.Name, pos)
+		case *object.Return:
+			return r.Value
+		}
+
+		next, isRecur := result.(*recurValue)
+		if !isRecur {
+			return result
+		}
+
+		args = next.args
 	}
 }
 
+func checkArity(fn *object.Function, n int) error {
+	name := fn.Name
+	if name == "" {
+		name = "anonymous function"
+	}
+
+	want := len(fn.Parameters)
+
+	switch {
+	case fn.Rest == nil && n != want:
+		return object.WithKind(object.ErrArity, fmt.Errorf("%s expects %d arguments, got %d", name, want, n))
+	case fn.Rest != nil && n < want:
+		return object.WithKind(object.ErrArity, fmt.Errorf("%s expects at least %d arguments, got %d", name, want, n))
+	}
+
+	return nil
+}
+
+// evalBody evaluates nodes one by one and returns the last result.
+// Evaluation stops at the first error or return.
+func evalBody(nodes []ast.Node, env *object.Env, eval object.Eval) object.Object {
+	var result object.Object = Null
+
+	for _, node := range nodes {
+		result = eval(node, env)
+		if asError(result) != nil || isReturn(result) {
+			return result
+		}
+	}
+
+	return result
+}
+
 func isSymbolName(node ast.Node, name string) bool {
 	symbol, ok := node.(*ast.Symbol)
 	return ok && symbol.Value == name
@@ -195,14 +400,14 @@ func isNode[N ast.Node](node ast.Node) bool {
 
 func evalSpecialOp(op *ast.SpecialOp, env *object.Env, eval object.Eval) object.Object {
 	switch op.Op {
-	case "*":
-		return multiply(op, env, eval)
-	case "+":
-		return sum(op, env)
+	case "+", "-", "*", "/", "mod", "rem":
+		return evalArithmetic(op, env, eval)
+	case "and":
+		return logical(op, env, eval, false)
+	case "or":
+		return logical(op, env, eval, true)
 	default:
-		return &object.Error{
-			Err: fmt.Errorf("%s: unexpected operation %q", op.From, op.Op),
-		}
+		return fmtError(op.Pos(), "unexpected operation %q", op.Op)
 	}
 }
 
@@ -211,10 +416,12 @@ func evalIf(op *ast.If, env *object.Env, eval object.Eval) object.Object {
 
 	var ok bool
 	switch condition := condition.(type) {
+	case *object.Error:
+		return fmtError(op.Cond.Pos(), "if condition: %w", condition.Err)
 	case *object.Primitive[bool]:
 		ok = condition.Value
 	default:
-		return fmtError(op.Pos(), "unexpected condition value: %s %q", condition.Kind(), condition.Inspect())
+		return fmtError(op.Pos(), "%w", errCondition(condition))
 	}
 
 	if ok {
@@ -228,115 +435,13 @@ func evalIf(op *ast.If, env *object.Env, eval object.Eval) object.Object {
 	return eval(op.Else, env)
 }
 
-func gt(op *ast.SExp, env *object.Env, eval object.Eval) object.Object {
-	if len(op.Items) < 2 {
-		return fmtError(op.Pos(), "< want at lease 2 arguments, got %d", len(op.Items)-1)
-	}
-
-	args := op.Items
-	var prev object.Object
-	result := true
-	for arg, err := range resolveMany(args, env, eval) {
-		if err != nil {
-			return fmtError(op.Pos(), "evaluation argument: %w", err)
-		}
-
-		if !object.IsPrimitive(arg) {
-			return fmtError(op.Pos(), "only primitive types are supported, got %s", arg.Kind())
-		}
-
-		if prev != nil && prev.Kind() != arg.Kind() {
-			return fmtError(op.Pos(), ">: type error, want %s, got %s", prev.Kind(), arg.Kind())
-		}
-
-		if prev != nil {
-			gt, ok := arg.(object.Ordered).Compare(prev)
-			if !ok {
-				return fmtError(op.Pos(), "unable to compare %s and %s", prev.Kind(), arg.Kind())
-			}
-			result = result && gt < 0
-		}
-
-		prev = arg
-	}
-
-	if result {
-		return True
-	}
-	return False
-}
-
-func sum(op *ast.SpecialOp, env *object.Env) object.Object {
-	xf, xi := 0.0, int64(0)
-	hasFloats := false
-	for i, a := range op.Items {
-		arg := Eval(a, env)
-
-		switch arg := arg.(type) {
-		case *object.Error:
-			return &object.Error{
-				Err: fmt.Errorf("%s: +: argument %d: %w", a.Pos().From, i, arg.Err),
-			}
-		case *object.Primitive[int64]:
-			xf += float64(arg.Value)
-			xi += arg.Value
-		case *object.Primitive[float64]:
-			hasFloats = true
-			xf += arg.Value
-			xi += int64(arg.Value)
-		default:
-			return &object.Error{
-				Err: fmt.Errorf("%s: +: unexpected argument %d type %s %q", a.Pos().From, i, arg.Kind(), arg.Inspect()),
-			}
-		}
-	}
-
-	if hasFloats {
-		return object.PrimitiveOf(xf)
-	}
-
-	return object.PrimitiveOf(xi)
-}
-
-func multiply(op *ast.SpecialOp, env *object.Env, eval object.Eval) object.Object {
-	xf, xi := 1.0, int64(1)
-	hasFloats := false
-	for i, a := range op.Items {
-		arg := eval(a, env)
-
-		switch arg := arg.(type) {
-		case *object.Error:
-			return &object.Error{
-				Err: fmt.Errorf("%s: *: argument %d: %w", a.Pos().From, i, arg.Err),
-			}
-		case *object.Primitive[int64]:
-			xf *= float64(arg.Value)
-			xi *= arg.Value
-		case *object.Primitive[float64]:
-			hasFloats = true
-			xf *= arg.Value
-			xi *= int64(arg.Value)
-		default:
-			return &object.Error{
-				Err: fmt.Errorf("%s: *: unexpected argument %d type %s %q", a.Pos().From, i, arg.Kind(), arg.Inspect()),
-			}
-		}
-	}
-
-	if hasFloats {
-		return object.PrimitiveOf(xf)
-	}
-
-	return object.PrimitiveOf(xi)
-}
-
 func resolveMany(nodes []ast.Node, env *object.Env, eval object.Eval) iter.Seq2[object.Object, error] {
 	return func(yield func(object.Object, error) bool) {
 		for i, node := range nodes {
 			result := eval(node, env)
 
 			if err, isErr := result.(*object.Error); isErr {
-				_ = yield(nil, fmt.Errorf("%s: %d: %w", node.Pos().From, i, err.Err))
+				_ = yield(nil, withContext(err.Err, "argument %d", i))
 				return
 			}
 
@@ -359,7 +464,7 @@ func resolveSkipKeys(nodes []ast.Node, env *object.Env) iter.Seq2[object.Object,
 			}
 
 			if err, isErr := result.(*object.Error); isErr {
-				_ = yield(nil, fmt.Errorf("%s: %d: %w", node.Pos().From, i, err.Err))
+				_ = yield(nil, withContext(err.Err, "argument %d", i))
 				return
 			}
 
@@ -370,11 +475,28 @@ func resolveSkipKeys(nodes []ast.Node, env *object.Env) iter.Seq2[object.Object,
 	}
 }
 
-func fmtError(pos ast.PosRange, msg string, args ...any) *object.Error {
-	args = append([]any{pos.From, pos.To}, args...)
-	return &object.Error{
-		Err: fmt.Errorf("%s-%s: "+msg, args...),
+// withContext adds the context to an error of a nested form.
+// Runtime errors already have positions and contexts, which would repeat
+// on every level of a recursion, so they are returned as is.
+func withContext(err error, msg string, args ...any) error {
+	var runtime *object.RuntimeError
+	if errors.As(err, &runtime) {
+		return err
 	}
+
+	return fmt.Errorf(msg+": %w", append(args, err)...)
+}
+
+func fmtError(pos ast.PosRange, msg string, args ...any) *object.Error {
+	return object.Errorf(pos, msg, args...)
+}
+
+func errCondition(cond object.Object) error {
+	return object.WithKind(object.ErrType, fmt.Errorf("unexpected condition value: %s %q", cond.Kind(), cond.Inspect()))
+}
+
+func errOutOfBounds(index int64, n int) error {
+	return object.WithKind(object.ErrIndex, fmt.Errorf("array index %d is out of bounds 0..%d", index, n))
 }
 
 func asError(obj object.Object) error {
@@ -0,0 +1,142 @@
+package astwalk
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// ReportAll makes the inferencer continue after type errors in bodies of forms,
+// so Check reports all of them at once.
+func (ti *TypeInferencer) ReportAll() *TypeInferencer {
+	ti.state.reportAll = true
+	return ti
+}
+
+// Check validates types of the node before evaluation: values of annotated names
+// must have their types and uses of all values must be consistent.
+// Forms of packages are checked in order, so later forms see names assigned by previous ones.
+// Check returns the first type error or all of them joined in the ReportAll mode.
+func (ti *TypeInferencer) Check(node ast.Node) error {
+	_, err := ti.infer(node)
+	return ti.state.collected(node.Pos(), err)
+}
+
+// report records the error in the ReportAll mode, so inference can continue.
+func (inf *inference) report(err error) bool {
+	if !inf.reportAll {
+		return false
+	}
+
+	inf.errs = append(inf.errs, err)
+	return true
+}
+
+// collected returns errors recorded in the ReportAll mode with the err and resets them.
+// Each error keeps its whole message and the position of the innermost error or pos.
+func (inf *inference) collected(pos ast.PosRange, err error) error {
+	errs := append(inf.errs, err)
+	inf.errs = nil
+
+	collected := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			collected = append(collected, object.Errorf(pos, "%w", err).Err)
+		}
+	}
+
+	return errors.Join(collected...)
+}
+
+// annotationType resolves a type annotation: a kind like integer,
+// an array of elements (array integer), a union (or integer null)
+// or a function (fn [integer & string] boolean).
+func annotationType(node ast.Node) (*object.Type, error) {
+	switch node := node.(type) {
+	case *ast.Symbol:
+		kind := object.Kind(node.Value)
+		if !slices.Contains(object.Kinds, kind) {
+			return nil, typeError(node.Pos(), "unknown type %s", node)
+		}
+
+		return object.TypeFor(kind), nil
+	case *ast.SExp:
+		if len(node.Items) == 0 {
+			break
+		}
+
+		switch head := node.Items[0]; {
+		case isSymbolName(head, "array") && len(node.Items) == 2:
+			elem, err := annotationType(node.Items[1])
+			if err != nil {
+				return nil, err
+			}
+
+			return &object.Type{ObjKind: TypeArray, Params: []object.Type{*elem}}, nil
+		case isSymbolName(head, "fn") && len(node.Items) == 3:
+			return annotationFuncType(node)
+		case isSymbolName(head, string(object.ObjUnion)) && len(node.Items) > 1:
+			types := make([]*object.Type, 0, len(node.Items)-1)
+			for _, item := range node.Items[1:] {
+				t, err := annotationType(item)
+				if err != nil {
+					return nil, err
+				}
+				types = append(types, t)
+			}
+
+			return object.UnionType(types...), nil
+		}
+	}
+
+	return nil, typeError(node.Pos(), "malformed type %s", node)
+}
+
+// annotationFuncType resolves a function type: (fn [params... & rest] result).
+func annotationFuncType(node *ast.SExp) (*object.Type, error) {
+	var params []ast.Node
+	switch list := node.Items[1].(type) {
+	case *ast.Vector:
+		params = list.Items
+	case *ast.SExp:
+		params = list.Items
+	default:
+		return nil, typeError(list.Pos(), "malformed parameter types %s", list)
+	}
+
+	t := object.FuncType(nil)
+
+	for i := 0; i < len(params); i++ {
+		if isSymbolName(params[i], "&") {
+			if i != len(params)-2 {
+				return nil, typeError(params[i].Pos(), "& must be followed by exactly one type")
+			}
+
+			elem, err := annotationType(params[i+1])
+			if err != nil {
+				return nil, err
+			}
+
+			t.Params = append(t.Params, object.Type{ObjKind: TypeArray, Params: []object.Type{*elem}})
+			t.Variadic = true
+			break
+		}
+
+		param, err := annotationType(params[i])
+		if err != nil {
+			return nil, err
+		}
+
+		t.Params = append(t.Params, *param)
+	}
+
+	ret, err := annotationType(node.Items[2])
+	if err != nil {
+		return nil, err
+	}
+	t.Return = ret
+
+	return t, nil
+}
@@ -0,0 +1,114 @@
+package astwalk
+
+import (
+	"github.com/ninedraft/sulisp/internal/seq"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+var collectionBuiltins = map[string]*object.Builtin{
+	"hash-set": newBuiltin(hashSet, object.TypeFor(TypeSet)),
+	"conj":     newBuiltin(conj, object.TypeFor(TypeAny)),
+	"disj":     newBuiltin(disj, object.TypeFor(TypeSet)),
+}
+
+// (hash-set value...)
+func hashSet(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	values, err := seq.CollectErr(resolveMany(sexp.Items, env, eval))
+	if err != nil {
+		return fmtError(sexp.Pos(), "%w", withContext(err, "hash-set: evaluating argument"))
+	}
+
+	set := object.NewSet()
+	for _, value := range values {
+		if err := set.Add(value); err != nil {
+			return fmtError(sexp.Pos(), "hash-set: %w", err)
+		}
+	}
+
+	return set
+}
+
+// (conj coll value...) adds values to the end of an array or to a set.
+func conj(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	args, err := seq.CollectErr(resolveMany(sexp.Items, env, eval))
+	if err != nil {
+		return fmtError(sexp.Pos(), "%w", withContext(err, "conj: evaluating argument"))
+	}
+
+	if len(args) == 0 {
+		return fmtError(sexp.Pos(), "conj want an array or a set as a first argument, got nothing")
+	}
+
+	switch coll := args[0].(type) {
+	case *object.Array:
+		array := coll.Clone()
+		array.Append(args[1:]...)
+		return array
+	case *object.Set:
+		set := coll.Clone()
+		for _, value := range args[1:] {
+			if err := set.Add(value); err != nil {
+				return fmtError(sexp.Pos(), "conj: %w", err)
+			}
+		}
+		return set
+	default:
+		return fmtError(sexp.Pos(), "conj want an array or a set as a first argument, got %s %q", coll.Kind(), coll.Inspect())
+	}
+}
+
+// (disj set value...)
+func disj(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	args, err := seq.CollectErr(resolveMany(sexp.Items, env, eval))
+	if err != nil {
+		return fmtError(sexp.Pos(), "%w", withContext(err, "disj: evaluating argument"))
+	}
+
+	if len(args) == 0 {
+		return fmtError(sexp.Pos(), "disj want a set as a first argument, got nothing")
+	}
+
+	set, ok := args[0].(*object.Set)
+	if !ok {
+		return fmtError(sexp.Pos(), "disj want a set as a first argument, got %s %q", args[0].Kind(), args[0].Inspect())
+	}
+
+	set = set.Clone()
+	for _, value := range args[1:] {
+		set.Delete(value)
+	}
+
+	return set
+}
+
+func evalVector(vec *ast.Vector, env *object.Env, eval object.Eval) object.Object {
+	elements, err := seq.CollectErr(resolveMany(vec.Items, env, eval))
+	if err != nil {
+		return fmtError(vec.PosRange, "%w", withContext(err, "vector items"))
+	}
+
+	return object.NewArray(elements...)
+}
+
+func evalMap(node *ast.Map, env *object.Env, eval object.Eval) object.Object {
+	m := object.NewMap()
+
+	for k, v := range seq.SlicePairs(node.Items) {
+		key := eval(k, env)
+		if err := asError(key); err != nil {
+			return fmtError(k.Pos(), "map key: %w", err)
+		}
+
+		value := eval(v, env)
+		if err := asError(value); err != nil {
+			return fmtError(v.Pos(), "map value %s: %w", key.Inspect(), err)
+		}
+
+		if err := m.Set(key, value); err != nil {
+			return fmtError(k.Pos(), "%w", err)
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,82 @@
+package astwalk
+
+import (
+	"fmt"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+var comparisons = map[string]object.BuiltinFn{
+	"<":    ordered("<", func(c int) bool { return c < 0 }),
+	"<=":   ordered("<=", func(c int) bool { return c <= 0 }),
+	">":    ordered(">", func(c int) bool { return c > 0 }),
+	">=":   ordered(">=", func(c int) bool { return c >= 0 }),
+	"=":    equal("=", true),
+	"not=": equal("not=", false),
+}
+
+// ordered builds a chained comparison: (< a b c) is true if a < b and b < c.
+func ordered(name string, ok func(cmp int) bool) object.BuiltinFn {
+	return func(op *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+		if len(op.Items) < 2 {
+			return fmtError(op.Pos(), "%w",
+				object.WithKind(object.ErrArity, fmt.Errorf("%s want at least 2 arguments, got %d", name, len(op.Items))))
+		}
+
+		var prev object.Object
+		result := true
+		for arg, err := range resolveMany(op.Items, env, eval) {
+			if err != nil {
+				return fmtError(op.Pos(), "%w", withContext(err, "%s: evaluating argument", name))
+			}
+
+			if prev != nil {
+				cmp, comparable := object.Compare(prev, arg)
+				if !comparable {
+					return fmtError(op.Pos(), "%s: %w", name,
+						object.WithKind(object.ErrType, fmt.Errorf("unable to compare %s and %s", prev.Kind(), arg.Kind())))
+				}
+				result = result && ok(cmp)
+			}
+
+			prev = arg
+		}
+
+		return boolean(result)
+	}
+}
+
+// equal builds a chained structural equality check.
+// If want is false, then the result is negated: (not= a b c) is (not (= a b c)).
+func equal(name string, want bool) object.BuiltinFn {
+	return func(op *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+		if len(op.Items) < 2 {
+			return fmtError(op.Pos(), "%w",
+				object.WithKind(object.ErrArity, fmt.Errorf("%s want at least 2 arguments, got %d", name, len(op.Items))))
+		}
+
+		var prev object.Object
+		result := true
+		for arg, err := range resolveMany(op.Items, env, eval) {
+			if err != nil {
+				return fmtError(op.Pos(), "%w", withContext(err, "%s: evaluating argument", name))
+			}
+
+			if prev != nil {
+				result = result && object.Equal(prev, arg)
+			}
+
+			prev = arg
+		}
+
+		return boolean(result == want)
+	}
+}
+
+func boolean(value bool) *object.Primitive[bool] {
+	if value {
+		return True
+	}
+	return False
+}
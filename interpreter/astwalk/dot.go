@@ -0,0 +1,155 @@
+package astwalk
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ninedraft/sulisp/internal/seq"
+	"github.com/ninedraft/sulisp/interpreter/gopkg"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// evalDotSelector resolves a name in a namespace: ns.name,
+// or a field or a method of a Go value: value.Field.
+// Selectors are left associative, so ns.sub.name looks up sub first.
+func evalDotSelector(dot *ast.DotSelector, env *object.Env, eval object.Eval) object.Object {
+	left := eval(dot.Left, env)
+	if err := asError(left); err != nil {
+		return left
+	}
+
+	name, isSymbol := dot.Right.(*ast.Symbol)
+	if !isSymbol {
+		return fmtError(dot.Right.Pos(), "can use only symbols as selectors, used a %s %q", dot.Right.Name(), dot.Right)
+	}
+
+	switch left := left.(type) {
+	case *object.Namespace:
+		value, ok := left.Env.LookUp(name.Value)
+		if !ok {
+			return fmtError(dot.Pos(), "namespace %s has no member %s", dot.Left, name.Value)
+		}
+
+		return value
+	case *object.GoValue:
+		value, err := gopkg.Field(left, name.Value)
+		if err != nil {
+			return fmtError(dot.Pos(), "%s.%s: %w", dot.Left, name.Value, err)
+		}
+
+		return value
+	default:
+		return fmtError(dot.Pos(), "can select only from namespaces and Go values, got %s %q", left.Kind(), left.Inspect())
+	}
+}
+
+// applyGoValue calls a Go function or gets an element of a Go collection by index or key.
+func applyGoValue(pos ast.PosRange, head *object.GoValue, args []ast.Node, env *object.Env, eval object.Eval) object.Object {
+	values, err := seq.CollectErr(resolveMany(args, env, eval))
+	if err != nil {
+		return fmtError(pos, "%w", withContext(err, "arguments"))
+	}
+
+	if head.Value.Kind() == reflect.Func {
+		result, err := gopkg.Call(head.Value, values)
+		if err != nil {
+			return fmtError(pos, "calling Go %s: %w", head.Value.Type(), err)
+		}
+
+		return result
+	}
+
+	if len(values) != 1 {
+		return fmtError(pos, "Go %s index want 1 argument, got %d", head.Value.Type(), len(values))
+	}
+
+	result, err := gopkg.Index(head, values[0])
+	if err != nil {
+		return fmtError(args[0].Pos(), "%w", err)
+	}
+
+	return result
+}
+
+// (go-value x) converts an object to a Go value.
+func builtinGoValue(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	if len(sexp.Items) != 1 {
+		return fmtError(sexp.Pos(), "go-value want 1 argument, got %d", len(sexp.Items))
+	}
+
+	arg := eval(sexp.Items[0], env)
+	if err := asError(arg); err != nil {
+		return fmtError(sexp.Pos(), "go-value: %w", err)
+	}
+
+	wrapped, err := gopkg.Wrap(arg)
+	if err != nil {
+		return fmtError(sexp.Pos(), "go-value: %w", err)
+	}
+
+	return wrapped
+}
+
+// (from-go x) converts a Go value to primitives, arrays and maps if possible.
+func builtinFromGo(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	if len(sexp.Items) != 1 {
+		return fmtError(sexp.Pos(), "from-go want 1 argument, got %d", len(sexp.Items))
+	}
+
+	arg := eval(sexp.Items[0], env)
+	if err := asError(arg); err != nil {
+		return fmtError(sexp.Pos(), "from-go: %w", err)
+	}
+
+	wrapped, ok := arg.(*object.GoValue)
+	if !ok {
+		return arg
+	}
+
+	result, err := gopkg.Unwrap(wrapped.Value)
+	if err != nil {
+		return fmtError(sexp.Pos(), "from-go: %w", err)
+	}
+
+	return result
+}
+
+// methodName returns a name of a method for heads like .Len.
+func methodName(head ast.Node, env *object.Env) (string, bool) {
+	symbol, ok := head.(*ast.Symbol)
+	if !ok {
+		return "", false
+	}
+
+	name, ok := strings.CutPrefix(symbol.Value, ".")
+	if !ok || name == "" {
+		return "", false
+	}
+
+	// bound names are called as usual
+	if _, bound := env.LookUp(symbol.Value); bound {
+		return "", false
+	}
+
+	return name, true
+}
+
+// evalMethodCall calls a method of a Go value: (.Method value args...).
+func evalMethodCall(pos ast.PosRange, name string, args []ast.Node, env *object.Env, eval object.Eval) object.Object {
+	if len(args) == 0 {
+		return fmtError(pos, "method call .%s needs a receiver", name)
+	}
+
+	values, err := seq.CollectErr(resolveMany(args, env, eval))
+	if err != nil {
+		return fmtError(pos, "%w", withContext(err, ".%s arguments", name))
+	}
+
+	result, err := gopkg.CallMethod(values[0], name, values[1:])
+	if err != nil {
+		return fmtError(pos, ".%s: %w", name, err)
+	}
+
+	return result
+}
@@ -0,0 +1,205 @@
+package astwalk
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ninedraft/sulisp/internal/seq"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+var exceptionBuiltins = map[string]*object.Builtin{
+	"throw":      newBuiltin(throw, object.FuncType(object.VarType("b"), *object.VarType("a"))),
+	"ex-info":    newBuiltin(exInfo, object.TypeFor(object.ObjException)),
+	"ex-message": newBuiltin(exMessage, object.FuncType(object.TypeFor(TypeString), *object.TypeFor(object.ObjException))),
+	"ex-data":    newBuiltin(exData, object.FuncType(object.TypeFor(TypeAny), *object.TypeFor(object.ObjException))),
+	"ex-kind":    newBuiltin(exKind, object.FuncType(object.TypeFor(object.ObjKeyword), *object.TypeFor(object.ObjException))),
+}
+
+// catchAll is a kind of catch clauses, which handle any error.
+const catchAll = ":default"
+
+// caughtName binds a caught value for a predicate call of a catch clause.
+// It's not a valid symbol, so it can't shadow user names.
+const caughtName = " caught"
+
+// evalTry evaluates the body and passes its error to the first matching catch clause.
+// Unhandled errors are returned as is. The finally body is evaluated at last,
+// its error or return replaces the result.
+func evalTry(try *ast.Try, env *object.Env, eval object.Eval) object.Object {
+	result := evalBody(try.Body, env, eval)
+
+	if err, isErr := result.(*object.Error); isErr {
+		result = handle(try, err, env, eval)
+	}
+
+	if try.Finally != nil {
+		if final := evalBody(try.Finally, env, eval); asError(final) != nil || isReturn(final) {
+			return final
+		}
+	}
+
+	return result
+}
+
+func handle(try *ast.Try, err *object.Error, env *object.Env, eval object.Eval) object.Object {
+	value, kind := caught(err)
+
+	for _, catch := range try.Catches {
+		ok, errMatch := matches(catch, value, kind, env, eval)
+		if errMatch != nil {
+			return errMatch
+		}
+
+		if ok {
+			scope := env.Child()
+			scope.Assign(catch.Ident.Value, value)
+
+			return evalBody(catch.Body, scope, eval)
+		}
+	}
+
+	return err
+}
+
+// matches reports if the catch clause handles the value.
+// Keywords are matched with the error kind, other forms must be predicates of the value.
+func matches(catch ast.Catch, value object.Object, kind object.ErrorKind, env *object.Env, eval object.Eval) (bool, *object.Error) {
+	if kw, ok := catch.Match.(*ast.Keyword); ok {
+		return kw.Value == catchAll || kw.Value == ":"+string(kind), nil
+	}
+
+	scope := env.Child()
+	scope.Assign(caughtName, value)
+
+	result := apply(catch.Pos(), catch.Match, []ast.Node{&ast.Symbol{PosRange: catch.Ident.PosRange, Value: caughtName}}, scope, eval)
+	switch result := result.(type) {
+	case *object.Error:
+		return false, fmtError(catch.Match.Pos(), "catch predicate: %w", result.Err)
+	case *object.Primitive[bool]:
+		return result.Value, nil
+	default:
+		return false, fmtError(catch.Match.Pos(), "catch predicate: %w",
+			object.WithKind(object.ErrType, fmt.Errorf("want a boolean, got %s %q", result.Kind(), result.Inspect())))
+	}
+}
+
+// caught returns a value raised by throw or the error as an exception.
+func caught(err *object.Error) (object.Object, object.ErrorKind) {
+	var thrown *object.ThrownError
+	if errors.As(err.Err, &thrown) {
+		return thrown.Value, object.ErrThrown
+	}
+
+	var runtime *object.RuntimeError
+	if !errors.As(err.Err, &runtime) {
+		runtime = &object.RuntimeError{Kind: object.KindOf(err.Err), Err: err.Err}
+	}
+
+	return &object.Exception{Err: runtime}, runtime.Kind
+}
+
+// (throw value) raises the value, exceptions are raised with their original positions.
+func throw(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	if len(sexp.Items) != 1 {
+		return fmtError(sexp.Pos(), "throw want 1 argument, got %d", len(sexp.Items))
+	}
+
+	switch value := eval(sexp.Items[0], env).(type) {
+	case *object.Error:
+		return value
+	case *object.Exception:
+		return &object.Error{Err: value.Err}
+	default:
+		return fmtError(sexp.Pos(), "%w", object.WithKind(object.ErrThrown, &object.ThrownError{Value: value}))
+	}
+}
+
+// (ex-info message) or (ex-info message data) creates an exception with a map of data.
+func exInfo(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	args, err := seq.CollectErr(resolveMany(sexp.Items, env, eval))
+	if err != nil {
+		return fmtError(sexp.Pos(), "%w", withContext(err, "ex-info: evaluating argument"))
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return fmtError(sexp.Pos(), "ex-info want a message and an optional map of data, got %d arguments", len(args))
+	}
+
+	msg, ok := args[0].(*object.Primitive[string])
+	if !ok {
+		return fmtError(sexp.Pos(), "ex-info want a string message, got %s %q", args[0].Kind(), args[0].Inspect())
+	}
+
+	info := &object.InfoError{Message: object.Unquote(msg.Value), Data: Null}
+
+	if len(args) == 2 {
+		switch data := args[1].(type) {
+		case *object.Map, object.Null:
+			info.Data = data
+		default:
+			return fmtError(sexp.Pos(), "ex-info want a map of data, got %s %q", data.Kind(), data.Inspect())
+		}
+	}
+
+	return &object.Exception{Err: &object.RuntimeError{
+		Kind: object.ErrInfo,
+		Pos:  sexp.Pos(),
+		Err:  info,
+	}}
+}
+
+// (ex-message e) returns a message of the exception.
+func exMessage(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	exc, errArg := exceptionArg("ex-message", sexp, env, eval)
+	if errArg != nil {
+		return errArg
+	}
+
+	return object.PrimitiveOf(strconv.Quote(exc.Err.Error()))
+}
+
+// (ex-data e) returns a map of data of the exception created by ex-info or null.
+func exData(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	exc, errArg := exceptionArg("ex-data", sexp, env, eval)
+	if errArg != nil {
+		return errArg
+	}
+
+	var info *object.InfoError
+	if !errors.As(exc.Err, &info) {
+		return Null
+	}
+
+	return info.Data
+}
+
+// (ex-kind e) returns a kind of the exception as a keyword: :type-error, :ex-info and so on.
+func exKind(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	exc, errArg := exceptionArg("ex-kind", sexp, env, eval)
+	if errArg != nil {
+		return errArg
+	}
+
+	return object.Keyword(":" + string(exc.Err.Kind))
+}
+
+func exceptionArg(name string, sexp *ast.SExp, env *object.Env, eval object.Eval) (*object.Exception, *object.Error) {
+	if len(sexp.Items) != 1 {
+		return nil, fmtError(sexp.Pos(), "%s want 1 argument, got %d", name, len(sexp.Items))
+	}
+
+	arg := eval(sexp.Items[0], env)
+	if err := asError(arg); err != nil {
+		return nil, fmtError(sexp.Pos(), "%s: evaluating argument: %w", name, err)
+	}
+
+	exc, ok := arg.(*object.Exception)
+	if !ok {
+		return nil, fmtError(sexp.Pos(), "%s want an exception, got %s %q", name, arg.Kind(), arg.Inspect())
+	}
+
+	return exc, nil
+}
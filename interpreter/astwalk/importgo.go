@@ -0,0 +1,52 @@
+package astwalk
+
+import (
+	"github.com/ninedraft/sulisp/interpreter/gopkg"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// evalImportGo binds registered Go packages to namespaces:
+//
+//	(import-go "strings" fmt (str "strings"))
+//
+// A package is bound by its name, an alias can be set by a (alias path) item.
+func evalImportGo(node *ast.ImportGo, env *object.Env) object.Object {
+	for _, item := range node.Items {
+		name, path := "", ""
+
+		switch item := item.(type) {
+		case *ast.Literal[string]:
+			path = object.Unquote(item.Value)
+		case *ast.Symbol:
+			path = item.Value
+		case *ast.SExp:
+			// (alias path), checked by the parser
+			name = item.Items[0].String()
+			path = item.Items[1].String()
+			if lit, ok := item.Items[1].(*ast.Literal[string]); ok {
+				path = object.Unquote(lit.Value)
+			}
+		default:
+			return fmtError(item.Pos(), "unexpected import-go item %s %q", item.Name(), item)
+		}
+
+		pkg, ok := gopkg.Default.Lookup(path)
+		if !ok {
+			return fmtError(item.Pos(), "go package %q is not registered", path)
+		}
+
+		if name == "" {
+			name = pkg.Name
+		}
+
+		ns, err := pkg.Namespace()
+		if err != nil {
+			return fmtError(item.Pos(), "importing %q: %w", path, err)
+		}
+
+		env.Assign(name, ns)
+	}
+
+	return Null
+}
@@ -0,0 +1,47 @@
+package astwalk
+
+import (
+	"fmt"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// logical evaluates operands until one of them is equal to stop.
+// (and a b) stops at the first false, (or a b) stops at the first true.
+// Operands after the stop value are not evaluated.
+func logical(op *ast.SpecialOp, env *object.Env, eval object.Eval, stop bool) object.Object {
+	for i, a := range op.Items {
+		arg := eval(a, env)
+
+		switch arg := arg.(type) {
+		case *object.Error:
+			return fmtError(a.Pos(), "%w", withContext(arg.Err, "%s: argument %d", op.Op, i))
+		case *object.Primitive[bool]:
+			if arg.Value == stop {
+				return boolean(stop)
+			}
+		default:
+			return fmtError(a.Pos(), "%s: %w", op.Op,
+				object.WithKind(object.ErrType, fmt.Errorf("argument %d must be a boolean, got %s %q", i, arg.Kind(), arg.Inspect())))
+		}
+	}
+
+	return boolean(!stop)
+}
+
+func not(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	if len(sexp.Items) != 1 {
+		return fmtError(sexp.Pos(), "not want 1 argument, got %d", len(sexp.Items))
+	}
+
+	switch arg := eval(sexp.Items[0], env).(type) {
+	case *object.Error:
+		return fmtError(sexp.Pos(), "%w", withContext(arg.Err, "not"))
+	case *object.Primitive[bool]:
+		return boolean(!arg.Value)
+	default:
+		return fmtError(sexp.Pos(), "not: %w",
+			object.WithKind(object.ErrType, fmt.Errorf("argument must be a boolean, got %s %q", arg.Kind(), arg.Inspect())))
+	}
+}
@@ -0,0 +1,121 @@
+package astwalk
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ninedraft/sulisp/internal/seq"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+const kindRecur object.Kind = "recur"
+
+// recurValue carries new binding values from a recur form to the enclosing loop or fn.
+// The parser guarantees that recur is in a tail position,
+// so the value is always returned as a result of the loop or fn body.
+type recurValue struct {
+	args []object.Object
+}
+
+func (*recurValue) Kind() object.Kind { return kindRecur }
+
+func (recur *recurValue) Inspect() string {
+	str := &strings.Builder{}
+	str.WriteString("(recur")
+
+	for _, arg := range recur.args {
+		str.WriteString(" ")
+		str.WriteString(arg.Inspect())
+	}
+
+	str.WriteString(")")
+	return str.String()
+}
+
+var errReturnOutside = errors.New("return outside of fn")
+
+// evalReturn wraps the value, so it's passed through enclosing forms up to the call of the fn.
+// The parser guarantees that return is in a body position of a fn.
+func evalReturn(ret *ast.Return, env *object.Env, eval object.Eval) object.Object {
+	if ret.Value == nil {
+		return &object.Return{Value: Null}
+	}
+
+	value := eval(ret.Value, env)
+	if err := asError(value); err != nil {
+		return fmtError(ret.Pos(), "return: %w", err)
+	}
+
+	return &object.Return{Value: value}
+}
+
+func isReturn(obj object.Object) bool {
+	_, ok := obj.(*object.Return)
+	return ok
+}
+
+func evalRecur(recur *ast.Recur, env *object.Env, eval object.Eval) object.Object {
+	args, err := seq.CollectErr(resolveMany(recur.Args, env, eval))
+	if err != nil {
+		return fmtError(recur.Pos(), "%w", withContext(err, "recur arguments"))
+	}
+
+	return &recurValue{args: args}
+}
+
+// evalLoop evaluates the loop body until it returns a value other than recur.
+// Each iteration gets a fresh scope, so closures capture values of their own iteration.
+func evalLoop(loop *ast.Loop, env *object.Env, eval object.Eval) object.Object {
+	scope := env.Child()
+
+	for _, binding := range loop.Bindings {
+		value := eval(binding.Value, scope)
+		if err := asError(value); err != nil {
+			return fmtError(binding.Value.Pos(), "loop binding %s: %w", binding.Target, err)
+		}
+
+		if err := bind(binding.Target, value, scope); err != nil {
+			return err
+		}
+	}
+
+	for {
+		result := evalBody(loop.Body, scope, eval)
+
+		next, isRecur := result.(*recurValue)
+		if !isRecur {
+			return result
+		}
+
+		if len(next.args) != len(loop.Bindings) {
+			return fmtError(loop.Pos(), "recur expects %d arguments, got %d", len(loop.Bindings), len(next.args))
+		}
+
+		scope = env.Child()
+		for i, binding := range loop.Bindings {
+			if err := bind(binding.Target, next.args[i], scope); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func evalWhile(while *ast.While, env *object.Env, eval object.Eval) object.Object {
+	for {
+		switch cond := eval(while.Cond, env).(type) {
+		case *object.Error:
+			return fmtError(while.Cond.Pos(), "while condition: %w", cond.Err)
+		case *object.Primitive[bool]:
+			if !cond.Value {
+				return Null
+			}
+		default:
+			return fmtError(while.Cond.Pos(), "%w", errCondition(cond))
+		}
+
+		if result := evalBody(while.Body, env, eval); asError(result) != nil || isReturn(result) {
+			return result
+		}
+	}
+}
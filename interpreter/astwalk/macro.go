@@ -0,0 +1,255 @@
+package astwalk
+
+import (
+	"errors"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+	"github.com/ninedraft/sulisp/parser"
+)
+
+// Expand is a macro expansion pass, which must be run between parsing and evaluation.
+// It registers macros defined by defmacro forms in the env
+// and replaces macro calls with results of macro functions.
+// Top level forms are expanded one by one, so a macro can be used right after its definition.
+func Expand(node ast.Node, env *object.Env) (ast.Node, error) {
+	if env == nil {
+		env = DefaultEnv()
+	}
+
+	ex := &expander{env: env}
+
+	pkg, isPkg := node.(*ast.Package)
+	if !isPkg {
+		return ex.expandTop(node)
+	}
+
+	expanded := &ast.Package{
+		PosRange: pkg.PosRange,
+		Nodes:    make([]ast.Node, 0, len(pkg.Nodes)),
+	}
+
+	for _, n := range pkg.Nodes {
+		n, err := ex.expandTop(n)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded.Nodes = append(expanded.Nodes, n)
+	}
+
+	return expanded, nil
+}
+
+// CheckCalls verifies recur and return forms in arguments of calls,
+// which the parser skips, as they can be macro calls.
+// Calls of macros and unbound names, which can be macros defined later, are still skipped.
+func CheckCalls(node ast.Node, env *object.Env) error {
+	return parser.CheckCalls(node, func(name string) bool {
+		value, ok := env.LookUp(name)
+		_, isMacro := value.(*object.Macro)
+		return !ok || isMacro
+	})
+}
+
+type expander struct {
+	env *object.Env
+	err *object.Error
+}
+
+func (ex *expander) expandTop(node ast.Node) (ast.Node, error) {
+	expanded := ex.expand(node)
+	if ex.err != nil {
+		return nil, ex.err.Err
+	}
+
+	// parser skips recur and return checks in macro calls
+	if err := errors.Join(parser.CheckRecur(expanded), parser.CheckReturn(expanded)); err != nil {
+		return nil, err
+	}
+
+	// modules are required before expansion of the following forms,
+	// so their macros can be used
+	if sexp, ok := expanded.(*ast.SExp); ok && len(sexp.Items) > 0 && isSymbolName(sexp.Items[0], "require") {
+		if err := asError(Eval(sexp, ex.env)); err != nil {
+			return nil, err
+		}
+	}
+
+	return expanded, nil
+}
+
+func (ex *expander) expand(node ast.Node) ast.Node {
+	if ex.err != nil {
+		return node
+	}
+
+	switch node := node.(type) {
+	case *ast.Quote:
+		return node
+	case *ast.Quasiquote:
+		clone := *node
+		clone.Node = ex.expandUnquoted(node.Node)
+		return &clone
+	case *ast.Defmacro:
+		expanded := ast.MapChildren(node, ex.expand)
+		if err := asError(Eval(expanded, ex.env)); err != nil {
+			ex.err = fmtError(node.Pos(), "defining macro %s: %w", node.Fn.Ident, err)
+		}
+		return expanded
+	case *ast.SExp:
+		macro := macroOf(node, ex.env)
+		if macro == nil {
+			break
+		}
+
+		expanded, err := expandMacro(node, macro)
+		if err != nil {
+			ex.err = err
+			return node
+		}
+
+		return ex.expand(expanded)
+	}
+
+	return ast.MapChildren(node, ex.expand)
+}
+
+// expandUnquoted expands macros in unquoted forms of a quasiquote template.
+func (ex *expander) expandUnquoted(node ast.Node) ast.Node {
+	switch node := node.(type) {
+	case *ast.Unquote:
+		clone := *node
+		clone.Node = ex.expand(node.Node)
+		return &clone
+	case *ast.Quasiquote:
+		return node
+	default:
+		return ast.MapChildren(node, ex.expandUnquoted)
+	}
+}
+
+func macroOf(sexp *ast.SExp, env *object.Env) *object.Macro {
+	if len(sexp.Items) == 0 {
+		return nil
+	}
+
+	head, ok := sexp.Items[0].(*ast.Symbol)
+	if !ok {
+		return nil
+	}
+
+	value, _ := env.LookUp(head.Value)
+	macro, _ := value.(*object.Macro)
+
+	return macro
+}
+
+// expandMacro expands a macro call and turns the result into special forms.
+func expandMacro(sexp *ast.SExp, macro *object.Macro) (ast.Node, *object.Error) {
+	expanded, err := expandMacro1(sexp, macro)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzed, errAnalyze := parser.Analyze(expanded)
+	if errAnalyze != nil {
+		return nil, fmtError(sexp.Pos(), "expanding macro %s: %w", sexp.Items[0], errAnalyze)
+	}
+
+	return analyzed, nil
+}
+
+// expandMacro1 calls the macro function with unevaluated arguments.
+func expandMacro1(sexp *ast.SExp, macro *object.Macro) (ast.Node, *object.Error) {
+	args := make([]object.Object, 0, len(sexp.Items)-1)
+	for _, item := range sexp.Items[1:] {
+		args = append(args, &object.AST{Node: item})
+	}
+
+	result := call(sexp.Pos(), macro.Fn, args, Eval)
+	if err := asError(result); err != nil {
+		return nil, fmtError(sexp.Pos(), "expanding macro %s: %w", sexp.Items[0], err)
+	}
+
+	return toNode(sexp.Pos(), result)
+}
+
+// applyMacro expands and evaluates a macro call, which was not expanded by Expand:
+// for example, a call built at runtime.
+func applyMacro(sexp *ast.SExp, macro *object.Macro, env *object.Env) object.Object {
+	expanded, err := expandMacro(sexp, macro)
+	if err != nil {
+		return err
+	}
+
+	expanded, errExpand := Expand(expanded, env)
+	if errExpand != nil {
+		return fmtError(sexp.Pos(), "%w", errExpand)
+	}
+
+	return Eval(expanded, env)
+}
+
+func macroexpand(once bool) object.BuiltinFn {
+	return func(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+		if len(sexp.Items) != 1 {
+			return fmtError(sexp.Pos(), "macroexpand want 1 argument, got %d", len(sexp.Items))
+		}
+
+		form := eval(sexp.Items[0], env)
+		if err := asError(form); err != nil {
+			return fmtError(sexp.Pos(), "macroexpand: %w", err)
+		}
+
+		quoted, ok := form.(*object.AST)
+		if !ok {
+			return fmtError(sexp.Pos(), "macroexpand: want a quoted form, got %s", form.Kind())
+		}
+
+		node := quoted.Node
+		for {
+			call, isCall := node.(*ast.SExp)
+			if !isCall {
+				break
+			}
+
+			macro := macroOf(call, env)
+			if macro == nil {
+				break
+			}
+
+			expanded, err := expandMacro1(call, macro)
+			if err != nil {
+				return err
+			}
+
+			node = expanded
+
+			if once {
+				break
+			}
+		}
+
+		return &object.AST{Node: node}
+	}
+}
+
+func builtinGensym(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	prefix := "G"
+
+	if len(sexp.Items) > 0 {
+		arg := eval(sexp.Items[0], env)
+
+		str, ok := arg.(*object.Primitive[string])
+		if !ok {
+			return fmtError(sexp.Pos(), "gensym: want a string prefix, got %s", arg.Kind())
+		}
+
+		prefix = object.Unquote(str.Value)
+	}
+
+	return &object.AST{
+		Node: &ast.Symbol{PosRange: sexp.PosRange, Value: gensym(prefix)},
+	}
+}
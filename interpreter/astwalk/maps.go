@@ -0,0 +1,191 @@
+package astwalk
+
+import (
+	"github.com/ninedraft/sulisp/internal/seq"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+var mapBuiltins = map[string]*object.Builtin{
+	"get":       newBuiltin(mapGet, object.TypeFor(TypeAny)),
+	"assoc":     newBuiltin(mapAssoc, object.TypeFor(TypeMap)),
+	"dissoc":    newBuiltin(mapDissoc, object.TypeFor(TypeMap)),
+	"keys":      newBuiltin(mapKeys, object.TypeFor(TypeArray, TypeAny)),
+	"vals":      newBuiltin(mapVals, object.TypeFor(TypeArray, TypeAny)),
+	"contains?": newBuiltin(mapContains, object.TypeFor(TypeBool)),
+}
+
+// (get m key) or (get m key default)
+func mapGet(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	args, errArgs := mapArgs("get", sexp, env, eval)
+	if errArgs != nil {
+		return errArgs
+	}
+
+	if len(args) < 2 || len(args) > 3 {
+		return fmtError(sexp.Pos(), "get want 2 or 3 arguments, got %d", len(args))
+	}
+
+	return lookup(args[0].(*object.Map), args[1], args[2:])
+}
+
+// (assoc m key value key value...)
+func mapAssoc(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	args, errArgs := mapArgs("assoc", sexp, env, eval)
+	if errArgs != nil {
+		return errArgs
+	}
+
+	if len(args) < 3 || len(args)%2 != 1 {
+		return fmtError(sexp.Pos(), "assoc want a map and pairs of keys and values, got %d arguments", len(args))
+	}
+
+	m := args[0].(*object.Map).Clone()
+
+	for key, value := range seq.SlicePairs(args[1:]) {
+		if err := m.Set(key, value); err != nil {
+			return fmtError(sexp.Pos(), "assoc: %w", err)
+		}
+	}
+
+	return m
+}
+
+// (dissoc m key...)
+func mapDissoc(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	args, errArgs := mapArgs("dissoc", sexp, env, eval)
+	if errArgs != nil {
+		return errArgs
+	}
+
+	m := args[0].(*object.Map).Clone()
+
+	for _, key := range args[1:] {
+		m.Delete(key)
+	}
+
+	return m
+}
+
+// (keys m)
+func mapKeys(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	args, errArgs := mapArgs("keys", sexp, env, eval)
+	if errArgs != nil {
+		return errArgs
+	}
+
+	if len(args) != 1 {
+		return fmtError(sexp.Pos(), "keys want 1 argument, got %d", len(args))
+	}
+
+	keys := &object.Array{}
+	for key := range args[0].(*object.Map).All() {
+		keys.Append(key)
+	}
+
+	return keys
+}
+
+// (vals m)
+func mapVals(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	args, errArgs := mapArgs("vals", sexp, env, eval)
+	if errArgs != nil {
+		return errArgs
+	}
+
+	if len(args) != 1 {
+		return fmtError(sexp.Pos(), "vals want 1 argument, got %d", len(args))
+	}
+
+	vals := &object.Array{}
+	for _, value := range args[0].(*object.Map).All() {
+		vals.Append(value)
+	}
+
+	return vals
+}
+
+// (contains? m key) or (contains? set value)
+func mapContains(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	args, err := seq.CollectErr(resolveMany(sexp.Items, env, eval))
+	if err != nil {
+		return fmtError(sexp.Pos(), "%w", withContext(err, "contains?: evaluating argument"))
+	}
+
+	if len(args) != 2 {
+		return fmtError(sexp.Pos(), "contains? want 2 arguments, got %d", len(args))
+	}
+
+	switch coll := args[0].(type) {
+	case *object.Map:
+		_, ok := coll.Get(args[1])
+		return boolean(ok)
+	case *object.Set:
+		return boolean(coll.Contains(args[1]))
+	default:
+		return fmtError(sexp.Pos(), "contains? want a map or a set as a first argument, got %s %q", coll.Kind(), coll.Inspect())
+	}
+}
+
+// mapArgs evaluates arguments of a map builtin and checks, that the first one is a map.
+func mapArgs(name string, sexp *ast.SExp, env *object.Env, eval object.Eval) ([]object.Object, *object.Error) {
+	args, err := seq.CollectErr(resolveMany(sexp.Items, env, eval))
+	if err != nil {
+		return nil, fmtError(sexp.Pos(), "%w", withContext(err, "%s: evaluating argument", name))
+	}
+
+	if len(args) == 0 {
+		return nil, fmtError(sexp.Pos(), "%s want a map as a first argument, got nothing", name)
+	}
+
+	if _, ok := args[0].(*object.Map); !ok {
+		return nil, fmtError(sexp.Pos(), "%s want a map as a first argument, got %s %q", name, args[0].Kind(), args[0].Inspect())
+	}
+
+	return args, nil
+}
+
+// lookup returns a value by the key, an optional default or null.
+func lookup(m *object.Map, key object.Object, dflt []object.Object) object.Object {
+	if value, ok := m.Get(key); ok {
+		return value
+	}
+
+	if len(dflt) > 0 {
+		return dflt[0]
+	}
+
+	return Null
+}
+
+// applyLookup handles calls of maps, sets and keywords: (m :key), (set x) and (:key m),
+// all accept an optional default value.
+func applyLookup(pos ast.PosRange, head object.Object, args []ast.Node, env *object.Env, eval object.Eval) object.Object {
+	values, err := seq.CollectErr(resolveMany(args, env, eval))
+	if err != nil {
+		return fmtError(pos, "%w", withContext(err, "lookup arguments"))
+	}
+
+	if len(values) < 1 || len(values) > 2 {
+		return fmtError(pos, "%s want 1 or 2 arguments, got %d", head.Kind(), len(values))
+	}
+
+	m, key := head, values[0]
+	if _, isKeyword := head.(object.Keyword); isKeyword {
+		m, key = values[0], head
+	}
+
+	switch m := m.(type) {
+	case *object.Map:
+		return lookup(m, key, values[1:])
+	case *object.Set:
+		if m.Contains(key) {
+			return key
+		}
+		return lookup(object.NewMap(), key, values[1:])
+	case object.Null:
+		return lookup(object.NewMap(), key, values[1:])
+	default:
+		return fmtError(pos, "keyword lookup want a map or a set, got %s %q", m.Kind(), m.Inspect())
+	}
+}
@@ -0,0 +1,222 @@
+package astwalk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+	"github.com/ninedraft/sulisp/lexer"
+	"github.com/ninedraft/sulisp/parser"
+)
+
+// Extension is added to module paths without an extension.
+const Extension = ".sl"
+
+var errImportCycle = object.WithKind(object.ErrImport, errors.New("import cycle"))
+
+// modules loads files of a program.
+// Each module is evaluated once into its own namespace,
+// files being loaded are tracked to report import cycles.
+type modules struct {
+	cache   map[string]*object.Namespace
+	loading []loading
+}
+
+// loading is a file being evaluated and the form, which started the evaluation.
+type loading struct {
+	file string
+	pos  ast.PosRange
+}
+
+func newModules() *modules {
+	return &modules{
+		cache: map[string]*object.Namespace{},
+	}
+}
+
+// require evaluates a module and binds it to a namespace named after the file:
+//
+//	(require "lib/strings") ; binds the strings namespace
+//	(require "lib/strings" :only [join split])
+//
+// With :only the listed members are bound directly.
+// The path is relative to the file of the require form.
+func (mods *modules) require(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	if len(sexp.Items) != 1 && len(sexp.Items) != 3 {
+		return fmtError(sexp.Pos(), "require want a path and optional :only [names], got %d arguments", len(sexp.Items))
+	}
+
+	file, errPath := modulePath(sexp, env, eval)
+	if errPath != nil {
+		return fmtError(sexp.Pos(), "require: %w", errPath)
+	}
+
+	ns, errLoad := mods.module(sexp.Pos(), file)
+	if errLoad != nil {
+		return errLoad
+	}
+
+	if len(sexp.Items) == 1 {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		env.Assign(name, ns)
+		return ns
+	}
+
+	if !isKeyword(sexp.Items[1], ":only") {
+		return fmtError(sexp.Items[1].Pos(), "require: unexpected option %s, want :only", sexp.Items[1])
+	}
+
+	names, ok := listItems(sexp.Items[2])
+	if !ok {
+		return fmtError(sexp.Items[2].Pos(), "require: want a list of names after :only, got %s", sexp.Items[2].Name())
+	}
+
+	for _, item := range names {
+		name, isSymbol := item.(*ast.Symbol)
+		if !isSymbol {
+			return fmtError(item.Pos(), "require: want a name, got %s %q", item.Name(), item)
+		}
+
+		value, ok := ns.Env.LookUp(name.Value)
+		if !ok {
+			return fmtError(item.Pos(), "%w",
+				object.WithKind(object.ErrImport, fmt.Errorf("module %s has no member %s", file, name.Value)))
+		}
+
+		env.Assign(name.Value, value)
+	}
+
+	return ns
+}
+
+// load evaluates a file in the current env on each call and returns the last value: (load "prelude").
+func (mods *modules) load(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+	if len(sexp.Items) != 1 {
+		return fmtError(sexp.Pos(), "load want a path, got %d arguments", len(sexp.Items))
+	}
+
+	file, err := modulePath(sexp, env, eval)
+	if err != nil {
+		return fmtError(sexp.Pos(), "load: %w", err)
+	}
+
+	return mods.evalFile(sexp.Pos(), file, env)
+}
+
+// module returns a cached namespace of the file or evaluates it.
+func (mods *modules) module(pos ast.PosRange, file string) (*object.Namespace, *object.Error) {
+	if ns, ok := mods.cache[file]; ok {
+		return ns, nil
+	}
+
+	env := newEnv(mods).Child()
+
+	if err, isErr := mods.evalFile(pos, file, env).(*object.Error); isErr {
+		return nil, err
+	}
+
+	ns := &object.Namespace{Env: env}
+	mods.cache[file] = ns
+
+	return ns, nil
+}
+
+func (mods *modules) evalFile(pos ast.PosRange, file string, env *object.Env) object.Object {
+	if err := mods.checkCycle(pos, file); err != nil {
+		return err
+	}
+
+	mods.loading = append(mods.loading, loading{file: file, pos: pos})
+	defer func() { mods.loading = mods.loading[:len(mods.loading)-1] }()
+
+	pkg, err := parseFile(file)
+	if err != nil {
+		return fmtError(pos, "loading %s: %w", file, object.WithKind(object.ErrImport, err))
+	}
+
+	expanded, err := Expand(pkg, env)
+	if err != nil {
+		return fmtError(pos, "loading %s: %w", file, object.WithKind(object.ErrImport, err))
+	}
+
+	result := Eval(expanded, env)
+	if err := asError(result); err != nil {
+		return fmtError(pos, "loading %s: %w", file, err)
+	}
+
+	return result
+}
+
+// checkCycle reports a chain of forms, which lead to the file being loaded again.
+func (mods *modules) checkCycle(pos ast.PosRange, file string) *object.Error {
+	for i, l := range mods.loading {
+		if l.file != file {
+			continue
+		}
+
+		chain := make([]string, 0, len(mods.loading)-i)
+		for _, next := range mods.loading[i+1:] {
+			chain = append(chain, fmt.Sprintf("%s: requires %s", next.pos.From, next.file))
+		}
+		chain = append(chain, fmt.Sprintf("%s: requires %s", pos.From, file))
+
+		return fmtError(pos, "%w:\n\t%s", errImportCycle, strings.Join(chain, "\n\t"))
+	}
+
+	return nil
+}
+
+// modulePath evaluates the path argument and resolves it relative to the file of the form.
+func modulePath(sexp *ast.SExp, env *object.Env, eval object.Eval) (string, error) {
+	arg := eval(sexp.Items[0], env)
+	if err := asError(arg); err != nil {
+		return "", err
+	}
+
+	str, ok := arg.(*object.Primitive[string])
+	if !ok {
+		return "", fmt.Errorf("want a string path, got %s", arg.Kind())
+	}
+
+	path := object.Unquote(str.Value)
+	if filepath.Ext(path) == "" {
+		path += Extension
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(sexp.Pos().From.File), path)
+	}
+
+	return filepath.Abs(path)
+}
+
+func parseFile(file string) (*ast.Package, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parser.New(lexer.NewLexer(file, bufio.NewReader(f))).Parse()
+}
+
+func isKeyword(node ast.Node, value string) bool {
+	kw, ok := node.(*ast.Keyword)
+	return ok && kw.Value == value
+}
+
+func listItems(node ast.Node) ([]ast.Node, bool) {
+	switch node := node.(type) {
+	case *ast.Vector:
+		return node.Items, true
+	case *ast.SExp:
+		return node.Items, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,225 @@
+package astwalk
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+func evalQuasiquote(quasi *ast.Quasiquote, env *object.Env, eval object.Eval) object.Object {
+	q := &quasiquoter{
+		env:     env,
+		eval:    eval,
+		gensyms: map[string]string{},
+	}
+
+	node, err := q.unquote(quasi.Node)
+	if err != nil {
+		return err
+	}
+
+	return &object.AST{Node: node}
+}
+
+type quasiquoter struct {
+	env  *object.Env
+	eval object.Eval
+
+	// auto generated names for symbols with # suffix,
+	// they are the same within a single template
+	gensyms map[string]string
+}
+
+// unquote returns a copy of the template with unquoted forms replaced by their values.
+// Symbols like name# are replaced by unique generated names,
+// so macro bindings don't capture names of the caller.
+// Nested quasiquotes are copied as is.
+func (q *quasiquoter) unquote(node ast.Node) (ast.Node, *object.Error) {
+	env, eval := q.env, q.eval
+
+	switch node := node.(type) {
+	case *ast.Symbol:
+		name, isAuto := strings.CutSuffix(node.Value, "#")
+		if !isAuto || name == "" {
+			return ast.Clone(node), nil
+		}
+
+		if _, ok := q.gensyms[name]; !ok {
+			q.gensyms[name] = gensym(name)
+		}
+
+		clone := ast.Clone(node)
+		clone.Value = q.gensyms[name]
+
+		return clone, nil
+	case *ast.Unquote:
+		if node.Splice {
+			return nil, fmtError(node.Pos(), "%s can be used only inside of a list", node.Name())
+		}
+
+		value := eval(node.Node, env)
+		if err := asError(value); err != nil {
+			return nil, fmtError(node.Pos(), "unquote: %w", err)
+		}
+
+		return toNode(node.Pos(), value)
+	case *ast.SExp:
+		items, err := q.unquoteItems(node.Items)
+		if err != nil {
+			return nil, err
+		}
+
+		clone := *node
+		clone.Items = items
+
+		return &clone, nil
+	case *ast.Vector:
+		items, err := q.unquoteItems(node.Items)
+		if err != nil {
+			return nil, err
+		}
+
+		clone := *node
+		clone.Items = items
+
+		return &clone, nil
+	case *ast.Map:
+		items, err := q.unquoteItems(node.Items)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(items)%2 != 0 {
+			return nil, fmtError(node.Pos(), "map template must have an even number of items, got %d", len(items))
+		}
+
+		clone := *node
+		clone.Items = items
+
+		return &clone, nil
+	case *ast.DotSelector:
+		left, err := q.unquote(node.Left)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := q.unquote(node.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		clone := *node
+		clone.Left, clone.Right = left, right
+
+		return &clone, nil
+	default:
+		return ast.Clone(node), nil
+	}
+}
+
+// unquoteItems unquotes list items and splices values of ~@ forms into the list.
+func (q *quasiquoter) unquoteItems(items []ast.Node) ([]ast.Node, *object.Error) {
+	result := make([]ast.Node, 0, len(items))
+
+	for _, item := range items {
+		splice, isSplice := item.(*ast.Unquote)
+		if !isSplice || !splice.Splice {
+			node, err := q.unquote(item)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, node)
+			continue
+		}
+
+		value := q.eval(splice.Node, q.env)
+
+		switch value := value.(type) {
+		case *object.Error:
+			return nil, fmtError(splice.Pos(), "unquote splicing: %w", value.Err)
+		case *object.Array:
+			for _, element := range value.All() {
+				node, err := toNode(splice.Pos(), element)
+				if err != nil {
+					return nil, err
+				}
+
+				result = append(result, node)
+			}
+		case *object.AST:
+			sexp, ok := value.Node.(*ast.SExp)
+			if !ok {
+				return nil, fmtError(splice.Pos(), "unquote splicing: want a list, got %s %q", value.Node.Name(), value.Inspect())
+			}
+
+			result = append(result, ast.Clone(sexp).Items...)
+		default:
+			return nil, fmtError(splice.Pos(), "unquote splicing: want an array or a list, got %s %q", value.Kind(), value.Inspect())
+		}
+	}
+
+	return result, nil
+}
+
+// toNode converts a value back to a form, which evaluates to an equal value.
+func toNode(pos ast.PosRange, value object.Object) (ast.Node, *object.Error) {
+	switch value := value.(type) {
+	case *object.AST:
+		return ast.Clone(value.Node), nil
+	case *object.Primitive[int64]:
+		return &ast.Literal[int64]{PosRange: pos, Value: value.Value}, nil
+	case *object.Primitive[float64]:
+		return &ast.Literal[float64]{PosRange: pos, Value: value.Value}, nil
+	case *object.Primitive[string]:
+		return &ast.Literal[string]{PosRange: pos, Value: value.Value}, nil
+	case *object.Primitive[bool]:
+		return &ast.Literal[bool]{PosRange: pos, Value: value.Value}, nil
+	case object.Null:
+		return &ast.Symbol{PosRange: pos, Value: "null"}, nil
+	case object.Keyword:
+		return &ast.Keyword{PosRange: pos, Value: string(value)}, nil
+	case *object.Array:
+		vec := &ast.Vector{PosRange: pos}
+
+		for _, element := range value.All() {
+			node, err := toNode(pos, element)
+			if err != nil {
+				return nil, err
+			}
+			vec.Items = append(vec.Items, node)
+		}
+
+		return vec, nil
+	case *object.Map:
+		m := &ast.Map{PosRange: pos}
+
+		for key, value := range value.All() {
+			keyNode, err := toNode(pos, key)
+			if err != nil {
+				return nil, err
+			}
+
+			valueNode, err := toNode(pos, value)
+			if err != nil {
+				return nil, err
+			}
+
+			m.Items = append(m.Items, keyNode, valueNode)
+		}
+
+		return m, nil
+	default:
+		return nil, fmtError(pos, "can't use %s %q as a form", value.Kind(), value.Inspect())
+	}
+}
+
+var gensymCounter atomic.Int64
+
+// gensym generates a unique symbol name.
+func gensym(prefix string) string {
+	return fmt.Sprintf("%s__%d__auto", prefix, gensymCounter.Add(1))
+}
@@ -0,0 +1,68 @@
+package astwalk
+
+import (
+	"fmt"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// evalLet binds values sequentially in a child scope,
+// so each binding can refer to the previous ones.
+func evalLet(let *ast.Let, env *object.Env, eval object.Eval) object.Object {
+	scope := env.Child()
+
+	for _, binding := range let.Bindings {
+		value := eval(binding.Value, scope)
+		if err := asError(value); err != nil {
+			return fmtError(binding.Value.Pos(), "let binding %s: %w", binding.Target, err)
+		}
+
+		if err := bind(binding.Target, value, scope); err != nil {
+			return err
+		}
+	}
+
+	return evalBody(let.Body, scope, eval)
+}
+
+// bind assigns a value to a symbol or destructures an array into a pattern.
+// Missing array elements are bound to null.
+func bind(target ast.Node, value object.Object, env *object.Env) *object.Error {
+	switch target := target.(type) {
+	case *ast.Symbol:
+		env.Assign(target.Value, value)
+		return nil
+	case *ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return fmtError(target.Pos(), "%w",
+				object.WithKind(object.ErrType, fmt.Errorf("can't destructure %s %q as an array", value.Kind(), value.Inspect())))
+		}
+
+		for i, item := range target.Items {
+			element, ok := array.At(i)
+			if !ok {
+				element = Null
+			}
+
+			if err := bind(item, element, env); err != nil {
+				return err
+			}
+		}
+
+		if target.Rest != nil {
+			rest := &object.Array{}
+			for i, element := range array.All() {
+				if i >= len(target.Items) {
+					rest.Append(element)
+				}
+			}
+			env.Assign(target.Rest.Value, rest)
+		}
+
+		return nil
+	default:
+		return fmtError(target.Pos(), "unexpected binding target %s", target.Name())
+	}
+}
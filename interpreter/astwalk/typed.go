@@ -1,8 +1,8 @@
 package astwalk
 
 import (
-	"errors"
 	"fmt"
+	"maps"
 
 	"github.com/ninedraft/sulisp/language/ast"
 	"github.com/ninedraft/sulisp/language/object"
@@ -17,19 +17,17 @@ const (
 	TypeNull   = object.ObjNull
 	TypeArray  = object.ObjArray
 	TypeError  = object.ObjError
+	TypeFunc   = object.ObjFunc
+	TypeMap    = object.ObjMap
+	TypeSet    = object.ObjSet
 )
 
-var errType = errors.New("type inference error")
-
-// Example usage in the Eval function, or similar functions for evaluation
+// EvalWithInference checks types of the node and evaluates it, if there are no type errors.
 func EvalWithInference(node ast.Node, env *object.Env) object.Object {
-	inferencer := NewTypeInferencer(env)
-	_, err := inferencer.Infer(node)
-	if err != nil {
+	if err := NewTypeInferencer(env).Check(node); err != nil {
 		return fmtError(node.Pos(), "%w", err)
 	}
 
-	// Continue with evaluation if type inference succeeds
 	return Eval(node, env)
 }
 
@@ -46,135 +44,806 @@ func Infer(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
 	return ot
 }
 
-// TypeInferencer structure to handle type inference
+// TypeInferencer infers types of expressions by the Hindley–Milner algorithm.
+// Types of values of the env are known, values of the any type are checked at runtime.
+// Names assigned by inferred forms are remembered, so forms of a file can be inferred one by one.
 type TypeInferencer struct {
-	env *object.Env
+	env    *object.Env
+	state  *inference
+	locals map[string]*scheme // types of let bindings and fn parameters
+	ret    *object.Type       // result type of the innermost fn, return forms must match it
+	recur  []*object.Type     // types of bindings of the innermost loop or fn, nil for patterns
 }
 
 // NewTypeInferencer initializes a TypeInferencer with a given environment
 func NewTypeInferencer(env *object.Env) *TypeInferencer {
-	return &TypeInferencer{env: env}
+	return &TypeInferencer{
+		env:   env,
+		state: newInference(),
+	}
 }
 
-// Infer infers the type of an AST node
+// Infer infers the type of an AST node.
+// Type variables of the result are named a, b, c and so on: (type (fn [a] a)).
 func (ti *TypeInferencer) Infer(node ast.Node) (*object.Type, error) {
+	t, err := ti.infer(node)
+	if err := ti.state.collected(node.Pos(), err); err != nil {
+		return nil, err
+	}
+
+	return ti.state.rename(t, map[string]string{}), nil
+}
+
+func (ti *TypeInferencer) infer(node ast.Node) (*object.Type, error) {
 	switch n := node.(type) {
 	case *ast.Literal[int64]:
-		return &object.Type{
-			ObjKind: TypeInt,
-		}, nil
+		return object.TypeFor(TypeInt), nil
 	case *ast.Literal[float64]:
-		return &object.Type{
-			ObjKind: TypeFloat,
-		}, nil
+		return object.TypeFor(TypeFloat), nil
 	case *ast.Literal[string]:
-		return &object.Type{
-			ObjKind: TypeString,
-		}, nil
+		return object.TypeFor(TypeString), nil
 	case *ast.Literal[bool]:
-		return &object.Type{
-			ObjKind: TypeBool,
-		}, nil
+		return object.TypeFor(TypeBool), nil
+	case *ast.Keyword:
+		return object.TypeFor(object.ObjKeyword), nil
 	case *ast.Symbol:
-		obj, _ := ti.env.LookUp(n.Value)
-		switch obj := obj.(type) {
-		case nil:
-			return object.TypeFor(object.ObjNull), nil
-		case *object.Builtin:
-			return obj.Type, nil
-		default:
-			return &object.Type{
-				ObjKind: obj.Kind(),
-			}, nil
-		}
+		return ti.lookup(n.Value), nil
 	case *ast.If:
-		condType, err := ti.Infer(n.Cond)
+		return ti.inferIf(n)
+	case *ast.Vector:
+		return ti.inferVector(n)
+	case *ast.Map:
+		for _, item := range n.Items {
+			if _, err := ti.infer(item); err != nil {
+				return nil, fmt.Errorf("map: %w", err)
+			}
+		}
+		return object.TypeFor(TypeMap), nil
+	case *ast.Package:
+		return ti.inferBody(n.Nodes)
+	case *ast.Do:
+		return ti.inferBody(n.Body)
+	case *ast.Let:
+		return ti.inferLet(n)
+	case *ast.Loop:
+		return ti.inferLoop(n)
+	case *ast.Recur:
+		return ti.inferRecur(n)
+	case *ast.Return:
+		return ti.inferReturn(n)
+	case *ast.While:
+		if err := ti.expect(object.TypeFor(TypeBool), n.PosRange, n.Cond); err != nil {
+			return nil, fmt.Errorf("while condition is not boolean: %w", err)
+		}
+		if _, err := ti.inferBody(n.Body); err != nil {
+			return nil, fmt.Errorf("while: %w", err)
+		}
+		return object.TypeFor(TypeNull), nil
+	case *ast.Try:
+		return ti.inferTry(n)
+	case *ast.ImportGo:
+		return object.TypeFor(TypeNull), nil
+	case *ast.DotSelector:
+		// members of namespaces are known only at runtime
+		return object.TypeFor(TypeAny), nil
+	case *ast.Quote, *ast.Quasiquote:
+		return object.TypeFor(object.ObjAST), nil
+	case *ast.Fn:
+		return ti.inferFn(n)
+	case *ast.Defn:
+		t, err := ti.inferFn(n.Fn)
 		if err != nil {
-			return nil, fmt.Errorf("cond: %w", err)
+			return nil, fmt.Errorf("defn %s: %w", n.Fn.Ident, err)
 		}
-		if condType.ObjKind != object.ObjBool {
-			return nil, fmt.Errorf("if condition is not boolean: got %s", condType.Inspect())
+		ti.state.globals[n.Fn.Ident.Value] = ti.state.generalize(t, ti.locals)
+		return t, nil
+	case *ast.Defmacro:
+		return object.TypeFor(object.ObjMacro), nil
+	case *ast.SExp:
+		return ti.inferSExp(n)
+	case *ast.SpecialOp:
+		return ti.inferSpecialOp(n)
+	}
+
+	return nil, typeError(node.Pos(), "unexpected node %s %q", node.Name(), node.String())
+}
+
+// expect infers the type of the node and unifies it with the type wanted by the form at pos.
+func (ti *TypeInferencer) expect(want *object.Type, pos ast.PosRange, node ast.Node) error {
+	t, err := ti.infer(node)
+	if err != nil {
+		return err
+	}
+
+	return ti.state.unify(want, pos, t, node.Pos())
+}
+
+// lookup returns a type of the name: locals and assigned names are generalized,
+// values of the env are typed by their kinds. Unbound names are null.
+func (ti *TypeInferencer) lookup(name string) *object.Type {
+	if local, ok := ti.locals[name]; ok {
+		return ti.state.instantiate(local)
+	}
+
+	if global, ok := ti.state.globals[name]; ok {
+		return ti.state.instantiate(global)
+	}
+
+	obj, ok := ti.env.LookUp(name)
+	if !ok {
+		// the name may be bound at runtime, until then it's null
+		return object.NullableType(ti.state.fresh())
+	}
+
+	switch obj := obj.(type) {
+	case *object.Builtin:
+		return ti.builtinType(obj)
+	case object.Defined:
+		return ti.functionType(obj)
+	default:
+		return object.TypeFor(obj.Kind())
+	}
+}
+
+// builtinType returns a function type of the builtin.
+// Builtins typed by their results take any arguments.
+func (ti *TypeInferencer) builtinType(builtin *object.Builtin) *object.Type {
+	switch {
+	case builtin.Type == nil:
+		return object.TypeFor(TypeFunc)
+	case builtin.Type.IsFunc():
+		return ti.state.instantiate(ti.state.generalize(builtin.Type, nil))
+	default:
+		t := object.FuncType(builtin.Type, *object.TypeFor(TypeArray, TypeAny))
+		t.Variadic = true
+		return t
+	}
+}
+
+// functionType infers a type of the function value by its body.
+// Functions, which can't be typed, are called with any arguments.
+func (ti *TypeInferencer) functionType(fn object.Defined) *object.Type {
+	if known, ok := ti.state.values[fn]; ok {
+		return ti.state.instantiate(known)
+	}
+
+	// recursive calls see the type being inferred
+	placeholder := ti.state.fresh()
+	ti.state.values[fn] = mono(placeholder)
+
+	def, env := fn.Definition()
+	if def == nil {
+		return object.TypeFor(TypeFunc)
+	}
+
+	scope := &TypeInferencer{env: env, state: ti.state}
+
+	// errors of the body make the function untyped, they aren't reported
+	reportAll := ti.state.reportAll
+	ti.state.reportAll = false
+	defer func() { ti.state.reportAll = reportAll }()
+
+	err := ti.state.try(func() error {
+		t, err := scope.inferFn(def)
+		if err != nil {
+			return err
 		}
 
-		thenType, err := ti.Infer(n.Then)
+		return ti.state.unify(placeholder, ast.PosRange{}, t, ast.PosRange{})
+	})
+	if err != nil {
+		ti.state.values[fn] = mono(object.TypeFor(TypeFunc))
+		return object.TypeFor(TypeFunc)
+	}
+
+	ti.state.values[fn] = ti.state.generalize(placeholder, nil)
+	return ti.state.instantiate(ti.state.values[fn])
+}
+
+// inferIf requires a boolean condition and branches of the same type.
+// A null branch makes the type of the other one nullable.
+func (ti *TypeInferencer) inferIf(n *ast.If) (*object.Type, error) {
+	if err := ti.expect(object.TypeFor(TypeBool), n.PosRange, n.Cond); err != nil {
+		return nil, fmt.Errorf("if condition is not boolean: %w", err)
+	}
+
+	thenType, err := ti.infer(n.Then)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Else == nil {
+		return object.NullableType(ti.state.apply(thenType)), nil
+	}
+
+	elseType, err := ti.infer(n.Else)
+	if err != nil {
+		return nil, fmt.Errorf("else: %w", err)
+	}
+
+	// unification would bind type variables of the other branch to null
+	switch {
+	case isNull(ti.state.apply(elseType)):
+		return object.NullableType(ti.state.apply(thenType)), nil
+	case isNull(ti.state.apply(thenType)):
+		return object.NullableType(ti.state.apply(elseType)), nil
+	}
+
+	err = ti.state.try(func() error {
+		return ti.state.unify(thenType, n.Then.Pos(), elseType, n.Else.Pos())
+	})
+	if err != nil {
+		// values of different types can come from different branches
+		return object.UnionType(ti.state.apply(thenType), ti.state.apply(elseType)), nil
+	}
+
+	return thenType, nil
+}
+
+func isNull(t *object.Type) bool {
+	return t.ObjKind == TypeNull && t.Var == ""
+}
+
+// inferSExp infers a type of the call result.
+func (ti *TypeInferencer) inferSExp(sexp *ast.SExp) (*object.Type, error) {
+	if len(sexp.Items) == 0 {
+		return object.TypeFor(TypeAny), nil
+	}
+
+	head, args := sexp.Items[0], sexp.Items[1:]
+
+	if symbol, ok := head.(*ast.Symbol); ok && symbol.Value == "assign" && ti.isBuiltin(symbol.Value) {
+		return ti.inferAssign(sexp)
+	}
+
+	fnType, err := ti.infer(head)
+	if err != nil {
+		return nil, fmt.Errorf("head of SExp: %w", err)
+	}
+
+	argTypes := make([]*object.Type, 0, len(args))
+	for i, arg := range args {
+		t, err := ti.infer(arg)
 		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+		argTypes = append(argTypes, t)
+	}
+
+	return ti.call(sexp, fnType, argTypes)
+}
+
+// call unifies arguments with parameters of the function type and returns its result type.
+// Collections are indexed, values of unknown types return any.
+func (ti *TypeInferencer) call(sexp *ast.SExp, fnType *object.Type, argTypes []*object.Type) (*object.Type, error) {
+	head, args := sexp.Items[0], sexp.Items[1:]
+	fnType, _ = ti.state.find(fnType, head.Pos())
+
+	switch {
+	case fnType.Var != "":
+		params := make([]object.Type, 0, len(argTypes))
+		for _, t := range argTypes {
+			params = append(params, *t)
+		}
+
+		ret := ti.state.fresh()
+		if err := ti.state.unify(fnType, head.Pos(), object.FuncType(ret, params...), sexp.PosRange); err != nil {
+			return nil, fmt.Errorf("call of %s: %w", callName(head), err)
+		}
+		return ret, nil
+	case fnType.IsFunc():
+		return ti.callFunc(sexp, fnType, argTypes)
+	}
+
+	switch fnType.ObjKind {
+	case TypeArray:
+		if len(args) == 0 {
+			return fnType, nil
+		}
+		if err := ti.state.unify(object.TypeFor(TypeInt), head.Pos(), argTypes[0], args[0].Pos()); err != nil {
+			return nil, fmt.Errorf("array index is not integer: %w", err)
+		}
+		return fnType.Elem(), nil
+	case TypeInt, TypeFloat, TypeString, TypeBool, object.ObjAST, object.ObjType, object.ObjException:
+		return nil, typeError(head.Pos(), "%s can't be called", ti.state.rename(fnType, map[string]string{}))
+	default:
+		// maps, keywords, namespaces, Go values and functions without known signatures,
+		// unbound heads are method calls or names bound at runtime
+		return object.TypeFor(TypeAny), nil
+	}
+}
+
+func (ti *TypeInferencer) callFunc(sexp *ast.SExp, fnType *object.Type, argTypes []*object.Type) (*object.Type, error) {
+	head, args := sexp.Items[0], sexp.Items[1:]
+
+	params := fnType.Params
+	if fnType.Variadic {
+		params = params[:len(params)-1]
+	}
+
+	switch {
+	case !fnType.Variadic && len(args) != len(params):
+		return nil, object.Errorf(sexp.PosRange, "%w", object.WithKind(object.ErrArity,
+			fmt.Errorf("%s want %d arguments, got %d", callName(head), len(params), len(args)))).Err
+	case fnType.Variadic && len(args) < len(params):
+		return nil, object.Errorf(sexp.PosRange, "%w", object.WithKind(object.ErrArity,
+			fmt.Errorf("%s want at least %d arguments, got %d", callName(head), len(params), len(args)))).Err
+	}
+
+	for i, arg := range args[:len(params)] {
+		if err := ti.state.unify(&params[i], head.Pos(), argTypes[i], arg.Pos()); err != nil {
+			return nil, fmt.Errorf("argument %d of %s: %w", i, callName(head), err)
+		}
+	}
+
+	if fnType.Variadic {
+		if err := ti.restArgs(head, fnType.Params[len(params)].Elem(), args, argTypes, len(params)); err != nil {
 			return nil, err
 		}
+	}
+
+	return fnType.Return, nil
+}
+
+// restArgs unifies arguments from the index with the element type of the rest parameter.
+// Arguments of different types make an unknown element type any, as elements of vector literals.
+func (ti *TypeInferencer) restArgs(head ast.Node, elem *object.Type, args []ast.Node, argTypes []*object.Type, from int) error {
+	elem, _ = ti.state.find(elem, head.Pos())
+
+	err := ti.state.try(func() error {
+		for i := from; i < len(args); i++ {
+			if err := ti.state.unify(elem, head.Pos(), argTypes[i], args[i].Pos()); err != nil {
+				return fmt.Errorf("argument %d of %s: %w", i, callName(head), err)
+			}
+		}
+		return nil
+	})
+
+	if err != nil && elem.Var != "" {
+		ti.state.bind(elem.Var, object.TypeFor(TypeAny), head.Pos())
+		return nil
+	}
+
+	return err
+}
+
+// callName returns a name of the called symbol or a name of the head form.
+func callName(head ast.Node) string {
+	if symbol, ok := head.(*ast.Symbol); ok {
+		return symbol.Value
+	}
+
+	return head.Name()
+}
+
+// isBuiltin reports if the name isn't shadowed by locals or assigned names.
+func (ti *TypeInferencer) isBuiltin(name string) bool {
+	if _, ok := ti.locals[name]; ok {
+		return false
+	}
+
+	if _, ok := ti.state.globals[name]; ok {
+		return false
+	}
+
+	obj, _ := ti.env.LookUp(name)
+	_, ok := obj.(*object.Builtin)
+	return ok
+}
+
+// inferAssign generalizes types of assigned values and remembers them for next forms.
+// Annotated names get the annotated types.
+func (ti *TypeInferencer) inferAssign(sexp *ast.SExp) (*object.Type, error) {
+	args := sexp.Items[1:]
+	if len(args)%2 != 0 {
+		return nil, object.Errorf(sexp.PosRange, "%w", object.WithKind(object.ErrArity,
+			fmt.Errorf("assign requires an even number of arguments, got %d", len(args)))).Err
+	}
 
-		if n.Else == nil {
-			return &object.Type{
-				ObjKind: object.ObjAny, // because thenType | nil
-			}, nil
+	for i := 0; i < len(args); i += 2 {
+		symbol, ok := args[i].(*ast.Symbol)
+		if !ok {
+			return nil, typeError(args[i].Pos(), "assign can only use symbols as names, got %s %q", args[i].Name(), args[i].String())
 		}
 
-		elseType, err := ti.Infer(n.Else)
+		t, err := ti.infer(args[i+1])
 		if err != nil {
-			return nil, fmt.Errorf("else: %w", err)
+			return nil, fmt.Errorf("assign %s: %w", symbol, err)
 		}
 
-		if !thenType.Equal(elseType) {
-			return &object.Type{
-				ObjKind: object.ObjAny, // because thenType | nil
-			}, nil
+		scope, err := ti.bind(symbol, t, args[i+1].Pos(), true)
+		if err != nil {
+			return nil, fmt.Errorf("assign %s: %w", symbol, err)
 		}
 
-		return thenType, nil
-	case *ast.SExp:
-		return ti.inferSExp(n)
-	case *ast.SpecialOp:
-		return ti.inferSpecialOp(n)
+		ti.state.globals[symbol.Value] = scope.locals[symbol.Value]
 	}
 
-	return nil, fmt.Errorf("%w: unexpected node %s %q", errType, node.Name(), node.String())
+	return object.TypeFor(TypeArray, TypeAny), nil
 }
 
-func (ti *TypeInferencer) inferSExp(sexp *ast.SExp) (*object.Type, error) {
-	if len(sexp.Items) == 0 {
-		return &object.Type{
-			ObjKind: TypeAny,
-		}, nil
+// inferVector returns an array type, parametrized by the element type if all elements have the same type.
+func (ti *TypeInferencer) inferVector(vec *ast.Vector) (*object.Type, error) {
+	elem, elemPos := ti.state.fresh(), vec.PosRange
+
+	for i, item := range vec.Items {
+		t, err := ti.infer(item)
+		if err != nil {
+			return nil, fmt.Errorf("vector item %d: %w", i, err)
+		}
+
+		err = ti.state.try(func() error {
+			return ti.state.unify(elem, elemPos, t, item.Pos())
+		})
+		if err != nil {
+			elem = object.TypeFor(TypeAny)
+		}
+	}
+
+	return &object.Type{
+		ObjKind: TypeArray,
+		Params:  []object.Type{*elem},
+	}, nil
+}
+
+func (ti *TypeInferencer) inferBody(body []ast.Node) (*object.Type, error) {
+	result := object.TypeFor(TypeNull)
+
+	for _, node := range body {
+		t, err := ti.infer(node)
+		switch {
+		case err == nil:
+			result = t
+		case ti.state.report(err):
+			result = object.TypeFor(TypeAny)
+		default:
+			return nil, err
+		}
 	}
 
-	fnType, err := ti.Infer(sexp.Items[0]) // Infer function type
+	return result, nil
+}
+
+// inferFn types parameters by fresh variables, which are bound by their uses in the body.
+func (ti *TypeInferencer) inferFn(fn *ast.Fn) (*object.Type, error) {
+	scope := ti.child()
+	scope.recur = nil
+
+	params := make([]object.Type, 0, len(fn.Params)+1)
+	for _, param := range fn.Params {
+		t, err := ti.paramType(param, ti.state.fresh())
+		if err != nil {
+			return nil, err
+		}
+
+		params = append(params, *t)
+		scope.recur = append(scope.recur, t)
+		scope.locals[param.Value] = mono(t)
+	}
+
+	if fn.Rest != nil {
+		rest, err := ti.paramType(fn.Rest, &object.Type{ObjKind: TypeArray, Params: []object.Type{*ti.state.fresh()}})
+		if err != nil {
+			return nil, err
+		}
+
+		params = append(params, *rest)
+		scope.locals[fn.Rest.Value] = mono(rest)
+	}
+
+	scope.ret = ti.state.fresh()
+
+	t := object.FuncType(scope.ret, params...)
+	t.Variadic = fn.Rest != nil
+
+	if fn.Ident != nil {
+		scope.locals[fn.Ident.Value] = mono(t)
+	}
+
+	body, err := scope.inferBody(fn.Body)
 	if err != nil {
-		return nil, fmt.Errorf("head of SExp: %w", err)
+		return nil, err
+	}
+
+	resultPos := fn.PosRange
+	if len(fn.Body) > 0 {
+		resultPos = fn.Body[len(fn.Body)-1].Pos()
 	}
 
-	if fnType.ObjKind == object.ObjArray {
-		if len(fnType.Params) == 0 {
-			return object.TypeFor(TypeArray, object.ObjAny), nil
+	if err := ti.state.unify(scope.ret, fn.PosRange, body, resultPos); err != nil {
+		return nil, fmt.Errorf("fn result: %w", err)
+	}
+
+	return t, nil
+}
+
+// paramType returns a type of the parameter: the inferred type t unified with the annotation.
+// Uses of annotated parameters report the annotation as the source of their types.
+func (ti *TypeInferencer) paramType(param *ast.Symbol, t *object.Type) (*object.Type, error) {
+	if param.Type == nil {
+		return t, nil
+	}
+
+	annotated, err := annotationType(param.Type)
+	if err != nil {
+		return nil, fmt.Errorf("parameter %s: %w", param.Value, err)
+	}
+
+	if isDynamic(annotated) {
+		return annotated, nil
+	}
+
+	if err := ti.state.unify(t, param.PosRange, annotated, param.Type.Pos()); err != nil {
+		return nil, fmt.Errorf("parameter %s: %w", param.Value, err)
+	}
+
+	return t, nil
+}
+
+// inferReturn unifies the value with the result of the innermost fn.
+// The form itself never produces a value, so its type is a fresh variable.
+func (ti *TypeInferencer) inferReturn(n *ast.Return) (*object.Type, error) {
+	t, pos := object.TypeFor(TypeNull), n.PosRange
+	if n.Value != nil {
+		var err error
+		if t, err = ti.infer(n.Value); err != nil {
+			return nil, fmt.Errorf("return: %w", err)
+		}
+		pos = n.Value.Pos()
+	}
+
+	if ti.ret != nil {
+		if err := ti.state.unify(ti.ret, n.PosRange, t, pos); err != nil {
+			return nil, fmt.Errorf("return: %w", err)
 		}
 	}
 
-	return fnType, nil
+	return ti.state.fresh(), nil
+}
+
+// inferRecur unifies arguments with bindings of the innermost loop or fn.
+// Like return, recur never produces a value.
+func (ti *TypeInferencer) inferRecur(n *ast.Recur) (*object.Type, error) {
+	for i, arg := range n.Args {
+		t, err := ti.infer(arg)
+		if err != nil {
+			return nil, fmt.Errorf("recur: %w", err)
+		}
+
+		if i >= len(ti.recur) || ti.recur[i] == nil {
+			continue
+		}
+
+		if err := ti.state.unify(ti.recur[i], n.PosRange, t, arg.Pos()); err != nil {
+			return nil, fmt.Errorf("recur argument %d: %w", i, err)
+		}
+	}
+
+	return ti.state.fresh(), nil
+}
+
+// inferLet generalizes types of bindings, so they can be used with different types.
+func (ti *TypeInferencer) inferLet(let *ast.Let) (*object.Type, error) {
+	scope := ti
+
+	for _, binding := range let.Bindings {
+		t, err := scope.infer(binding.Value)
+		if err != nil {
+			return nil, fmt.Errorf("let binding %s: %w", binding.Target, err)
+		}
+
+		scope, err = scope.bind(binding.Target, t, binding.Value.Pos(), true)
+		if err != nil {
+			return nil, fmt.Errorf("let binding %s: %w", binding.Target, err)
+		}
+	}
+
+	return scope.inferBody(let.Body)
+}
+
+// inferTry returns the type of the body, catch clauses must produce the same type.
+func (ti *TypeInferencer) inferTry(try *ast.Try) (*object.Type, error) {
+	result, err := ti.inferBody(try.Body)
+	if err != nil {
+		return nil, fmt.Errorf("try: %w", err)
+	}
+
+	resultPos := try.PosRange
+	if len(try.Body) > 0 {
+		resultPos = try.Body[len(try.Body)-1].Pos()
+	}
+
+	for _, catch := range try.Catches {
+		if _, err := ti.infer(catch.Match); err != nil {
+			return nil, fmt.Errorf("catch %s: %w", catch.Match, err)
+		}
+
+		scope, err := ti.bind(catch.Ident, object.TypeFor(TypeAny), catch.Ident.PosRange, false)
+		if err != nil {
+			return nil, fmt.Errorf("catch %s: %w", catch.Match, err)
+		}
+
+		t, err := scope.inferBody(catch.Body)
+		if err != nil {
+			return nil, fmt.Errorf("catch %s: %w", catch.Match, err)
+		}
+
+		if err := ti.state.unify(result, resultPos, t, catch.PosRange); err != nil {
+			return nil, fmt.Errorf("catch %s has a different type: %w", catch.Match, err)
+		}
+	}
+
+	if _, err := ti.inferBody(try.Finally); err != nil {
+		return nil, fmt.Errorf("finally: %w", err)
+	}
+
+	return result, nil
+}
+
+// inferLoop types bindings like let, but doesn't generalize them,
+// because recur rebinds them with its arguments.
+func (ti *TypeInferencer) inferLoop(loop *ast.Loop) (*object.Type, error) {
+	scope := ti.child()
+	scope.recur = nil
+
+	for _, binding := range loop.Bindings {
+		t, err := scope.infer(binding.Value)
+		if err != nil {
+			return nil, fmt.Errorf("loop binding %s: %w", binding.Target, err)
+		}
+
+		if _, isSymbol := binding.Target.(*ast.Symbol); isSymbol {
+			scope.recur = append(scope.recur, t)
+		} else {
+			scope.recur = append(scope.recur, nil)
+		}
+
+		recur := scope.recur
+		scope, err = scope.bind(binding.Target, t, binding.Value.Pos(), false)
+		if err != nil {
+			return nil, fmt.Errorf("loop binding %s: %w", binding.Target, err)
+		}
+		scope.recur = recur
+	}
+
+	return scope.inferBody(loop.Body)
+}
+
+// child returns a copy of the inferencer, which can bind locals.
+func (ti *TypeInferencer) child() *TypeInferencer {
+	scope := *ti
+	scope.locals = maps.Clone(ti.locals)
+
+	if scope.locals == nil {
+		scope.locals = map[string]*scheme{}
+	}
+
+	return &scope
+}
+
+// bind returns a copy of the inferencer with the target names typed by t,
+// which comes from the expression at pos. Patterns require arrays.
+func (ti *TypeInferencer) bind(target ast.Node, t *object.Type, pos ast.PosRange, generalize bool) (*TypeInferencer, error) {
+	scope := ti.child()
+
+	switch target := target.(type) {
+	case *ast.Symbol:
+		if target.Type != nil {
+			annotated, err := annotationType(target.Type)
+			if err != nil {
+				return nil, err
+			}
+
+			// the name has the annotated type, even if its value is more specific
+			if err := ti.state.unify(annotated, target.Type.Pos(), t, pos); err != nil {
+				return nil, err
+			}
+			t = annotated
+		}
+
+		if generalize {
+			scope.locals[target.Value] = ti.state.generalize(t, ti.locals)
+		} else {
+			scope.locals[target.Value] = mono(t)
+		}
+	case *ast.ArrayPattern:
+		elem := ti.state.fresh()
+		if resolved, _ := ti.state.find(t, pos); resolved.Var == "" && resolved.ObjKind == TypeAny {
+			elem = object.TypeFor(TypeAny)
+		}
+
+		array := &object.Type{ObjKind: TypeArray, Params: []object.Type{*elem}}
+		if err := ti.state.unify(array, target.PosRange, t, pos); err != nil {
+			return nil, fmt.Errorf("array pattern: %w", err)
+		}
+
+		for _, item := range target.Items {
+			var err error
+			if scope, err = scope.bind(item, elem, pos, generalize); err != nil {
+				return nil, err
+			}
+		}
+
+		if target.Rest != nil {
+			scope.locals[target.Rest.Value] = mono(array)
+		}
+	}
+
+	return scope, nil
 }
 
 func (ti *TypeInferencer) inferSpecialOp(op *ast.SpecialOp) (*object.Type, error) {
 	switch op.Op {
-	case "+", "*":
-		return ti.inferArithmetic(op.Items)
+	case "+", "*", "-", "/", "mod", "rem":
+		return ti.inferArithmetic(op)
+	case "and", "or":
+		return ti.inferLogical(op)
 	default:
-		return nil, errType
+		return nil, typeError(op.PosRange, "unexpected operator %q", op.Op)
 	}
 }
 
-// inferArithmetic ensures consistent types (int or float) across operands
-func (ti *TypeInferencer) inferArithmetic(items []ast.Node) (*object.Type, error) {
-	hasFloats := false
-	for _, item := range items {
-		itemType, err := ti.Infer(item)
+// inferArithmetic returns float64 if any operand is float64 and integer if all operands are integers.
+// Operands of unknown types are constrained to numbers, both integers and floats,
+// and make the result a number too, unless it's float64.
+func (ti *TypeInferencer) inferArithmetic(op *ast.SpecialOp) (*object.Type, error) {
+	number := object.UnionType(object.TypeFor(TypeInt), object.TypeFor(TypeFloat))
+
+	var (
+		result  = object.TypeFor(TypeInt)
+		dynamic bool
+		mixed   bool
+	)
+
+	for i, item := range op.Items {
+		t, err := ti.infer(item)
 		if err != nil {
-			return nil, fmt.Errorf("number arguments: %w", err)
+			return nil, fmt.Errorf("%s argument %d: %w", op.Op, i, err)
 		}
-		if itemType.ObjKind == object.ObjFloat64 || itemType.ObjKind == object.ObjAST {
-			hasFloats = true
-		} else if itemType.ObjKind != object.ObjInteger {
-			return nil, errType
+
+		t, _ = ti.state.find(t, item.Pos())
+		switch {
+		case t.Var != "":
+			if err := ti.state.unify(number, op.PosRange, t, item.Pos()); err != nil {
+				return nil, fmt.Errorf("%s argument %d: %w", op.Op, i, err)
+			}
+			mixed = true
+		case t.ObjKind == TypeAny:
+			dynamic = true
+		case t.ObjKind == TypeFloat:
+			result = t
+		case t.ObjKind == TypeInt:
+		case t.ObjKind == object.ObjUnion && ti.state.apply(t).IsSubtype(number):
+			mixed = true
+		default:
+			return nil, typeError(item.Pos(), "%s argument %d is not a number: %w", op.Op, i, &MismatchError{
+				Want:    number,
+				WantPos: op.PosRange,
+				Got:     ti.state.rename(t, map[string]string{}),
+				GotPos:  item.Pos(),
+			})
 		}
 	}
 
-	if hasFloats {
-		return object.TypeFor(object.ObjFloat64), nil
+	switch {
+	case dynamic:
+		return object.TypeFor(TypeAny), nil
+	case result.ObjKind == TypeFloat:
+		return result, nil
+	case mixed:
+		return number, nil
+	default:
+		return result, nil
+	}
+}
+
+// inferLogical ensures that all operands are booleans
+func (ti *TypeInferencer) inferLogical(op *ast.SpecialOp) (*object.Type, error) {
+	for i, item := range op.Items {
+		if err := ti.expect(object.TypeFor(TypeBool), op.PosRange, item); err != nil {
+			return nil, fmt.Errorf("%s argument %d is not boolean: %w", op.Op, i, err)
+		}
 	}
-	return object.TypeFor(object.ObjInteger), nil
+
+	return object.TypeFor(TypeBool), nil
 }
@@ -0,0 +1,322 @@
+package astwalk
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// MismatchError reports types, which can't be unified,
+// and positions of expressions they come from.
+type MismatchError struct {
+	Want, Got       *object.Type
+	WantPos, GotPos ast.PosRange
+}
+
+func (err *MismatchError) Error() string {
+	return fmt.Sprintf("want %s at %s, got %s at %s", err.Want, err.WantPos.From, err.Got, err.GotPos.From)
+}
+
+// inference is a state shared by all scopes of a type inferencer.
+type inference struct {
+	subst   map[string]binding         // bound type variables
+	globals map[string]*scheme         // types of names assigned by inferred forms
+	values  map[object.Defined]*scheme // types of functions found in the env
+	next    int                        // counter of fresh variables
+
+	reportAll bool
+	errs      []error // type errors recorded in the ReportAll mode
+}
+
+// binding is a type of a variable and a position of the expression it comes from.
+type binding struct {
+	t   *object.Type
+	pos ast.PosRange
+}
+
+// scheme is a type generalized over variables, every use of it gets fresh copies of them.
+type scheme struct {
+	vars []string
+	t    *object.Type
+}
+
+// mono returns a scheme without generalized variables.
+func mono(t *object.Type) *scheme {
+	return &scheme{t: t}
+}
+
+func newInference() *inference {
+	return &inference{
+		subst:   map[string]binding{},
+		globals: map[string]*scheme{},
+		values:  map[object.Defined]*scheme{},
+	}
+}
+
+func (inf *inference) fresh() *object.Type {
+	inf.next++
+	return object.VarType("t" + strconv.Itoa(inf.next))
+}
+
+// find follows bindings of the variable and returns its type
+// with the position of the last binding.
+func (inf *inference) find(t *object.Type, pos ast.PosRange) (*object.Type, ast.PosRange) {
+	for t.Var != "" {
+		b, ok := inf.subst[t.Var]
+		if !ok {
+			break
+		}
+		t, pos = b.t, b.pos
+	}
+
+	return t, pos
+}
+
+// apply replaces all bound variables in the type.
+func (inf *inference) apply(t *object.Type) *object.Type {
+	return inf.replace(t, func(name string) *object.Type {
+		if b, ok := inf.subst[name]; ok {
+			return inf.apply(b.t)
+		}
+		return nil
+	})
+}
+
+// replace copies the type, replacing variables by types returned from fn.
+// Variables, for which fn returns nil, are kept as is.
+func (inf *inference) replace(t *object.Type, fn func(name string) *object.Type) *object.Type {
+	if t.Var != "" {
+		if replaced := fn(t.Var); replaced != nil {
+			return replaced
+		}
+		return t
+	}
+
+	if len(t.Params) == 0 && t.Return == nil {
+		return t
+	}
+
+	replaced := *t
+	replaced.Params = make([]object.Type, 0, len(t.Params))
+	for i := range t.Params {
+		replaced.Params = append(replaced.Params, *inf.replace(&t.Params[i], fn))
+	}
+
+	if t.Return != nil {
+		replaced.Return = inf.replace(t.Return, fn)
+	}
+
+	if t.ObjKind == object.ObjUnion {
+		// replaced variables can make types of the union the same
+		return object.UnionType(&replaced)
+	}
+
+	return &replaced
+}
+
+// unify makes types equal by binding their variables.
+// Positions point at expressions the types come from, bindings keep them to report conflicts.
+func (inf *inference) unify(want *object.Type, wantPos ast.PosRange, got *object.Type, gotPos ast.PosRange) error {
+	want, wantPos = inf.find(want, wantPos)
+	got, gotPos = inf.find(got, gotPos)
+
+	if inf.match(want, wantPos, got, gotPos) {
+		return nil
+	}
+
+	names := map[string]string{}
+	if want.Var != "" || got.Var != "" {
+		return typeError(gotPos, "infinite type: %s is %s", inf.rename(want, names), inf.rename(got, names))
+	}
+
+	return typeError(gotPos, "%w", &MismatchError{
+		Want:    inf.rename(want, names),
+		WantPos: wantPos,
+		Got:     inf.rename(got, names),
+		GotPos:  gotPos,
+	})
+}
+
+func (inf *inference) match(want *object.Type, wantPos ast.PosRange, got *object.Type, gotPos ast.PosRange) bool {
+	want, wantPos = inf.find(want, wantPos)
+	got, gotPos = inf.find(got, gotPos)
+
+	switch {
+	case want.Var != "" && want.Var == got.Var:
+		return true
+	case isDynamic(want) || isDynamic(got):
+		// variables stay unbound, so other uses can resolve them
+		return true
+	case want.Var != "":
+		return inf.bind(want.Var, got, gotPos)
+	case got.Var != "":
+		return inf.bind(got.Var, want, wantPos)
+	case got.ObjKind == object.ObjUnion:
+		// each of the got types must match
+		for i := range got.Params {
+			if !inf.match(want, wantPos, &got.Params[i], gotPos) {
+				return false
+			}
+		}
+		return true
+	case want.ObjKind == object.ObjUnion:
+		// the got type must match one of the wanted types
+		for i := range want.Params {
+			err := inf.try(func() error {
+				if !inf.match(&want.Params[i], wantPos, got, gotPos) {
+					return errNoMatch
+				}
+				return nil
+			})
+			if err == nil {
+				return true
+			}
+		}
+		return false
+	case want.ObjKind != got.ObjKind:
+		return false
+	case want.IsFunc() && got.IsFunc():
+		if want.Variadic != got.Variadic || len(want.Params) != len(got.Params) {
+			return false
+		}
+
+		for i := range want.Params {
+			if !inf.match(&want.Params[i], wantPos, &got.Params[i], gotPos) {
+				return false
+			}
+		}
+
+		return inf.match(want.Return, wantPos, got.Return, gotPos)
+	case len(want.Params) == len(got.Params):
+		for i := range want.Params {
+			if !inf.match(&want.Params[i], wantPos, &got.Params[i], gotPos) {
+				return false
+			}
+		}
+	}
+
+	// types without parameters match any parametrized types of their kinds,
+	// as functions without signatures match any functions
+	return true
+}
+
+var errNoMatch = errors.New("types don't match")
+
+// isDynamic reports if values of the type are checked only at runtime.
+func isDynamic(t *object.Type) bool {
+	return t.Var == "" && t.ObjKind == object.ObjAny
+}
+
+// bind binds the variable to the type, unless the type contains the variable.
+func (inf *inference) bind(name string, t *object.Type, pos ast.PosRange) bool {
+	if slices.Contains(inf.freeVars(t, nil), name) {
+		return false
+	}
+
+	inf.subst[name] = binding{t: t, pos: pos}
+	return true
+}
+
+// try runs fn and rolls back bindings made by it, if it fails.
+func (inf *inference) try(fn func() error) error {
+	subst := maps.Clone(inf.subst)
+
+	err := fn()
+	if err != nil {
+		inf.subst = subst
+	}
+
+	return err
+}
+
+// freeVars appends names of unbound variables of the type to vars.
+func (inf *inference) freeVars(t *object.Type, vars []string) []string {
+	t, _ = inf.find(t, ast.PosRange{})
+
+	if t.Var != "" {
+		if !slices.Contains(vars, t.Var) {
+			vars = append(vars, t.Var)
+		}
+		return vars
+	}
+
+	for i := range t.Params {
+		vars = inf.freeVars(&t.Params[i], vars)
+	}
+
+	if t.Return != nil {
+		vars = inf.freeVars(t.Return, vars)
+	}
+
+	return vars
+}
+
+// generalize quantifies variables of the type, which are not used by the locals.
+func (inf *inference) generalize(t *object.Type, locals map[string]*scheme) *scheme {
+	var bound []string
+	for _, local := range locals {
+		for _, name := range inf.freeVars(local.t, nil) {
+			if !slices.Contains(local.vars, name) {
+				bound = append(bound, name)
+			}
+		}
+	}
+
+	s := &scheme{t: inf.apply(t)}
+	for _, name := range inf.freeVars(s.t, nil) {
+		if !slices.Contains(bound, name) {
+			s.vars = append(s.vars, name)
+		}
+	}
+
+	return s
+}
+
+// instantiate returns the type of the scheme with fresh variables.
+func (inf *inference) instantiate(s *scheme) *object.Type {
+	if len(s.vars) == 0 {
+		return s.t
+	}
+
+	vars := make(map[string]*object.Type, len(s.vars))
+	for _, name := range s.vars {
+		vars[name] = inf.fresh()
+	}
+
+	return inf.replace(s.t, func(name string) *object.Type {
+		return vars[name]
+	})
+}
+
+// rename applies the substitution and renames free variables to a, b, c and so on.
+// Names are shared by all types renamed with the same map.
+func (inf *inference) rename(t *object.Type, names map[string]string) *object.Type {
+	return inf.replace(inf.apply(t), func(name string) *object.Type {
+		renamed, ok := names[name]
+		if !ok {
+			renamed = varName(len(names))
+			names[name] = renamed
+		}
+
+		return object.VarType(renamed)
+	})
+}
+
+func varName(i int) string {
+	name := string(rune('a' + i%26))
+	if i >= 26 {
+		name += strconv.Itoa(i / 26)
+	}
+
+	return name
+}
+
+// typeError returns an inference error at the position.
+func typeError(pos ast.PosRange, format string, args ...any) error {
+	return object.Errorf(pos, "%w", object.WithKind(object.ErrType, fmt.Errorf(format, args...))).Err
+}
@@ -0,0 +1,282 @@
+package gopkg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+var errConvert = object.WithKind(object.ErrType, errors.New("can't convert"))
+
+// ToGo converts an object to a Go value of the type.
+func ToGo(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if wrapped, ok := obj.(*object.GoValue); ok {
+		if !wrapped.Value.Type().AssignableTo(t) {
+			return reflect.Value{}, fmt.Errorf("%w Go %s to %s", errConvert, wrapped.Value.Type(), t)
+		}
+
+		value := reflect.New(t).Elem()
+		value.Set(wrapped.Value)
+
+		return value, nil
+	}
+
+	if t.Kind() == reflect.Interface {
+		return toInterface(obj, t)
+	}
+
+	value := reflect.New(t).Elem()
+
+	switch obj := obj.(type) {
+	case object.Null:
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return value, nil
+		}
+	case *object.Primitive[int64]:
+		switch {
+		case isInt(t):
+			if value.OverflowInt(obj.Value) {
+				return value, fmt.Errorf("%w: %d overflows %s", errConvert, obj.Value, t)
+			}
+			value.SetInt(obj.Value)
+			return value, nil
+		case isUint(t):
+			if obj.Value < 0 || value.OverflowUint(uint64(obj.Value)) {
+				return value, fmt.Errorf("%w: %d overflows %s", errConvert, obj.Value, t)
+			}
+			value.SetUint(uint64(obj.Value))
+			return value, nil
+		case isFloat(t):
+			value.SetFloat(float64(obj.Value))
+			return value, nil
+		}
+	case *object.Primitive[float64]:
+		if isFloat(t) {
+			value.SetFloat(obj.Value)
+			return value, nil
+		}
+	case *object.Primitive[string]:
+		str := object.Unquote(obj.Value)
+		switch {
+		case t.Kind() == reflect.String:
+			value.SetString(str)
+			return value, nil
+		case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+			value.SetBytes([]byte(str))
+			return value, nil
+		}
+	case *object.Primitive[bool]:
+		if t.Kind() == reflect.Bool {
+			value.SetBool(obj.Value)
+			return value, nil
+		}
+	case *object.Array:
+		if t.Kind() == reflect.Slice {
+			return toSlice(obj, t)
+		}
+	case *object.Map:
+		if t.Kind() == reflect.Map {
+			return toMap(obj, t)
+		}
+	}
+
+	return value, fmt.Errorf("%w %s %q to Go %s", errConvert, obj.Kind(), obj.Inspect(), t)
+}
+
+// toInterface converts an object to its natural Go representation
+// and checks that it implements the interface.
+func toInterface(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	var natural reflect.Type
+
+	switch kw := obj.(type) {
+	case object.Null:
+		return reflect.Zero(t), nil
+	case *object.Primitive[int64]:
+		natural = reflect.TypeFor[int64]()
+	case *object.Primitive[float64]:
+		natural = reflect.TypeFor[float64]()
+	case *object.Primitive[string]:
+		natural = reflect.TypeFor[string]()
+	case *object.Primitive[bool]:
+		natural = reflect.TypeFor[bool]()
+	case object.Keyword:
+		natural = reflect.TypeFor[string]()
+		obj = object.PrimitiveOf(strconv.Quote(string(kw)))
+	case *object.Array:
+		natural = reflect.TypeFor[[]any]()
+	case *object.Map:
+		natural = reflect.TypeFor[map[any]any]()
+	default:
+		return reflect.Value{}, fmt.Errorf("%w %s %q to Go %s", errConvert, obj.Kind(), obj.Inspect(), t)
+	}
+
+	if !natural.Implements(t) {
+		return reflect.Value{}, fmt.Errorf("%w %s %q to Go %s", errConvert, obj.Kind(), obj.Inspect(), t)
+	}
+
+	value, err := ToGo(obj, natural)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	result := reflect.New(t).Elem()
+	result.Set(value)
+
+	return result, nil
+}
+
+func toSlice(array *object.Array, t reflect.Type) (reflect.Value, error) {
+	slice := reflect.MakeSlice(t, 0, array.Len())
+
+	for i, element := range array.All() {
+		value, err := ToGo(element, t.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+		}
+
+		slice = reflect.Append(slice, value)
+	}
+
+	return slice, nil
+}
+
+func toMap(m *object.Map, t reflect.Type) (reflect.Value, error) {
+	result := reflect.MakeMapWithSize(t, m.Len())
+
+	for key, value := range m.All() {
+		k, err := ToGo(key, t.Key())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("key %s: %w", key.Inspect(), err)
+		}
+
+		v, err := ToGo(value, t.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("value of %s: %w", key.Inspect(), err)
+		}
+
+		result.SetMapIndex(k, v)
+	}
+
+	return result, nil
+}
+
+// FromGo converts a Go value to an object.
+// Numbers become integers and floats, slices become arrays and maps become maps,
+// other values are wrapped as is. Numbers, strings and booleans of types with methods
+// are wrapped too, so the methods can be called: (.Seconds (time.ParseDuration "1s")).
+func FromGo(value reflect.Value) (object.Object, error) {
+	if value.IsValid() && isBasic(value.Type()) && value.Type().NumMethod() > 0 {
+		return &object.GoValue{Value: value}, nil
+	}
+
+	return Unwrap(value)
+}
+
+// Unwrap converts a Go value to an object as FromGo,
+// but values of basic types with methods are converted too: time.Duration becomes an integer.
+func Unwrap(value reflect.Value) (object.Object, error) {
+	if !value.IsValid() {
+		return object.Null{}, nil
+	}
+
+	switch {
+	case isInt(value.Type()):
+		return object.PrimitiveOf(value.Int()), nil
+	case isUint(value.Type()):
+		if value.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %d overflows integer", errConvert, value.Uint())
+		}
+		return object.PrimitiveOf(int64(value.Uint())), nil
+	case isFloat(value.Type()):
+		return object.PrimitiveOf(value.Float()), nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return object.PrimitiveOf(strconv.Quote(value.String())), nil
+	case reflect.Bool:
+		return object.PrimitiveOf(value.Bool()), nil
+	case reflect.Interface, reflect.Pointer:
+		if value.IsNil() {
+			return object.Null{}, nil
+		}
+		if value.Kind() == reflect.Interface {
+			return FromGo(value.Elem())
+		}
+		return &object.GoValue{Value: value}, nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return object.Null{}, nil
+		}
+
+		array := &object.Array{}
+		for i := range value.Len() {
+			element, err := FromGo(value.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			array.Append(element)
+		}
+
+		return array, nil
+	case reflect.Map:
+		if value.IsNil() {
+			return object.Null{}, nil
+		}
+
+		m := object.NewMap()
+		for iter := value.MapRange(); iter.Next(); {
+			key, err := FromGo(iter.Key())
+			if err != nil {
+				return nil, fmt.Errorf("map key: %w", err)
+			}
+
+			elem, err := FromGo(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("map value: %w", err)
+			}
+
+			if err := m.Set(key, elem); err != nil {
+				return nil, err
+			}
+		}
+
+		return m, nil
+	}
+
+	return &object.GoValue{Value: value}, nil
+}
+
+// isBasic reports if values of the type are converted to primitives.
+func isBasic(t reflect.Type) bool {
+	return isInt(t) || isUint(t) || isFloat(t) || t.Kind() == reflect.String || t.Kind() == reflect.Bool
+}
+
+func isInt(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isFloat(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
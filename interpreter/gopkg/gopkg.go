@@ -0,0 +1,407 @@
+// Package gopkg is a registry of Go packages, which can be imported by import-go forms.
+//
+// Package members are registered by value:
+//
+//	gopkg.Register("strings", gopkg.Members{
+//		"ToUpper": strings.ToUpper,
+//		"Builder": reflect.TypeFor[strings.Builder](),
+//	})
+//
+// Functions become builtins, which convert arguments and results,
+// reflect.Type values become conversion functions and other values are constants.
+package gopkg
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// Members of a Go package by their names.
+type Members map[string]any
+
+// Package is a registered Go package.
+type Package struct {
+	Path    string
+	Name    string // default name of the namespace
+	Members Members
+}
+
+// Registry is a set of Go packages, which can be imported by their paths.
+type Registry struct {
+	mu       sync.RWMutex
+	packages map[string]*Package
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		packages: map[string]*Package{},
+	}
+}
+
+// Default registry is used by the interpreter, it contains a subset of the standard library.
+var Default = NewRegistry()
+
+// Register adds a package to the default registry.
+func Register(pkgPath string, members Members) {
+	Default.Register(pkgPath, members)
+}
+
+// Register adds a package or adds members to an already registered one.
+// The package name is the last element of the path.
+func (registry *Registry) Register(pkgPath string, members Members) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	pkg, ok := registry.packages[pkgPath]
+	if !ok {
+		pkg = &Package{
+			Path:    pkgPath,
+			Name:    path.Base(pkgPath),
+			Members: Members{},
+		}
+		registry.packages[pkgPath] = pkg
+	}
+
+	for name, member := range members {
+		pkg.Members[name] = member
+	}
+}
+
+// Lookup returns a registered package by its path.
+func (registry *Registry) Lookup(pkgPath string) (*Package, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	pkg, ok := registry.packages[pkgPath]
+	return pkg, ok
+}
+
+// Namespace binds members of the package to a namespace.
+func (pkg *Package) Namespace() (*object.Namespace, error) {
+	env := object.NewEnv()
+
+	for name, member := range pkg.Members {
+		value, err := Bind(pkg.Name+"."+name, member)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", pkg.Path, name, err)
+		}
+
+		env.Assign(name, value)
+	}
+
+	return &object.Namespace{Env: env}, nil
+}
+
+// Bind converts a Go value to an object: functions become builtins,
+// types become conversion functions, other values are converted to objects.
+func Bind(name string, member any) (object.Object, error) {
+	if t, isType := member.(reflect.Type); isType {
+		return typeBuiltin(name, t), nil
+	}
+
+	value := reflect.ValueOf(member)
+	if value.Kind() == reflect.Func {
+		return funcBuiltin(name, value), nil
+	}
+
+	return FromGo(value)
+}
+
+func funcBuiltin(name string, fn reflect.Value) *object.Builtin {
+	return &object.Builtin{
+		Name: name,
+		Type: object.TypeFor(object.ObjAny),
+		Fn: func(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+			args, err := evalArgs(sexp, env, eval)
+			if err != nil {
+				return errorf(sexp.Pos(), "%s: %w", name, err)
+			}
+
+			result, err := Call(fn, args)
+			var panicked *panicError
+			switch {
+			case err == nil:
+				return result
+			case object.KindOf(err) == object.ErrGo && !errors.As(err, &panicked):
+				// errors returned by Go functions name them already: strconv.Atoi: parsing "x"
+				return errorf(sexp.Pos(), "%w", err)
+			default:
+				return errorf(sexp.Pos(), "%s: %w", name, goError(err))
+			}
+		},
+	}
+}
+
+// typeBuiltin converts a value to the type: (time.Duration 10),
+// without arguments it returns a zero value.
+// Structs are allocated, so their pointer methods can be called: (strings.Builder).
+func typeBuiltin(name string, t reflect.Type) *object.Builtin {
+	return &object.Builtin{
+		Name: name,
+		Type: object.TypeFor(object.ObjAny),
+		Fn: func(sexp *ast.SExp, env *object.Env, eval object.Eval) object.Object {
+			args, err := evalArgs(sexp, env, eval)
+			if err != nil {
+				return errorf(sexp.Pos(), "%s: %w", name, err)
+			}
+
+			value := reflect.New(t).Elem()
+
+			switch len(args) {
+			case 0:
+				if t.Kind() == reflect.Struct {
+					value = reflect.New(t)
+				}
+			case 1:
+				value, err = ToGo(args[0], t)
+				if err != nil {
+					return errorf(sexp.Pos(), "%s: %w", name, err)
+				}
+			default:
+				return errorf(sexp.Pos(), "%s: type conversion want 0 or 1 arguments, got %d", name, len(args))
+			}
+
+			result, err := FromGo(value)
+			if err != nil {
+				return errorf(sexp.Pos(), "%s: %w", name, err)
+			}
+
+			return result
+		},
+	}
+}
+
+// Call calls a Go function with converted arguments.
+// A non-nil error as the last result and panics are returned as errors of the ErrGo kind,
+// a single result is returned as is, multiple results are packed into an array.
+func Call(fn reflect.Value, args []object.Object) (object.Object, error) {
+	in, err := callArgs(fn.Type(), args)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := call(fn, in)
+	if err != nil {
+		return nil, err
+	}
+
+	if n := len(out); n > 0 && fn.Type().Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return nil, goError(err)
+		}
+		out = out[:n-1]
+	}
+
+	results := make([]object.Object, 0, len(out))
+	for i, value := range out {
+		result, err := FromGo(value)
+		if err != nil {
+			return nil, fmt.Errorf("result %d: %w", i, err)
+		}
+		results = append(results, result)
+	}
+
+	switch len(results) {
+	case 0:
+		return object.Null{}, nil
+	case 1:
+		return results[0], nil
+	default:
+		return object.NewArray(results...), nil
+	}
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// panicError is a panic of a called Go function.
+type panicError struct {
+	value any
+}
+
+func (err *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", err.value)
+}
+
+// call calls the function, recovering its panics.
+func call(fn reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = object.WithKind(object.ErrGo, &panicError{value: r})
+		}
+	}()
+
+	return fn.Call(in), nil
+}
+
+// goError marks errors returned by Go functions, conversion errors keep their type kind.
+func goError(err error) error {
+	if object.KindOf(err) != object.ErrGeneric {
+		return err
+	}
+
+	return object.WithKind(object.ErrGo, err)
+}
+
+// CallMethod calls a method of a wrapped Go value by its name.
+func CallMethod(recv object.Object, name string, args []object.Object) (object.Object, error) {
+	wrapped, ok := recv.(*object.GoValue)
+	if !ok {
+		return nil, fmt.Errorf("%s %q has no methods", recv.Kind(), recv.Inspect())
+	}
+
+	method := wrapped.Value.MethodByName(name)
+	if !method.IsValid() {
+		return nil, fmt.Errorf("Go %s has no method %s", wrapped.Value.Type(), name)
+	}
+
+	return Call(method, args)
+}
+
+func callArgs(fnType reflect.Type, args []object.Object) ([]reflect.Value, error) {
+	want := fnType.NumIn()
+
+	switch {
+	case !fnType.IsVariadic() && len(args) != want:
+		return nil, fmt.Errorf("want %d arguments, got %d", want, len(args))
+	case fnType.IsVariadic() && len(args) < want-1:
+		return nil, fmt.Errorf("want at least %d arguments, got %d", want-1, len(args))
+	}
+
+	in := make([]reflect.Value, 0, len(args))
+
+	for i, arg := range args {
+		var t reflect.Type
+		if fnType.IsVariadic() && i >= want-1 {
+			t = fnType.In(want - 1).Elem()
+		} else {
+			t = fnType.In(i)
+		}
+
+		value, err := ToGo(arg, t)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+
+		in = append(in, value)
+	}
+
+	return in, nil
+}
+
+func evalArgs(sexp *ast.SExp, env *object.Env, eval object.Eval) ([]object.Object, error) {
+	args := make([]object.Object, 0, len(sexp.Items))
+
+	for i, item := range sexp.Items {
+		arg := eval(item, env)
+		if err, isErr := arg.(*object.Error); isErr {
+			return nil, fmt.Errorf("argument %d: %w", i, err.Err)
+		}
+
+		args = append(args, arg)
+	}
+
+	return args, nil
+}
+
+func errorf(pos ast.PosRange, msg string, args ...any) *object.Error {
+	return object.Errorf(pos, msg, args...)
+}
+
+// Wrap converts an object to its natural Go value: integers to int64, arrays to []any and so on.
+func Wrap(obj object.Object) (*object.GoValue, error) {
+	if wrapped, ok := obj.(*object.GoValue); ok {
+		return wrapped, nil
+	}
+
+	value, err := ToGo(obj, anyType)
+	if err != nil {
+		return nil, err
+	}
+
+	if value.IsNil() {
+		return nil, fmt.Errorf("%w %s to a Go value", errConvert, obj.Kind())
+	}
+
+	return &object.GoValue{Value: value.Elem()}, nil
+}
+
+var anyType = reflect.TypeFor[any]()
+
+// Field returns a field of a struct or a method of a Go value.
+// Pointers to structs are dereferenced.
+func Field(recv *object.GoValue, name string) (object.Object, error) {
+	if method := recv.Value.MethodByName(name); method.IsValid() {
+		return funcBuiltin(name, method), nil
+	}
+
+	value := recv.Value
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, fmt.Errorf("can't get field %s of nil %s", name, recv.Value.Type())
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Go %s has no field or method %s", recv.Value.Type(), name)
+	}
+
+	field, ok := value.Type().FieldByName(name)
+	if !ok || !field.IsExported() {
+		return nil, fmt.Errorf("Go %s has no field or method %s", recv.Value.Type(), name)
+	}
+
+	return FromGo(value.FieldByIndex(field.Index))
+}
+
+// Index returns an element of a slice, an array, a string or a map,
+// or a field of a struct by a keyword: (point :X).
+// Missing map keys give null.
+func Index(recv *object.GoValue, key object.Object) (object.Object, error) {
+	value := recv.Value
+	if value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		kw, ok := key.(object.Keyword)
+		if !ok {
+			return nil, fmt.Errorf("field of Go %s must be a keyword, got %s", value.Type(), key.Kind())
+		}
+
+		return Field(recv, strings.TrimPrefix(string(kw), ":"))
+	case reflect.Slice, reflect.Array, reflect.String:
+		i, ok := key.(*object.Primitive[int64])
+		if !ok {
+			return nil, fmt.Errorf("index of Go %s must be an integer, got %s", value.Type(), key.Kind())
+		}
+
+		if i.Value < 0 || i.Value >= int64(value.Len()) {
+			return nil, fmt.Errorf("index %d is out of bounds 0..%d", i.Value, value.Len())
+		}
+
+		return FromGo(value.Index(int(i.Value)))
+	case reflect.Map:
+		k, err := ToGo(key, value.Type().Key())
+		if err != nil {
+			return nil, fmt.Errorf("map key: %w", err)
+		}
+
+		elem := value.MapIndex(k)
+		if !elem.IsValid() {
+			return object.Null{}, nil
+		}
+
+		return FromGo(elem)
+	default:
+		return nil, fmt.Errorf("Go %s can't be indexed", value.Type())
+	}
+}
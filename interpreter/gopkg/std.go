@@ -0,0 +1,74 @@
+package gopkg
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// packages of the standard library, which are available by default
+func init() {
+	Register("strings", Members{
+		"Builder":    reflect.TypeFor[strings.Builder](),
+		"Contains":   strings.Contains,
+		"Count":      strings.Count,
+		"Fields":     strings.Fields,
+		"HasPrefix":  strings.HasPrefix,
+		"HasSuffix":  strings.HasSuffix,
+		"Index":      strings.Index,
+		"Join":       strings.Join,
+		"Repeat":     strings.Repeat,
+		"Replace":    strings.Replace,
+		"ReplaceAll": strings.ReplaceAll,
+		"Split":      strings.Split,
+		"ToLower":    strings.ToLower,
+		"ToUpper":    strings.ToUpper,
+		"TrimSpace":  strings.TrimSpace,
+	})
+
+	Register("strconv", Members{
+		"Atoi":       strconv.Atoi,
+		"Itoa":       strconv.Itoa,
+		"ParseFloat": strconv.ParseFloat,
+		"ParseInt":   strconv.ParseInt,
+		"Quote":      strconv.Quote,
+		"Unquote":    strconv.Unquote,
+	})
+
+	Register("math", Members{
+		"Abs":    math.Abs,
+		"Ceil":   math.Ceil,
+		"Floor":  math.Floor,
+		"Inf":    math.Inf,
+		"IsInf":  math.IsInf,
+		"IsNaN":  math.IsNaN,
+		"Max":    math.Max,
+		"Min":    math.Min,
+		"Pow":    math.Pow,
+		"Round":  math.Round,
+		"Sqrt":   math.Sqrt,
+		"E":      math.E,
+		"Pi":     math.Pi,
+		"MaxInt": math.MaxInt,
+		"MinInt": math.MinInt,
+	})
+
+	Register("unicode/utf8", Members{
+		"RuneCountInString": utf8.RuneCountInString,
+		"ValidString":       utf8.ValidString,
+	})
+
+	Register("time", Members{
+		"Nanosecond":    time.Nanosecond,
+		"Microsecond":   time.Microsecond,
+		"Millisecond":   time.Millisecond,
+		"Second":        time.Second,
+		"Minute":        time.Minute,
+		"Hour":          time.Hour,
+		"Duration":      reflect.TypeFor[time.Duration](),
+		"ParseDuration": time.ParseDuration,
+	})
+}
@@ -0,0 +1,48 @@
+package vm
+
+import (
+	"github.com/ninedraft/sulisp/compiler/bytecode"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// Closure is a compiled function with locals of enclosing function calls,
+// captured at the moment the closure was created.
+// Closures can be called by the interpreter as object.Callable.
+type Closure struct {
+	vm    *VM
+	fn    *bytecode.Function
+	outer *captured
+}
+
+func (*Closure) Kind() object.Kind { return object.ObjFunc }
+
+func (closure *Closure) Inspect() string {
+	node := closure.fn.Node
+	if node == nil {
+		return "fn main"
+	}
+
+	fn := &object.Function{
+		Name:       closure.fn.Name,
+		Parameters: node.Params,
+		Rest:       node.Rest,
+		Body:       node.Body,
+	}
+
+	return fn.Inspect()
+}
+
+// Definition returns the compiled fn form, its body sees globals of the VM.
+func (closure *Closure) Definition() (*ast.Fn, *object.Env) {
+	return closure.fn.Node, closure.vm.env
+}
+
+func (closure *Closure) Call(pos ast.PosRange, args []object.Object) object.Object {
+	if err := checkArity(closure.fn, len(args)); err != nil {
+		return fmtError(pos, "%w", err)
+	}
+
+	vm := closure.vm
+	return vm.execute(vm.newFrame(closure, pos, args))
+}
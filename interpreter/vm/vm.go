@@ -0,0 +1,590 @@
+// Package vm executes programs compiled by the bytecode package.
+//
+// The VM is an alternative to the tree-walking interpreter and produces the same results.
+// Values are kept on a stack, locals of a function call live in cells of its frame,
+// which are shared with closures created by the call.
+// Forms without bytecode counterparts are evaluated by astwalk with values of visible locals,
+// locals they assign are written back to cells, other names they bind are kept by the call.
+package vm
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ninedraft/sulisp/compiler/bytecode"
+	"github.com/ninedraft/sulisp/internal/collections/stack"
+	"github.com/ninedraft/sulisp/interpreter/astwalk"
+	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/object"
+)
+
+// Eval compiles the node and executes it in the env.
+// It's a drop-in replacement of astwalk.Eval.
+func Eval(node ast.Node, env *object.Env) object.Object {
+	if env == nil {
+		env = astwalk.DefaultEnv()
+	}
+
+	if err := astwalk.CheckCalls(node, env); err != nil {
+		return &object.Error{Err: err}
+	}
+
+	program, err := bytecode.Compile(node)
+	if err != nil {
+		return &object.Error{Err: err}
+	}
+
+	return New(env, program).Run()
+}
+
+// VM executes a program. Globals are stored in the env.
+type VM struct {
+	env     *object.Env
+	program *bytecode.Program
+	values  *stack.Stack[object.Object]
+	frames  *stack.Stack[*frame]
+}
+
+func New(env *object.Env, program *bytecode.Program) *VM {
+	return &VM{
+		env:     env,
+		program: program,
+		values:  &stack.Stack[object.Object]{},
+		frames:  &stack.Stack[*frame]{},
+	}
+}
+
+// frame is a call of a function.
+type frame struct {
+	closure *Closure
+	ip      int
+	locals  []*cell
+	base    int          // stack size at the call
+	call    ast.PosRange // position of the call, it's reported in stack traces
+	names   *names       // nil until the call creates a closure or binds a name
+}
+
+// names are bound by forms evaluated by the interpreter inside a function call,
+// like (import-go "strings") or (defn g ...) nested in a try form.
+// They are shared with closures created by the call.
+type names struct {
+	env *object.Env
+}
+
+func (n *names) lookup(name string) (object.Object, bool) {
+	if n == nil || n.env == nil {
+		return nil, false
+	}
+	return n.env.LookUp(name)
+}
+
+// cell is a binding of a local. Assignments change the value of the cell,
+// entering a block again replaces cells of its locals, so closures capturing old ones keep their values.
+type cell struct {
+	value object.Object
+}
+
+// get returns the value of the cell, unassigned cells are null.
+func (c *cell) get() object.Object {
+	if c.value != nil {
+		return c.value
+	}
+	return astwalk.Null
+}
+
+// captured is locals of an enclosing function call at the moment a closure was created.
+type captured struct {
+	locals []*cell
+	names  *names
+	outer  *captured
+}
+
+// local returns a cell of the current function or an enclosing one at the depth.
+func (fr *frame) local(depth, slot int) *cell {
+	if depth == 0 {
+		return fr.locals[slot]
+	}
+
+	outer := fr.closure.outer
+	for range depth - 1 {
+		outer = outer.outer
+	}
+
+	return outer.locals[slot]
+}
+
+// Run executes the main function of the program.
+func (vm *VM) Run() object.Object {
+	main := &Closure{vm: vm, fn: vm.program.Main}
+	return vm.execute(vm.newFrame(main, ast.PosRange{}, nil))
+}
+
+func (vm *VM) newFrame(closure *Closure, call ast.PosRange, args []object.Object) *frame {
+	fn := closure.fn
+	fr := &frame{
+		closure: closure,
+		locals:  make([]*cell, fn.NumLocals),
+		base:    vm.values.Len(),
+		call:    call,
+	}
+
+	for i := range fr.locals {
+		fr.locals[i] = &cell{}
+	}
+
+	for i, arg := range args[:fn.NumParams] {
+		fr.locals[i].value = arg
+	}
+
+	if fn.HasRest {
+		fr.locals[fn.NumParams].value = object.NewArray(args[fn.NumParams:]...)
+	}
+
+	if fn.HasSelf {
+		fr.locals[fn.SelfSlot()].value = closure
+	}
+
+	return fr
+}
+
+// execute runs the frame until it returns.
+// Calls of compiled functions push new frames without growing the Go stack.
+func (vm *VM) execute(entry *frame) object.Object {
+	depth := vm.frames.Len()
+	vm.frames.Push(entry)
+
+	for {
+		fr, _ := vm.frames.Peek()
+		fn := fr.closure.fn
+		ins := fn.Instructions
+
+		start := fr.ip
+		op := bytecode.Op(ins[start])
+		fr.ip++
+
+		switch op {
+		case bytecode.OpConstant:
+			vm.values.Push(vm.program.Constants[vm.operand(fr)])
+		case bytecode.OpNull:
+			vm.values.Push(astwalk.Null)
+		case bytecode.OpTrue:
+			vm.values.Push(astwalk.True)
+		case bytecode.OpFalse:
+			vm.values.Push(astwalk.False)
+		case bytecode.OpPop:
+			vm.pop()
+		case bytecode.OpDup:
+			top, _ := vm.values.Peek()
+			vm.values.Push(top)
+		case bytecode.OpGetGlobal:
+			name := vm.program.Names[vm.operand(fr)]
+			value, ok := vm.lookup(fr, name)
+			if !ok && vm.env.IsStrict() {
+				// locals are suggested as well as globals
+				scope := vm.scopeEnv(fr, fn.Scopes[start])
+				return vm.fail(depth, fmtError(fn.PosAt(start), "%w", scope.Undefined(name)))
+			}
+			vm.values.Push(value)
+		case bytecode.OpSetGlobal:
+			vm.env.Assign(vm.program.Names[vm.operand(fr)], vm.pop())
+		case bytecode.OpGetLocal:
+			vm.values.Push(fr.locals[vm.operand(fr)].get())
+		case bytecode.OpSetLocal:
+			fr.locals[vm.operand(fr)].value = vm.pop()
+		case bytecode.OpEnter:
+			for _, slot := range fn.Blocks[vm.operand(fr)] {
+				fr.locals[slot] = &cell{}
+			}
+		case bytecode.OpGetOuter:
+			depth := vm.byteOperand(fr)
+			vm.values.Push(fr.local(depth, vm.operand(fr)).get())
+		case bytecode.OpJump:
+			fr.ip = vm.operand(fr)
+		case bytecode.OpJumpIfFalse:
+			address := vm.operand(fr)
+
+			switch cond := vm.pop().(type) {
+			case *object.Primitive[bool]:
+				if !cond.Value {
+					fr.ip = address
+				}
+			default:
+				return vm.fail(depth, fmtError(fn.PosAt(start), "%w",
+					object.WithKind(object.ErrType, fmt.Errorf("unexpected condition value: %s %q", cond.Kind(), cond.Inspect()))))
+			}
+		case bytecode.OpLogical:
+			stop := vm.byteOperand(fr) == 1
+			index := vm.operand(fr)
+			address := vm.operand(fr)
+
+			name := "and"
+			if stop {
+				name = "or"
+			}
+
+			switch arg := vm.pop().(type) {
+			case *object.Primitive[bool]:
+				if arg.Value == stop {
+					vm.values.Push(arg)
+					fr.ip = address
+				}
+			default:
+				return vm.fail(depth, fmtError(fn.PosAt(start), "%s: %w", name,
+					object.WithKind(object.ErrType, fmt.Errorf("argument %d must be a boolean, got %s %q", index, arg.Kind(), arg.Inspect()))))
+			}
+		case bytecode.OpArithmetic:
+			operator := bytecode.Operators[vm.byteOperand(fr)]
+			args := vm.popN(vm.operand(fr))
+
+			result, err := astwalk.Arithmetic(operator, args)
+			if err != nil {
+				return vm.fail(depth, fmtError(fn.PosAt(start), "%s: %w", operator, err))
+			}
+
+			vm.values.Push(result)
+		case bytecode.OpArray:
+			vm.values.Push(object.NewArray(vm.popN(vm.operand(fr))...))
+		case bytecode.OpMap:
+			items := vm.popN(vm.operand(fr))
+
+			m := object.NewMap()
+			for i := 0; i < len(items); i += 2 {
+				if err := m.Set(items[i], items[i+1]); err != nil {
+					return vm.fail(depth, fmtError(fn.PosAt(start), "%w", err))
+				}
+			}
+
+			vm.values.Push(m)
+		case bytecode.OpUnpack:
+			n := vm.operand(fr)
+			hasRest := vm.byteOperand(fr) == 1
+
+			value := vm.pop()
+			array, ok := value.(*object.Array)
+			if !ok {
+				return vm.fail(depth, fmtError(fn.PosAt(start), "%w",
+					object.WithKind(object.ErrType, fmt.Errorf("can't destructure %s %q as an array", value.Kind(), value.Inspect()))))
+			}
+
+			unpack(vm.values, array, n, hasRest)
+		case bytecode.OpClosure:
+			if fr.names == nil {
+				fr.names = &names{}
+			}
+
+			vm.values.Push(&Closure{
+				vm: vm,
+				fn: vm.program.Functions[vm.operand(fr)],
+				outer: &captured{
+					locals: slices.Clone(fr.locals),
+					names:  fr.names,
+					outer:  fr.closure.outer,
+				},
+			})
+		case bytecode.OpApply:
+			form := vm.program.Forms[vm.operand(fr)]
+			address := vm.operand(fr)
+
+			head, _ := vm.values.Peek()
+			if isFunction(head) {
+				continue
+			}
+			vm.pop()
+
+			sexp := form.Node.(*ast.SExp)
+			scope := vm.formScope(fr, form)
+			result := astwalk.Apply(sexp.PosRange, sexp.Items[0], head, sexp.Items[1:], scope.env)
+			vm.writeBack(fr, scope)
+			if err, isErr := result.(*object.Error); isErr {
+				return vm.fail(depth, err)
+			}
+
+			vm.values.Push(result)
+			fr.ip = address
+		case bytecode.OpCall:
+			args := vm.popN(vm.operand(fr))
+			head := vm.pop()
+			pos := fn.PosAt(start)
+
+			switch head := head.(type) {
+			case *Closure:
+				if err := checkArity(head.fn, len(args)); err != nil {
+					return vm.fail(depth, fmtError(pos, "%w", err))
+				}
+
+				vm.frames.Push(vm.newFrame(head, pos, args))
+			case *object.Function:
+				result := astwalk.Call(pos, head, args)
+				if err, isErr := result.(*object.Error); isErr {
+					return vm.fail(depth, err)
+				}
+
+				vm.values.Push(result)
+			case object.Callable:
+				result := head.Call(pos, args)
+				if err, isErr := result.(*object.Error); isErr {
+					return vm.fail(depth, err)
+				}
+
+				vm.values.Push(result)
+			default:
+				return vm.fail(depth, fmtError(pos, "unexpected apply argument %s %s", head.Kind(), head.Inspect()))
+			}
+		case bytecode.OpReturn:
+			result := vm.pop()
+
+			if !vm.leave(depth, fr, result) {
+				return result
+			}
+		case bytecode.OpEval:
+			form := vm.program.Forms[vm.operand(fr)]
+
+			scope := vm.formScope(fr, form)
+			result := astwalk.Eval(form.Node, scope.env)
+			vm.writeBack(fr, scope)
+			switch r := result.(type) {
+			case *object.Error:
+				return vm.fail(depth, r)
+			case *object.Return:
+				// a return form nested in a form without bytecode counterpart
+				if fn == vm.program.Main {
+					return vm.fail(depth, fmtError(form.Node.Pos(), "return outside of fn"))
+				}
+
+				if !vm.leave(depth, fr, r.Value) {
+					return r.Value
+				}
+			default:
+				vm.values.Push(result)
+			}
+		default:
+			return vm.fail(depth, fmtError(fn.PosAt(start), "unexpected operation %d", op))
+		}
+	}
+}
+
+// leave pops the frame and passes the result to the calling frame.
+// It reports false if the frame is the entry frame of the current execute call.
+func (vm *VM) leave(depth int, fr *frame, result object.Object) bool {
+	vm.frames.Pop()
+	vm.truncate(fr.base)
+
+	if vm.frames.Len() == depth {
+		return false
+	}
+
+	vm.values.Push(result)
+	return true
+}
+
+// fail unwinds frames started by the current execute call and returns the error.
+// Calls of unwound functions are added to the stack of the error.
+func (vm *VM) fail(depth int, err *object.Error) object.Object {
+	var entry *frame
+	for vm.frames.Len() > depth {
+		entry, _ = vm.frames.Pop()
+
+		if fn := entry.closure.fn; fn != vm.program.Main {
+			err = err.WithFrame(fn.Name, entry.call)
+		}
+	}
+
+	vm.truncate(entry.base)
+
+	return err
+}
+
+func (vm *VM) operand(fr *frame) int {
+	ins := fr.closure.fn.Instructions
+	value := bytecode.ReadUint16(ins[fr.ip:])
+	fr.ip += 2
+	return int(value)
+}
+
+func (vm *VM) byteOperand(fr *frame) int {
+	value := fr.closure.fn.Instructions[fr.ip]
+	fr.ip++
+	return int(value)
+}
+
+func (vm *VM) pop() object.Object {
+	value, _ := vm.values.Pop()
+	return value
+}
+
+// popN pops n values in the order they were pushed.
+func (vm *VM) popN(n int) []object.Object {
+	values := make([]object.Object, n)
+	for i := n - 1; i >= 0; i-- {
+		values[i] = vm.pop()
+	}
+	return values
+}
+
+func (vm *VM) truncate(size int) {
+	for vm.values.Len() > size {
+		vm.values.Pop()
+	}
+}
+
+// lookup returns a value of a global or a name bound by forms of the call or enclosing ones.
+func (vm *VM) lookup(fr *frame, name string) (object.Object, bool) {
+	if value, ok := fr.names.lookup(name); ok {
+		return value, true
+	}
+
+	for outer := fr.closure.outer; outer != nil; outer = outer.outer {
+		if value, ok := outer.names.lookup(name); ok {
+			return value, true
+		}
+	}
+
+	return vm.env.LookUp(name)
+}
+
+// bind binds a name in the call, it shadows globals and names of enclosing calls.
+func (fr *frame) bind(name string, value object.Object) {
+	if fr.names == nil {
+		fr.names = &names{}
+	}
+
+	if fr.names.env == nil {
+		fr.names.env = object.NewEnv()
+	}
+
+	fr.names.env.Assign(name, value)
+}
+
+// scope is an env of a form evaluated by the interpreter
+// and values of locals it was created with.
+type scope struct {
+	env    *object.Env
+	locals []bytecode.Local
+	values []object.Object
+}
+
+// formScope binds values of locals visible to the form in a child of the globals env.
+func (vm *VM) formScope(fr *frame, form *bytecode.Form) *scope {
+	if len(form.Locals) == 0 && fr.closure.fn == vm.program.Main {
+		// top level forms bind globals
+		return &scope{env: vm.env}
+	}
+
+	env := vm.scopeEnv(fr, form.Locals)
+
+	values := make([]object.Object, len(form.Locals))
+	for i, l := range form.Locals {
+		values[i] = fr.local(l.Depth, l.Slot).value
+	}
+
+	return &scope{env: env, locals: form.Locals, values: values}
+}
+
+// writeBack assigns locals changed by a form to their cells
+// and binds new names in the call, as the interpreter binds them in the env of the function.
+func (vm *VM) writeBack(fr *frame, scope *scope) {
+	if scope.env == vm.env {
+		return
+	}
+
+	for name := range scope.env.Names {
+		value, _ := scope.env.LookUp(name)
+
+		i := slices.IndexFunc(scope.locals, func(l bytecode.Local) bool { return l.Name == name })
+		if i >= 0 {
+			// locals assigned by called closures in the meantime are kept
+			if value != scope.values[i] {
+				l := scope.locals[i]
+				fr.local(l.Depth, l.Slot).value = value
+			}
+			continue
+		}
+
+		if prev, ok := vm.lookup(fr, name); !ok || prev != value {
+			fr.bind(name, value)
+		}
+	}
+}
+
+// scopeEnv returns a child of the env with names bound by forms and values of the locals.
+func (vm *VM) scopeEnv(fr *frame, locals []bytecode.Local) *object.Env {
+	env := vm.env.Child()
+
+	var bound []*object.Env
+	if fr.names != nil && fr.names.env != nil {
+		bound = append(bound, fr.names.env)
+	}
+	for outer := fr.closure.outer; outer != nil; outer = outer.outer {
+		if outer.names != nil && outer.names.env != nil {
+			bound = append(bound, outer.names.env)
+		}
+	}
+
+	// names of outer calls first, so inner ones shadow them
+	for _, names := range slices.Backward(bound) {
+		for name := range names.Names {
+			value, _ := names.LookUp(name)
+			env.Assign(name, value)
+		}
+	}
+
+	for _, l := range locals {
+		if value := fr.local(l.Depth, l.Slot).value; value != nil {
+			env.Assign(l.Name, value)
+		}
+	}
+
+	return env
+}
+
+// unpack pushes the rest array and n elements of the array in the reverse order,
+// so they can be popped one by one. Missing elements are null.
+func unpack(values *stack.Stack[object.Object], array *object.Array, n int, hasRest bool) {
+	if hasRest {
+		rest := &object.Array{}
+		for i, element := range array.All() {
+			if i >= n {
+				rest.Append(element)
+			}
+		}
+		values.Push(rest)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		element, ok := array.At(i)
+		if !ok {
+			element = astwalk.Null
+		}
+		values.Push(element)
+	}
+}
+
+// isFunction reports if arguments of a call of the value are compiled.
+func isFunction(value object.Object) bool {
+	switch value.(type) {
+	case *object.Function, object.Callable:
+		return true
+	default:
+		return false
+	}
+}
+
+func checkArity(fn *bytecode.Function, n int) error {
+	name := fn.Name
+	if name == "" {
+		name = "anonymous function"
+	}
+
+	switch {
+	case !fn.HasRest && n != fn.NumParams:
+		return object.WithKind(object.ErrArity, fmt.Errorf("%s expects %d arguments, got %d", name, fn.NumParams, n))
+	case fn.HasRest && n < fn.NumParams:
+		return object.WithKind(object.ErrArity, fmt.Errorf("%s expects at least %d arguments, got %d", name, fn.NumParams, n))
+	}
+
+	return nil
+}
+
+func fmtError(pos ast.PosRange, msg string, args ...any) *object.Error {
+	return object.Errorf(pos, msg, args...)
+}
@@ -112,6 +112,7 @@ func (pkg *Package) Clone() Node {
 type Symbol struct {
 	PosRange
 	Value string
+	Type  Node // optional type annotation of a bound name: x :- integer
 }
 
 func (sym *Symbol) Equal(node Node) bool {
@@ -119,19 +120,37 @@ func (sym *Symbol) Equal(node Node) bool {
 		return node == nil
 	}
 
-	if o, _ := node.(*Symbol); o != nil {
-		return sym.Value == o.Value
+	o, _ := node.(*Symbol)
+	if o == nil || sym.Value != o.Value {
+		return false
 	}
 
-	return false
+	if sym.Type == nil || o.Type == nil {
+		return sym.Type == nil && o.Type == nil
+	}
+
+	return sym.Type.Equal(o.Type)
 }
 
 func (*Symbol) Name() string { return tokens.TokenSymbol.String() }
 
-func (sym *Symbol) String() string { return sym.Value }
+func (sym *Symbol) String() string {
+	if sym.Type != nil {
+		return sym.Value + " :- " + sym.Type.String()
+	}
+
+	return sym.Value
+}
 
 func (sym *Symbol) Clone() Node {
-	return shallow(sym)
+	if sym == nil {
+		return nil
+	}
+
+	clone := *sym
+	clone.Type = Clone(sym.Type)
+
+	return &clone
 }
 
 type Keyword struct {
@@ -244,6 +263,200 @@ func (dot *DotSelector) Clone() Node {
 	return clone
 }
 
+// Vector is an array literal: [a b c].
+type Vector struct {
+	PosRange
+	Items []Node
+}
+
+func (vec *Vector) Equal(other Node) bool {
+	if vec == nil {
+		return other == nil
+	}
+
+	if o, _ := other.(*Vector); o != nil {
+		return equalSlices(vec.Items, o.Items)
+	}
+
+	return false
+}
+
+func (*Vector) Name() string { return "vector" }
+
+func (vec *Vector) String() string {
+	str := &strings.Builder{}
+
+	str.WriteRune('[')
+	joinStringers(str, " ", vec.Items)
+	str.WriteRune(']')
+
+	return str.String()
+}
+
+func (vec *Vector) Clone() Node {
+	if vec == nil {
+		return nil
+	}
+
+	clone := *vec
+	clone.Items = cloneSlice(vec.Items)
+
+	return &clone
+}
+
+// Map is a hash map literal: {key value key value}.
+// Items are keys and values in turn.
+type Map struct {
+	PosRange
+	Items []Node
+}
+
+func (m *Map) Equal(other Node) bool {
+	if m == nil {
+		return other == nil
+	}
+
+	if o, _ := other.(*Map); o != nil {
+		return equalSlices(m.Items, o.Items)
+	}
+
+	return false
+}
+
+func (*Map) Name() string { return "map" }
+
+func (m *Map) String() string {
+	str := &strings.Builder{}
+
+	str.WriteRune('{')
+	joinStringers(str, " ", m.Items)
+	str.WriteRune('}')
+
+	return str.String()
+}
+
+func (m *Map) Clone() Node {
+	if m == nil {
+		return nil
+	}
+
+	clone := *m
+	clone.Items = cloneSlice(m.Items)
+
+	return &clone
+}
+
+// Quote is a quoted form: 'x.
+type Quote struct {
+	PosRange
+	Node Node
+}
+
+func (quote *Quote) Equal(other Node) bool {
+	if quote == nil {
+		return other == nil
+	}
+
+	if o, _ := other.(*Quote); o != nil {
+		return quote.Node.Equal(o.Node)
+	}
+
+	return false
+}
+
+func (*Quote) Name() string { return "quote" }
+
+func (quote *Quote) String() string { return "'" + quote.Node.String() }
+
+func (quote *Quote) Clone() Node {
+	if quote == nil {
+		return nil
+	}
+
+	clone := shallow(quote)
+	clone.Node = Clone(quote.Node)
+
+	return clone
+}
+
+// Quasiquote is a template form: `(a ~b ~@c).
+type Quasiquote struct {
+	PosRange
+	Node Node
+}
+
+func (quasi *Quasiquote) Equal(other Node) bool {
+	if quasi == nil {
+		return other == nil
+	}
+
+	if o, _ := other.(*Quasiquote); o != nil {
+		return quasi.Node.Equal(o.Node)
+	}
+
+	return false
+}
+
+func (*Quasiquote) Name() string { return "quasiquote" }
+
+func (quasi *Quasiquote) String() string { return "`" + quasi.Node.String() }
+
+func (quasi *Quasiquote) Clone() Node {
+	if quasi == nil {
+		return nil
+	}
+
+	clone := shallow(quasi)
+	clone.Node = Clone(quasi.Node)
+
+	return clone
+}
+
+// Unquote evaluates a form inside of a quasiquote: ~x.
+// Splice unquote ~@x inserts elements of x into the enclosing list.
+type Unquote struct {
+	PosRange
+	Node   Node
+	Splice bool
+}
+
+func (unquote *Unquote) Equal(other Node) bool {
+	if unquote == nil {
+		return other == nil
+	}
+
+	if o, _ := other.(*Unquote); o != nil {
+		return unquote.Splice == o.Splice && unquote.Node.Equal(o.Node)
+	}
+
+	return false
+}
+
+func (unquote *Unquote) Name() string {
+	if unquote.Splice {
+		return "unquote-splicing"
+	}
+	return "unquote"
+}
+
+func (unquote *Unquote) String() string {
+	if unquote.Splice {
+		return "~@" + unquote.Node.String()
+	}
+	return "~" + unquote.Node.String()
+}
+
+func (unquote *Unquote) Clone() Node {
+	if unquote == nil {
+		return nil
+	}
+
+	clone := shallow(unquote)
+	clone.Node = Clone(unquote.Node)
+
+	return clone
+}
+
 func Clone[E Node](node E) E {
 	n := Node(node)
 
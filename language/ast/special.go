@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"slices"
 	"strings"
 )
 
@@ -148,3 +149,616 @@ func (special *SpecialOp) String() string {
 
 	return str.String()
 }
+
+type Fn struct {
+	PosRange
+	Ident  *Symbol // optional, binds the function to itself inside the body
+	Params []*Symbol
+	Rest   *Symbol // optional, collects the remaining arguments: (x & rest)
+	Body   []Node
+}
+
+func (*Fn) Name() string { return "fn" }
+
+func (fn *Fn) Equal(other Node) bool {
+	if fn == nil {
+		return other == nil
+	}
+
+	o, ok := other.(*Fn)
+	if !ok {
+		return false
+	}
+
+	return equalOptional(fn.Ident, o.Ident) &&
+		equalSlices(fn.Params, o.Params) &&
+		equalOptional(fn.Rest, o.Rest) &&
+		equalSlices(fn.Body, o.Body)
+}
+
+func (fn *Fn) Clone() Node {
+	if fn == nil {
+		return nil
+	}
+
+	clone := *fn
+	clone.Ident = Clone(fn.Ident)
+	clone.Params = cloneSlice(fn.Params)
+	clone.Rest = Clone(fn.Rest)
+	clone.Body = cloneSlice(fn.Body)
+
+	return &clone
+}
+
+func (fn *Fn) String() string {
+	str := &strings.Builder{}
+
+	writeStrs(str, "(fn ")
+	if fn.Ident != nil {
+		writeStrs(str, fn.Ident.String(), " ")
+	}
+	writeParams(str, fn.Params, fn.Rest)
+
+	for _, item := range fn.Body {
+		writeStrs(str, "\n    ", item.String())
+	}
+
+	writeStrs(str, ")")
+
+	return str.String()
+}
+
+type Defn struct {
+	PosRange
+	Fn *Fn // Fn.Ident is the name assigned in the env
+}
+
+func (*Defn) Name() string { return "defn" }
+
+func (defn *Defn) Equal(other Node) bool {
+	if defn == nil {
+		return other == nil
+	}
+
+	if o, ok := other.(*Defn); ok {
+		return defn.Fn.Equal(o.Fn)
+	}
+
+	return false
+}
+
+func (defn *Defn) Clone() Node {
+	if defn == nil {
+		return nil
+	}
+
+	clone := *defn
+	clone.Fn = Clone(defn.Fn)
+
+	return &clone
+}
+
+func (defn *Defn) String() string {
+	str := &strings.Builder{}
+
+	writeStrs(str, "(defn ", defn.Fn.Ident.String(), " ")
+	writeParams(str, defn.Fn.Params, defn.Fn.Rest)
+
+	for _, item := range defn.Fn.Body {
+		writeStrs(str, "\n    ", item.String())
+	}
+
+	writeStrs(str, ")")
+
+	return str.String()
+}
+
+func writeParams[N Node](str *strings.Builder, params []N, rest *Symbol) {
+	writeStrs(str, "(")
+	joinStringers(str, " ", params)
+
+	if rest != nil {
+		if len(params) > 0 {
+			writeStrs(str, " ")
+		}
+		writeStrs(str, "& ", rest.String())
+	}
+
+	writeStrs(str, ")")
+}
+
+// equalOptional compares optional node fields, which can be nil pointers.
+func equalOptional[N interface {
+	*E
+	Node
+}, E any](a, b N) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return a.Equal(b)
+}
+
+type Do struct {
+	PosRange
+	Body []Node
+}
+
+func (*Do) Name() string { return "do" }
+
+func (do *Do) Equal(other Node) bool {
+	if do == nil {
+		return other == nil
+	}
+
+	if o, ok := other.(*Do); ok {
+		return equalSlices(do.Body, o.Body)
+	}
+
+	return false
+}
+
+func (do *Do) Clone() Node {
+	if do == nil {
+		return nil
+	}
+
+	clone := *do
+	clone.Body = cloneSlice(do.Body)
+
+	return &clone
+}
+
+func (do *Do) String() string {
+	str := &strings.Builder{}
+
+	writeStrs(str, "(do")
+
+	for _, item := range do.Body {
+		writeStrs(str, "\n    ", item.String())
+	}
+
+	writeStrs(str, ")")
+
+	return str.String()
+}
+
+// Binding is a single name-value pair of a let form.
+type Binding struct {
+	Target Node // *Symbol | *ArrayPattern
+	Value  Node
+}
+
+func (binding Binding) Equal(other Binding) bool {
+	return binding.Target.Equal(other.Target) && binding.Value.Equal(other.Value)
+}
+
+type Let struct {
+	PosRange
+	Bindings []Binding
+	Body     []Node
+}
+
+func (*Let) Name() string { return "let" }
+
+func (let *Let) Equal(other Node) bool {
+	if let == nil {
+		return other == nil
+	}
+
+	o, ok := other.(*Let)
+	if !ok {
+		return false
+	}
+
+	return slices.EqualFunc(let.Bindings, o.Bindings, Binding.Equal) &&
+		equalSlices(let.Body, o.Body)
+}
+
+func (let *Let) Clone() Node {
+	if let == nil {
+		return nil
+	}
+
+	clone := *let
+	clone.Bindings = cloneBindings(let.Bindings)
+	clone.Body = cloneSlice(let.Body)
+
+	return &clone
+}
+
+func (let *Let) String() string {
+	str := &strings.Builder{}
+	writeBindingForm(str, "let", let.Bindings, let.Body)
+	return str.String()
+}
+
+// ArrayPattern destructures an array into names: (a (b c) & rest).
+type ArrayPattern struct {
+	PosRange
+	Items []Node  // *Symbol | *ArrayPattern
+	Rest  *Symbol // optional
+}
+
+func (*ArrayPattern) Name() string { return "array-pattern" }
+
+func (pattern *ArrayPattern) Equal(other Node) bool {
+	if pattern == nil {
+		return other == nil
+	}
+
+	if o, ok := other.(*ArrayPattern); ok {
+		return equalSlices(pattern.Items, o.Items) && equalOptional(pattern.Rest, o.Rest)
+	}
+
+	return false
+}
+
+func (pattern *ArrayPattern) Clone() Node {
+	if pattern == nil {
+		return nil
+	}
+
+	clone := *pattern
+	clone.Items = cloneSlice(pattern.Items)
+	clone.Rest = Clone(pattern.Rest)
+
+	return &clone
+}
+
+func (pattern *ArrayPattern) String() string {
+	str := &strings.Builder{}
+	writeParams(str, pattern.Items, pattern.Rest)
+	return str.String()
+}
+
+// Loop is a recur target: (loop (name value) body...).
+type Loop struct {
+	PosRange
+	Bindings []Binding
+	Body     []Node
+}
+
+func (*Loop) Name() string { return "loop" }
+
+func (loop *Loop) Equal(other Node) bool {
+	if loop == nil {
+		return other == nil
+	}
+
+	o, ok := other.(*Loop)
+	if !ok {
+		return false
+	}
+
+	return slices.EqualFunc(loop.Bindings, o.Bindings, Binding.Equal) &&
+		equalSlices(loop.Body, o.Body)
+}
+
+func (loop *Loop) Clone() Node {
+	if loop == nil {
+		return nil
+	}
+
+	clone := *loop
+	clone.Bindings = cloneBindings(loop.Bindings)
+	clone.Body = cloneSlice(loop.Body)
+
+	return &clone
+}
+
+func (loop *Loop) String() string {
+	str := &strings.Builder{}
+	writeBindingForm(str, "loop", loop.Bindings, loop.Body)
+	return str.String()
+}
+
+// Recur jumps to the innermost loop or fn with new values of bindings.
+// It can be used only in a tail position.
+type Recur struct {
+	PosRange
+	Args []Node
+}
+
+func (*Recur) Name() string { return "recur" }
+
+func (recur *Recur) Equal(other Node) bool {
+	if recur == nil {
+		return other == nil
+	}
+
+	if o, ok := other.(*Recur); ok {
+		return equalSlices(recur.Args, o.Args)
+	}
+
+	return false
+}
+
+func (recur *Recur) Clone() Node {
+	if recur == nil {
+		return nil
+	}
+
+	clone := *recur
+	clone.Args = cloneSlice(recur.Args)
+
+	return &clone
+}
+
+func (recur *Recur) String() string {
+	str := &strings.Builder{}
+
+	writeStrs(str, "(recur")
+	for _, arg := range recur.Args {
+		writeStrs(str, " ", arg.String())
+	}
+	writeStrs(str, ")")
+
+	return str.String()
+}
+
+// Return leaves the innermost fn with the value, null if it's omitted.
+// It can be used in any position of a body, including branches of if, do and loops.
+type Return struct {
+	PosRange
+	Value Node // optional
+}
+
+func (*Return) Name() string { return "return" }
+
+func (ret *Return) Equal(other Node) bool {
+	if ret == nil {
+		return other == nil
+	}
+
+	o, ok := other.(*Return)
+	if !ok {
+		return false
+	}
+
+	if ret.Value == nil || o.Value == nil {
+		return ret.Value == nil && o.Value == nil
+	}
+
+	return ret.Value.Equal(o.Value)
+}
+
+func (ret *Return) Clone() Node {
+	if ret == nil {
+		return nil
+	}
+
+	clone := *ret
+	clone.Value = Clone(ret.Value)
+
+	return &clone
+}
+
+func (ret *Return) String() string {
+	if ret.Value == nil {
+		return "(return)"
+	}
+
+	return "(return " + ret.Value.String() + ")"
+}
+
+type While struct {
+	PosRange
+	Cond Node
+	Body []Node
+}
+
+func (*While) Name() string { return "while" }
+
+func (while *While) Equal(other Node) bool {
+	if while == nil {
+		return other == nil
+	}
+
+	if o, ok := other.(*While); ok {
+		return while.Cond.Equal(o.Cond) && equalSlices(while.Body, o.Body)
+	}
+
+	return false
+}
+
+func (while *While) Clone() Node {
+	if while == nil {
+		return nil
+	}
+
+	clone := *while
+	clone.Cond = Clone(while.Cond)
+	clone.Body = cloneSlice(while.Body)
+
+	return &clone
+}
+
+func (while *While) String() string {
+	str := &strings.Builder{}
+
+	writeStrs(str, "(while ", while.Cond.String())
+
+	for _, item := range while.Body {
+		writeStrs(str, "\n    ", item.String())
+	}
+
+	writeStrs(str, ")")
+
+	return str.String()
+}
+
+// Try evaluates the body and handles its errors by the first matching catch clause.
+// The finally body is evaluated at last in any case, its result is dropped.
+//
+//	(try body...
+//	  (catch :type-error e body...)
+//	  (catch pred e body...)
+//	  (finally body...))
+type Try struct {
+	PosRange
+	Body    []Node
+	Catches []Catch
+	Finally []Node // optional
+}
+
+// Catch is a clause of a try form.
+// Match is a keyword of an error kind, :default to catch anything, or a predicate of a caught value.
+type Catch struct {
+	PosRange
+	Match Node
+	Ident *Symbol
+	Body  []Node
+}
+
+func (catch Catch) Equal(other Catch) bool {
+	return catch.Match.Equal(other.Match) &&
+		catch.Ident.Equal(other.Ident) &&
+		equalSlices(catch.Body, other.Body)
+}
+
+func (*Try) Name() string { return "try" }
+
+func (try *Try) Equal(other Node) bool {
+	if try == nil {
+		return other == nil
+	}
+
+	o, ok := other.(*Try)
+	if !ok {
+		return false
+	}
+
+	return equalSlices(try.Body, o.Body) &&
+		slices.EqualFunc(try.Catches, o.Catches, Catch.Equal) &&
+		equalSlices(try.Finally, o.Finally)
+}
+
+func (try *Try) Clone() Node {
+	if try == nil {
+		return nil
+	}
+
+	clone := *try
+	clone.Body = cloneSlice(try.Body)
+	clone.Finally = cloneSlice(try.Finally)
+
+	clone.Catches = make([]Catch, 0, len(try.Catches))
+	for _, catch := range try.Catches {
+		catch.Match = Clone(catch.Match)
+		catch.Ident = Clone(catch.Ident)
+		catch.Body = cloneSlice(catch.Body)
+		clone.Catches = append(clone.Catches, catch)
+	}
+
+	return &clone
+}
+
+func (try *Try) String() string {
+	str := &strings.Builder{}
+
+	writeStrs(str, "(try")
+
+	for _, item := range try.Body {
+		writeStrs(str, "\n    ", item.String())
+	}
+
+	for _, catch := range try.Catches {
+		writeStrs(str, "\n    (catch ", catch.Match.String(), " ", catch.Ident.String())
+		for _, item := range catch.Body {
+			writeStrs(str, "\n        ", item.String())
+		}
+		writeStrs(str, ")")
+	}
+
+	if try.Finally != nil {
+		writeStrs(str, "\n    (finally")
+		for _, item := range try.Finally {
+			writeStrs(str, "\n        ", item.String())
+		}
+		writeStrs(str, ")")
+	}
+
+	writeStrs(str, ")")
+
+	return str.String()
+}
+
+func cloneBindings(bindings []Binding) []Binding {
+	clone := make([]Binding, 0, len(bindings))
+
+	for _, binding := range bindings {
+		clone = append(clone, Binding{
+			Target: Clone(binding.Target),
+			Value:  Clone(binding.Value),
+		})
+	}
+
+	return clone
+}
+
+func writeBindingForm(str *strings.Builder, name string, bindings []Binding, body []Node) {
+	writeStrs(str, "(", name, " (")
+
+	for i, binding := range bindings {
+		if i > 0 {
+			writeStrs(str, "\n      ")
+		}
+		writeStrs(str, binding.Target.String(), " ", binding.Value.String())
+	}
+
+	writeStrs(str, ")")
+
+	for _, item := range body {
+		writeStrs(str, "\n    ", item.String())
+	}
+
+	writeStrs(str, ")")
+}
+
+// Defmacro defines a macro: a function from forms to a form,
+// which is called before evaluation.
+type Defmacro struct {
+	PosRange
+	Fn *Fn // Fn.Ident is the macro name
+}
+
+func (*Defmacro) Name() string { return "defmacro" }
+
+func (defmacro *Defmacro) Equal(other Node) bool {
+	if defmacro == nil {
+		return other == nil
+	}
+
+	if o, ok := other.(*Defmacro); ok {
+		return defmacro.Fn.Equal(o.Fn)
+	}
+
+	return false
+}
+
+func (defmacro *Defmacro) Clone() Node {
+	if defmacro == nil {
+		return nil
+	}
+
+	clone := *defmacro
+	clone.Fn = Clone(defmacro.Fn)
+
+	return &clone
+}
+
+func (defmacro *Defmacro) String() string {
+	str := &strings.Builder{}
+
+	writeStrs(str, "(defmacro ", defmacro.Fn.Ident.String(), " ")
+	writeParams(str, defmacro.Fn.Params, defmacro.Fn.Rest)
+
+	for _, item := range defmacro.Fn.Body {
+		writeStrs(str, "\n    ", item.String())
+	}
+
+	writeStrs(str, ")")
+
+	return str.String()
+}
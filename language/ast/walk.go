@@ -0,0 +1,120 @@
+package ast
+
+// MapChildren returns a shallow copy of the node with child forms replaced by results of fn.
+// Binding targets, parameters and names are kept as is.
+// Quoted forms are data, so they are returned without changes.
+func MapChildren(node Node, fn func(Node) Node) Node {
+	mapAll := func(nodes []Node) []Node {
+		mapped := make([]Node, 0, len(nodes))
+		for _, node := range nodes {
+			mapped = append(mapped, fn(node))
+		}
+		return mapped
+	}
+
+	mapBindings := func(bindings []Binding) []Binding {
+		mapped := make([]Binding, 0, len(bindings))
+		for _, binding := range bindings {
+			mapped = append(mapped, Binding{
+				Target: binding.Target,
+				Value:  fn(binding.Value),
+			})
+		}
+		return mapped
+	}
+
+	switch node := node.(type) {
+	case *Package:
+		clone := *node
+		clone.Nodes = mapAll(node.Nodes)
+		return &clone
+	case *SExp:
+		clone := *node
+		clone.Items = mapAll(node.Items)
+		return &clone
+	case *Vector:
+		clone := *node
+		clone.Items = mapAll(node.Items)
+		return &clone
+	case *Map:
+		clone := *node
+		clone.Items = mapAll(node.Items)
+		return &clone
+	case *DotSelector:
+		clone := *node
+		clone.Left, clone.Right = fn(node.Left), fn(node.Right)
+		return &clone
+	case *Unquote:
+		clone := *node
+		clone.Node = fn(node.Node)
+		return &clone
+	case *If:
+		clone := *node
+		clone.Cond, clone.Then = fn(node.Cond), fn(node.Then)
+		if node.Else != nil {
+			clone.Else = fn(node.Else)
+		}
+		return &clone
+	case *SpecialOp:
+		clone := *node
+		clone.Items = mapAll(node.Items)
+		return &clone
+	case *Fn:
+		clone := *node
+		clone.Body = mapAll(node.Body)
+		return &clone
+	case *Defn:
+		clone := *node
+		clone.Fn = MapChildren(node.Fn, fn).(*Fn)
+		return &clone
+	case *Defmacro:
+		clone := *node
+		clone.Fn = MapChildren(node.Fn, fn).(*Fn)
+		return &clone
+	case *Do:
+		clone := *node
+		clone.Body = mapAll(node.Body)
+		return &clone
+	case *Let:
+		clone := *node
+		clone.Bindings = mapBindings(node.Bindings)
+		clone.Body = mapAll(node.Body)
+		return &clone
+	case *Loop:
+		clone := *node
+		clone.Bindings = mapBindings(node.Bindings)
+		clone.Body = mapAll(node.Body)
+		return &clone
+	case *Recur:
+		clone := *node
+		clone.Args = mapAll(node.Args)
+		return &clone
+	case *Return:
+		clone := *node
+		if node.Value != nil {
+			clone.Value = fn(node.Value)
+		}
+		return &clone
+	case *While:
+		clone := *node
+		clone.Cond = fn(node.Cond)
+		clone.Body = mapAll(node.Body)
+		return &clone
+	case *Try:
+		clone := *node
+		clone.Body = mapAll(node.Body)
+		clone.Catches = make([]Catch, 0, len(node.Catches))
+		for _, catch := range node.Catches {
+			catch.Match = fn(catch.Match)
+			catch.Body = mapAll(catch.Body)
+			clone.Catches = append(clone.Catches, catch)
+		}
+		if node.Finally != nil {
+			clone.Finally = mapAll(node.Finally)
+		}
+		return &clone
+	default:
+		// literals, symbols, keywords, quotes and imports have no child forms
+		return node
+	}
+}
@@ -1,8 +1,15 @@
 package object
 
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
 type Env struct {
 	parent *Env
 	values map[string]Object
+	strict bool
 }
 
 func NewEnv() *Env {
@@ -19,6 +26,115 @@ func (env *Env) Names(yield func(string) bool) {
 	}
 }
 
+// VisibleNames yields names bound in the env and its parents, each name once.
+func (env *Env) VisibleNames(yield func(string) bool) {
+	seen := map[string]bool{}
+	for scope := env; scope != nil; scope = scope.parent {
+		for name := range scope.Names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			if !yield(name) {
+				return
+			}
+		}
+	}
+}
+
+// SetStrict switches the strict mode of the env and its children:
+// evaluation of unbound names fails instead of returning null.
+func (env *Env) SetStrict(strict bool) *Env {
+	env.strict = strict
+	return env
+}
+
+// IsStrict reports if the env or one of its parents is in the strict mode.
+func (env *Env) IsStrict() bool {
+	for scope := env; scope != nil; scope = scope.parent {
+		if scope.strict {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Undefined returns an error of the unbound name
+// with suggestions of similar names visible in the env.
+func (env *Env) Undefined(name string) error {
+	return WithKind(ErrUndefined, &UndefinedError{
+		Name:        name,
+		Suggestions: env.similar(name),
+	})
+}
+
+// maxSuggestions limits names suggested for an unbound name.
+const maxSuggestions = 3
+
+// similar returns visible names close to the name by the edit distance,
+// the closest first. Names sharing no characters with short names aren't similar.
+func (env *Env) similar(name string) []string {
+	size := len([]rune(name))
+	limit := min(max(1, size/3), size-1)
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	var candidates []candidate
+	for visible := range env.VisibleNames {
+		if d := editDistance(name, visible); d <= limit {
+			candidates = append(candidates, candidate{name: visible, distance: d})
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(cmp.Compare(a.distance, b.distance), strings.Compare(a.name, b.name))
+	})
+
+	names := make([]string, 0, min(len(candidates), maxSuggestions))
+	for _, c := range candidates[:min(len(candidates), maxSuggestions)] {
+		names = append(names, c.name)
+	}
+
+	return names
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters turning a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// rows of distances between prefixes of a and prefixes of b
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := range ra {
+		cur[0] = i + 1
+		for j := range rb {
+			cost := 1
+			if ra[i] == rb[j] {
+				cost = 0
+			}
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+cost)
+
+			if i > 0 && j > 0 && ra[i] == rb[j-1] && ra[i-1] == rb[j] {
+				cur[j+1] = min(cur[j+1], prev2[j-1]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(rb)]
+}
+
 func (env *Env) Child() *Env {
 	return &Env{
 		parent: env,
@@ -0,0 +1,159 @@
+package object
+
+import "cmp"
+
+// Equal reports whether two objects are structurally equal.
+// Integers and floats are compared numerically,
+// arrays, maps, sets and namespaces are compared element by element.
+func Equal(a, b Object) bool {
+	if x, y, ok := numbers(a, b); ok {
+		return x == y
+	}
+
+	switch a := a.(type) {
+	case *Primitive[int64]:
+		b, ok := b.(*Primitive[int64])
+		return ok && a.Value == b.Value
+	case *Primitive[string]:
+		b, ok := b.(*Primitive[string])
+		return ok && a.Value == b.Value
+	case *Primitive[bool]:
+		b, ok := b.(*Primitive[bool])
+		return ok && a.Value == b.Value
+	case Null:
+		return b.Kind() == ObjNull
+	case Kind:
+		b, ok := b.(Kind)
+		return ok && a == b
+	case *Type:
+		b, ok := b.(*Type)
+		return ok && a.Equal(b)
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && equalArrays(a, b)
+	case Keyword:
+		b, ok := b.(Keyword)
+		return ok && a == b
+	case *Map:
+		b, ok := b.(*Map)
+		return ok && equalMaps(a, b)
+	case *Set:
+		b, ok := b.(*Set)
+		return ok && equalSets(a, b)
+	case *Namespace:
+		b, ok := b.(*Namespace)
+		return ok && equalEnvs(a.Env, b.Env)
+	case *AST:
+		b, ok := b.(*AST)
+		return ok && a.Node.Equal(b.Node)
+	case *GoValue:
+		b, ok := b.(*GoValue)
+		return ok && a.Value.Comparable() && b.Value.Comparable() && a.Value.Equal(b.Value)
+	}
+
+	return a == b
+}
+
+// Compare orders two objects. Integers and floats are compared numerically,
+// other objects are comparable only with objects of the same kind.
+func Compare(a, b Object) (int, bool) {
+	if x, y, ok := numbers(a, b); ok {
+		return cmp.Compare(x, y), true
+	}
+
+	if a.Kind() != b.Kind() {
+		return 0, false
+	}
+
+	ordered, ok := a.(Ordered)
+	if !ok {
+		return 0, false
+	}
+
+	return ordered.Compare(b)
+}
+
+// numbers converts a pair of numeric objects to floats if one of them is a float.
+func numbers(a, b Object) (x, y float64, ok bool) {
+	ai, aIsInt := a.(*Primitive[int64])
+	bi, bIsInt := b.(*Primitive[int64])
+	af, aIsFloat := a.(*Primitive[float64])
+	bf, bIsFloat := b.(*Primitive[float64])
+
+	switch {
+	case aIsInt && bIsInt:
+		// floats can lose precision on large integers
+		return 0, 0, false
+	case aIsFloat && bIsFloat:
+		return af.Value, bf.Value, true
+	case aIsInt && bIsFloat:
+		return float64(ai.Value), bf.Value, true
+	case aIsFloat && bIsInt:
+		return af.Value, float64(bi.Value), true
+	}
+
+	return 0, 0, false
+}
+
+func equalArrays(a, b *Array) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+
+	for i, element := range a.All() {
+		other, _ := b.At(i)
+		if !Equal(element, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalMaps(a, b *Map) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+
+	for key, value := range a.All() {
+		other, ok := b.Get(key)
+		if !ok || !Equal(value, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalSets(a, b *Set) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+
+	for element := range a.All() {
+		if !b.Contains(element) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalEnvs(a, b *Env) bool {
+	if a == b {
+		return true
+	}
+
+	if len(a.values) != len(b.values) {
+		return false
+	}
+
+	for name, value := range a.values {
+		other, ok := b.values[name]
+		if !ok || !Equal(value, other) {
+			return false
+		}
+	}
+
+	return true
+}
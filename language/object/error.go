@@ -0,0 +1,261 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ninedraft/sulisp/language/ast"
+)
+
+// ErrorKind classifies runtime errors.
+type ErrorKind string
+
+const (
+	ErrGeneric    ErrorKind = "error"
+	ErrType       ErrorKind = "type-error"
+	ErrArity      ErrorKind = "arity-error"
+	ErrArithmetic ErrorKind = "arithmetic-error"
+	ErrIndex      ErrorKind = "index-error"
+	ErrImport     ErrorKind = "import-error"
+	ErrGo         ErrorKind = "go-error"
+	ErrInfo       ErrorKind = "ex-info"
+	ErrThrown     ErrorKind = "thrown"
+	ErrUndefined  ErrorKind = "undefined-symbol"
+)
+
+// RuntimeError is an error of evaluation.
+// Its message has no position, the position is kept in Pos,
+// so errors can be wrapped without duplicating positions.
+type RuntimeError struct {
+	Kind  ErrorKind
+	Pos   ast.PosRange
+	Err   error
+	Stack []StackFrame // innermost call first
+
+	// frames is shared by errors with stacks, which are prefixes of it, see WithFrame
+	frames *[]StackFrame
+}
+
+// StackFrame is a call of a user function.
+type StackFrame struct {
+	Name string       // empty for anonymous functions
+	Pos  ast.PosRange // position of the call
+}
+
+func (err *RuntimeError) Error() string { return err.Err.Error() }
+
+func (err *RuntimeError) Unwrap() error { return err.Err }
+
+// InfoError is an error with a message and a map of data, see ex-info.
+type InfoError struct {
+	Message string
+	Data    Object // *Map or Null
+}
+
+func (err *InfoError) Error() string { return err.Message }
+
+// UndefinedError is an evaluation of an unbound name in the strict mode, see Env.SetStrict.
+type UndefinedError struct {
+	Name        string
+	Suggestions []string // similar names bound in the env
+}
+
+func (err *UndefinedError) Error() string {
+	msg := "undefined symbol " + err.Name
+
+	switch len(err.Suggestions) {
+	case 0:
+		return msg
+	case 1:
+		return msg + ", did you mean " + err.Suggestions[0] + "?"
+	default:
+		last := len(err.Suggestions) - 1
+		return msg + ", did you mean " + strings.Join(err.Suggestions[:last], ", ") + " or " + err.Suggestions[last] + "?"
+	}
+}
+
+// ThrownError is a value raised by throw, which isn't an exception.
+type ThrownError struct {
+	Value Object
+}
+
+func (err *ThrownError) Error() string { return "uncaught " + err.Value.Inspect() }
+
+// Exception is an error as a value: it's bound by catch clauses and created by ex-info.
+// Unlike Error, it doesn't stop evaluation, throw raises it again.
+type Exception struct {
+	Err *RuntimeError
+}
+
+func (*Exception) Kind() Kind { return ObjException }
+
+func (exc *Exception) Inspect() string {
+	str := &strings.Builder{}
+
+	fmt.Fprintf(str, "(exception :%s %q", exc.Err.Kind, exc.Err.Error())
+
+	var info *InfoError
+	if errors.As(exc.Err, &info) {
+		fmt.Fprintf(str, " %s", info.Data.Inspect())
+	}
+
+	str.WriteString(")")
+
+	return str.String()
+}
+
+// Errorf creates a runtime error at the position.
+// If an argument is a runtime error, the new error keeps its position and stack
+// and extends its message, so the innermost position is reported.
+// The kind is taken from wrapped errors, see WithKind.
+func Errorf(pos ast.PosRange, format string, args ...any) *Error {
+	err := fmt.Errorf(format, args...)
+
+	runtime := &RuntimeError{
+		Kind: KindOf(err),
+		Pos:  pos,
+		Err:  err,
+	}
+
+	var inner *RuntimeError
+	if errors.As(err, &inner) {
+		runtime.Pos = inner.Pos
+		runtime.Stack = inner.Stack
+		runtime.frames = inner.frames
+	}
+
+	return &Error{Err: runtime}
+}
+
+type kindError struct {
+	kind ErrorKind
+	err  error
+}
+
+func (err *kindError) Error() string { return err.err.Error() }
+
+func (err *kindError) Unwrap() error { return err.err }
+
+// WithKind marks the error with a kind, which is used by runtime errors wrapping it.
+func WithKind(kind ErrorKind, err error) error {
+	return &kindError{kind: kind, err: err}
+}
+
+// KindOf returns the kind of the innermost marked error in the chain.
+func KindOf(err error) ErrorKind {
+	var runtime *RuntimeError
+	if errors.As(err, &runtime) {
+		return runtime.Kind
+	}
+
+	kind := ErrGeneric
+	for err != nil {
+		if marked, ok := err.(*kindError); ok {
+			kind = marked.kind
+		}
+		err = errors.Unwrap(err)
+	}
+
+	return kind
+}
+
+// WithFrame returns the error with a user function call added to its stack.
+// Errors without positions are returned as is.
+func (err *Error) WithFrame(name string, pos ast.PosRange) *Error {
+	var runtime *RuntimeError
+	if !errors.As(err.Err, &runtime) {
+		return err
+	}
+
+	clone := *runtime
+	frame := StackFrame{Name: name, Pos: pos}
+
+	// errors are unwound frame by frame, so the frame is appended in place
+	// while no other error has extended the shared frames
+	if clone.frames != nil && len(*clone.frames) == len(clone.Stack) {
+		*clone.frames = append(*clone.frames, frame)
+	} else {
+		frames := append(clone.Stack[:len(clone.Stack):len(clone.Stack)], frame)
+		clone.frames = &frames
+	}
+	clone.Stack = *clone.frames
+
+	return &Error{Err: &clone}
+}
+
+// maxPrettyFrames limits the printed call stack, deep recursion can produce thousands of frames.
+const maxPrettyFrames = 16
+
+// Pretty formats the error with the source line and a caret under the error position,
+// followed by the call stack:
+//
+//	script.sl:2:3: arithmetic-error: /: division by zero
+//	  (/ x 0)
+//	  ^^^^^^^
+//	  in divide called at script.sl:4:1
+//
+// The source is a content of the file of the error position, it can be nil.
+func (err *RuntimeError) Pretty(source []byte) string {
+	str := &strings.Builder{}
+	pos := err.Pos
+
+	fmt.Fprintf(str, "%s: %s: %s\n", pos.From, err.Kind, err.Err)
+
+	if text, ok := sourceLine(source, pos.From.Line); ok {
+		// columns are counted in runes
+		line := []rune(text)
+		from := min(pos.From.Column, len(line))
+
+		to := len(line)
+		if pos.To.Line == pos.From.Line && pos.To.Column > from {
+			to = min(pos.To.Column, len(line))
+		}
+
+		// ranges of atoms end at the next token
+		for to > from+1 && unicode.IsSpace(line[to-1]) {
+			to--
+		}
+
+		// tabs are kept, so the caret is aligned with the source line
+		indent := strings.Map(func(ru rune) rune {
+			if ru == '\t' {
+				return ru
+			}
+			return ' '
+		}, string(line[:from]))
+
+		fmt.Fprintf(str, "  %s\n", text)
+		fmt.Fprintf(str, "  %s%s\n", indent, strings.Repeat("^", max(to-from, 1)))
+	}
+
+	for i, frame := range err.Stack {
+		if i == maxPrettyFrames {
+			fmt.Fprintf(str, "  ... %d more calls\n", len(err.Stack)-i)
+			break
+		}
+
+		name := frame.Name
+		if name == "" {
+			name = "anonymous function"
+		}
+
+		fmt.Fprintf(str, "  in %s called at %s\n", name, frame.Pos.From)
+	}
+
+	return str.String()
+}
+
+func sourceLine(source []byte, n int) (string, bool) {
+	if source == nil || n < 0 {
+		return "", false
+	}
+
+	lines := strings.Split(string(source), "\n")
+	if n >= len(lines) {
+		return "", false
+	}
+
+	return strings.TrimRight(lines[n], "\r"), true
+}
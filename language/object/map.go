@@ -0,0 +1,184 @@
+package object
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"strings"
+
+	"github.com/ninedraft/sulisp/internal/collections/hamt"
+	"github.com/ninedraft/sulisp/internal/collections/vector"
+)
+
+// Keyword is a name which evaluates to itself, like :key.
+// The value includes the leading colon.
+type Keyword string
+
+func (Keyword) Kind() Kind { return ObjKeyword }
+
+func (kw Keyword) Inspect() string { return string(kw) }
+
+// Map is a hash map of objects. Keys must be hashable:
+// primitives, keywords and null. Entries keep the insertion order.
+// Entries are stored in persistent collections, so clones share them
+// and changes of a clone copy only changed paths.
+type Map struct {
+	index   hamt.Map[hashKey, int]  // positions of entries by keys
+	entries vector.Vector[MapEntry] // in the insertion order, deleted entries leave holes
+	holes   int                     // deleted entries, they have nil keys
+}
+
+type MapEntry struct {
+	Key, Value Object
+}
+
+func NewMap() *Map {
+	return &Map{}
+}
+
+func (*Map) Kind() Kind { return ObjMap }
+
+func (m *Map) Inspect() string {
+	str := &strings.Builder{}
+	str.WriteRune('{')
+
+	first := true
+	for key, value := range m.All() {
+		if !first {
+			str.WriteString(", ")
+		}
+		first = false
+
+		str.WriteString(key.Inspect())
+		str.WriteRune(' ')
+		str.WriteString(value.Inspect())
+	}
+
+	str.WriteRune('}')
+	return str.String()
+}
+
+func (m *Map) Len() int {
+	return m.entries.Len() - m.holes
+}
+
+// Get returns a value by the key.
+func (m *Map) Get(key Object) (Object, bool) {
+	hash, ok := hashOf(key)
+	if !ok {
+		return nil, false
+	}
+
+	i, ok := m.index.Get(hash)
+	if !ok {
+		return nil, false
+	}
+
+	entry, _ := m.entries.At(i)
+	return entry.Value, true
+}
+
+// Set adds or replaces a value by the key.
+func (m *Map) Set(key, value Object) error {
+	hash, ok := hashOf(key)
+	if !ok {
+		return fmt.Errorf("%s %q can't be used as a map key", key.Kind(), key.Inspect())
+	}
+
+	if i, ok := m.index.Get(hash); ok {
+		entry, _ := m.entries.At(i)
+		entry.Value = value
+		m.entries = m.entries.Set(i, entry)
+		return nil
+	}
+
+	m.index = m.index.Set(hash, m.entries.Len())
+	m.entries = m.entries.Append(MapEntry{Key: key, Value: value})
+
+	return nil
+}
+
+// Delete removes the key from the map.
+func (m *Map) Delete(key Object) {
+	hash, ok := hashOf(key)
+	if !ok {
+		return
+	}
+
+	i, ok := m.index.Get(hash)
+	if !ok {
+		return
+	}
+
+	m.index = m.index.Delete(hash)
+	m.entries = m.entries.Set(i, MapEntry{})
+	m.holes++
+
+	// holes are dropped, when they are the most of entries,
+	// so each deletion copies a constant number of entries on average
+	if m.holes > m.entries.Len()/2 {
+		m.compact()
+	}
+}
+
+// compact rebuilds entries without holes.
+func (m *Map) compact() {
+	live := *m
+	*m = Map{}
+
+	for key, value := range live.All() {
+		hash, _ := hashOf(key)
+		m.index = m.index.Set(hash, m.entries.Len())
+		m.entries = m.entries.Append(MapEntry{Key: key, Value: value})
+	}
+}
+
+// All iterates over entries in the insertion order.
+func (m *Map) All() iter.Seq2[Object, Object] {
+	return func(yield func(Object, Object) bool) {
+		for _, entry := range m.entries.All() {
+			if entry.Key == nil {
+				continue
+			}
+			if !yield(entry.Key, entry.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Clone returns a copy of the map, which can be changed independently.
+// The copy shares entries with the map until one of them is changed.
+func (m *Map) Clone() *Map {
+	clone := *m
+	return &clone
+}
+
+type hashKey struct {
+	kind  Kind
+	value any
+}
+
+// hashOf returns a comparable key for the object.
+// Integral floats are hashed as integers, because they are equal to them.
+func hashOf(obj Object) (hashKey, bool) {
+	switch obj := obj.(type) {
+	case *Primitive[int64]:
+		return hashKey{ObjInteger, obj.Value}, true
+	case *Primitive[float64]:
+		if obj.Value == math.Trunc(obj.Value) && math.Abs(obj.Value) < math.MaxInt64 {
+			return hashKey{ObjInteger, int64(obj.Value)}, true
+		}
+		return hashKey{ObjFloat64, obj.Value}, true
+	case *Primitive[string]:
+		return hashKey{ObjString, obj.Value}, true
+	case *Primitive[bool]:
+		return hashKey{ObjBool, obj.Value}, true
+	case Keyword:
+		return hashKey{ObjKeyword, string(obj)}, true
+	case Null:
+		return hashKey{kind: ObjNull}, true
+	default:
+		return hashKey{}, false
+	}
+}
@@ -2,13 +2,17 @@ package object
 
 import (
 	"cmp"
+	"errors"
 	"fmt"
-	"io"
+	"iter"
 	"maps"
+	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/ninedraft/itermore"
+	"github.com/ninedraft/sulisp/internal/collections/vector"
 	"github.com/ninedraft/sulisp/language/ast"
 )
 
@@ -30,6 +34,16 @@ const (
 	ObjArray     Kind = "array"
 	ObjAST       Kind = "ast"
 	ObjNamespace Kind = "namespace"
+	ObjMacro     Kind = "macro"
+	ObjKeyword   Kind = "keyword"
+	ObjMap       Kind = "map"
+	ObjSet       Kind = "set"
+	ObjGoValue   Kind = "go-value"
+	ObjException Kind = "exception"
+
+	// ObjUnion is a kind of union types, there are no values of it:
+	// values of (or integer null) are integers or null.
+	ObjUnion Kind = "or"
 )
 
 var Kinds = []Kind{
@@ -48,6 +62,12 @@ var Kinds = []Kind{
 	ObjArray,
 	ObjAST,
 	ObjNamespace,
+	ObjMacro,
+	ObjKeyword,
+	ObjMap,
+	ObjSet,
+	ObjGoValue,
+	ObjException,
 }
 
 func (ot Kind) Kind() Kind { return ObjKind }
@@ -103,10 +123,10 @@ func (primitive *Primitive[E]) Compare(other Object) (_ int, ok bool) {
 	case string:
 		return cmp.Compare(v, any(o.Value).(string)), true
 	case bool:
-		if v && primitive.Value != o.Value {
+		if v {
 			return 1, true
 		}
-		return -1, false
+		return -1, true
 	}
 
 	return 0, false
@@ -116,6 +136,17 @@ func (primitive *Primitive[E]) Inspect() string {
 	return fmt.Sprint(primitive.Value)
 }
 
+// Unquote returns a raw value of a string literal.
+// Strings keep their literals, so "a" is stored with quotes.
+// Strings, which aren't quoted, are returned as is.
+func Unquote(str string) string {
+	unquoted, err := strconv.Unquote(str)
+	if err != nil {
+		return str
+	}
+	return unquoted
+}
+
 func (primitive *Primitive[E]) Kind() Kind {
 	v := any(Primitive[E]{}.Value)
 	switch v.(type) {
@@ -132,9 +163,19 @@ func (primitive *Primitive[E]) Kind() Kind {
 	}
 }
 
+// Type describes values of a kind.
+// Params are element types of collections or parameter types of functions.
 type Type struct {
 	ObjKind Kind
 	Params  []Type
+
+	// Return is a result type of a function, it's nil for other types.
+	Return *Type
+	// Variadic functions collect the remaining arguments into the last parameter, which is an array.
+	Variadic bool
+	// Var names a type variable, which stands for a type unknown until inference resolves it.
+	// Variables have the ObjAny kind.
+	Var string
 }
 
 func TypeFor(kind Kind, paramsKinds ...Kind) *Type {
@@ -149,8 +190,138 @@ func TypeFor(kind Kind, paramsKinds ...Kind) *Type {
 	}
 }
 
+// FuncType returns a type of functions, which take params and return ret.
+func FuncType(ret *Type, params ...Type) *Type {
+	return &Type{
+		ObjKind: ObjFunc,
+		Params:  params,
+		Return:  ret,
+	}
+}
+
+// VarType returns a type variable.
+func VarType(name string) *Type {
+	return &Type{
+		ObjKind: ObjAny,
+		Var:     name,
+	}
+}
+
+// UnionType returns a type of values of any of the types.
+// Nested unions are flattened and duplicates are dropped,
+// a union of a single type is the type itself and a union with any is any.
+func UnionType(types ...*Type) *Type {
+	union := &Type{ObjKind: ObjUnion}
+	var add func(t *Type)
+	add = func(t *Type) {
+		if t.ObjKind == ObjUnion && t.Var == "" {
+			for i := range t.Params {
+				add(&t.Params[i])
+			}
+			return
+		}
+
+		if !slices.ContainsFunc(union.Params, func(p Type) bool { return p.Equal(t) }) {
+			union.Params = append(union.Params, *t)
+		}
+	}
+
+	for _, t := range types {
+		add(t)
+	}
+
+	switch {
+	case slices.ContainsFunc(union.Params, func(p Type) bool { return isAny(&p) }):
+		return TypeFor(ObjAny)
+	case len(union.Params) == 1:
+		return &union.Params[0]
+	default:
+		return union
+	}
+}
+
+// NullableType returns a type of values of the type or null: (or integer null).
+func NullableType(t *Type) *Type {
+	return UnionType(t, TypeFor(ObjNull))
+}
+
+// IsNullable reports if null is a value of the type.
+func (ot *Type) IsNullable() bool {
+	return TypeFor(ObjNull).IsSubtype(ot)
+}
+
+// IsSubtype reports if all values of the type are values of the other type.
+// Any is compatible with all types both ways, as its values are checked at runtime,
+// and types without parameters are compatible with parametrized types of their kinds.
+// Functions take parameters of subtypes of their parameter types.
+func (ot *Type) IsSubtype(other *Type) bool {
+	switch {
+	case isAny(ot) || isAny(other):
+		return true
+	case ot.Var != "" || other.Var != "":
+		return ot.Var == other.Var
+	case ot.ObjKind == ObjUnion:
+		for i := range ot.Params {
+			if !ot.Params[i].IsSubtype(other) {
+				return false
+			}
+		}
+		return true
+	case other.ObjKind == ObjUnion:
+		for i := range other.Params {
+			if ot.IsSubtype(&other.Params[i]) {
+				return true
+			}
+		}
+		return false
+	case ot.ObjKind != other.ObjKind:
+		return false
+	case ot.IsFunc() && other.IsFunc():
+		if ot.Variadic != other.Variadic || len(ot.Params) != len(other.Params) {
+			return false
+		}
+
+		for i := range ot.Params {
+			if !other.Params[i].IsSubtype(&ot.Params[i]) {
+				return false
+			}
+		}
+
+		return ot.Return.IsSubtype(other.Return)
+	case len(ot.Params) == len(other.Params):
+		for i := range ot.Params {
+			if !ot.Params[i].IsSubtype(&other.Params[i]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func isAny(t *Type) bool {
+	return t.ObjKind == ObjAny && t.Var == ""
+}
+
+// IsFunc reports if the type describes parameters and result of a function.
+func (ot *Type) IsFunc() bool {
+	return ot.Return != nil
+}
+
+// Equal reports if types are the same, unions are equal regardless of the order of their types.
 func (ot *Type) Equal(other *Type) bool {
+	if ot.ObjKind == ObjUnion && other.ObjKind == ObjUnion {
+		return len(ot.Params) == len(other.Params) &&
+			!slices.ContainsFunc(ot.Params, func(t Type) bool {
+				return !slices.ContainsFunc(other.Params, func(o Type) bool { return o.Equal(&t) })
+			})
+	}
+
 	return ot.ObjKind == other.ObjKind &&
+		ot.Var == other.Var &&
+		ot.Variadic == other.Variadic &&
+		(ot.Return == nil) == (other.Return == nil) &&
+		(ot.Return == nil || ot.Return.Equal(other.Return)) &&
 		slices.EqualFunc(ot.Params, other.Params, func(a, b Type) bool {
 			return a.Equal(&b)
 		})
@@ -160,18 +331,53 @@ func (ot *Type) Kind() Kind {
 	return ObjType
 }
 
+// Inspect returns a type form: (type integer), (type (array string)), (type (or integer null))
+// or (type (fn [a & integer] a)).
 func (ot *Type) Inspect() string {
-	str := &strings.Builder{}
-	str.WriteString("(type ")
-	str.WriteString(ot.ObjKind.Inspect())
+	return "(type " + ot.String() + ")"
+}
+
+// String returns a type without the type form: integer, (array string) or (fn [a & integer] a).
+// Variadic functions show the element type of the last parameter.
+func (ot *Type) String() string {
+	switch {
+	case ot.Var != "":
+		return ot.Var
+	case ot.IsFunc():
+		params := make([]string, 0, len(ot.Params)+1)
+		for i, param := range ot.Params {
+			if ot.Variadic && i == len(ot.Params)-1 {
+				params = append(params, "&", param.Elem().String())
+				continue
+			}
+			params = append(params, param.String())
+		}
+
+		return "(fn [" + strings.Join(params, " ") + "] " + ot.Return.String() + ")"
+	case len(ot.Params) > 0:
+		str := &strings.Builder{}
+		str.WriteString("(")
+		str.WriteString(ot.ObjKind.Inspect())
+
+		for _, param := range ot.Params {
+			str.WriteString(" ")
+			str.WriteString(param.String())
+		}
 
-	for _, param := range ot.Params {
-		str.WriteString(" ")
-		str.WriteString(param.Inspect())
+		str.WriteString(")")
+		return str.String()
+	default:
+		return ot.ObjKind.Inspect()
 	}
+}
 
-	str.WriteString(")")
-	return str.String()
+// Elem returns an element type of an array or any for other types.
+func (ot *Type) Elem() *Type {
+	if ot.ObjKind == ObjArray && len(ot.Params) > 0 {
+		return &ot.Params[0]
+	}
+
+	return TypeFor(ObjAny)
 }
 
 type Namespace struct {
@@ -215,6 +421,8 @@ func (Null) Inspect() string {
 	return "null"
 }
 
+// Error is a result of a failed evaluation.
+// Runtime errors are usually *RuntimeError, which carry a position and a call stack.
 type Error struct {
 	Err error
 }
@@ -224,9 +432,15 @@ func (err *Error) Kind() Kind {
 }
 
 func (err *Error) Inspect() string {
+	var runtime *RuntimeError
+	if errors.As(err.Err, &runtime) {
+		return fmt.Sprintf("!!! %s: %s", runtime.Pos.From, err.Err)
+	}
+
 	return "!!! " + err.Err.Error()
 }
 
+// Return carries a value of a return form through enclosing forms up to the call of the function.
 type Return struct {
 	Value Object
 }
@@ -238,11 +452,17 @@ func (ret *Return) Inspect() string {
 }
 
 type Function struct {
+	Name       string // optional
 	Parameters []*ast.Symbol
-	Body       *ast.SExp
+	Rest       *ast.Symbol // optional, collects variadic arguments
+	Body       []ast.Node
 	Env        *Env
 }
 
+func (fn *Function) Definition() (*ast.Fn, *Env) {
+	return &ast.Fn{Params: fn.Parameters, Rest: fn.Rest, Body: fn.Body}, fn.Env
+}
+
 func (fn *Function) Kind() Kind {
 	return ObjFunc
 }
@@ -250,20 +470,45 @@ func (fn *Function) Kind() Kind {
 func (fn *Function) Inspect() string {
 	str := &strings.Builder{}
 
-	params := make([]string, 0, len(fn.Parameters))
+	params := make([]string, 0, len(fn.Parameters)+1)
 	for _, param := range fn.Parameters {
 		params = append(params, param.Value)
 	}
 
-	str.WriteString("fn(")
+	if fn.Rest != nil {
+		params = append(params, "& "+fn.Rest.Value)
+	}
+
+	str.WriteString("fn")
+	if fn.Name != "" {
+		str.WriteString(" " + fn.Name)
+	}
+	str.WriteString("(")
 	str.WriteString(strings.Join(params, ", "))
-	str.WriteString(") {\n")
-	str.WriteString(fn.Body.String())
+	str.WriteString(") {")
+
+	for _, item := range fn.Body {
+		str.WriteString("\n\t")
+		str.WriteString(item.String())
+	}
+
 	str.WriteString("\n}")
 
 	return str.String()
 }
 
+// Macro is a function, which is called at expansion time
+// with forms as arguments and returns a form.
+type Macro struct {
+	Fn *Function
+}
+
+func (*Macro) Kind() Kind { return ObjMacro }
+
+func (macro *Macro) Inspect() string {
+	return "macro " + macro.Fn.Inspect()
+}
+
 type Eval = func(node ast.Node, env *Env) Object
 
 type BuiltinFn func(args *ast.SExp, env *Env, eval Eval) Object
@@ -271,6 +516,8 @@ type BuiltinFn func(args *ast.SExp, env *Env, eval Eval) Object
 type Builtin struct {
 	Name string
 	Fn   BuiltinFn
+	// Type is a function type or a result type of builtins, which take any arguments.
+	// Type variables of function types are instantiated on every use.
 	Type *Type
 }
 
@@ -280,8 +527,29 @@ func (builtin *Builtin) Inspect() string {
 	return fmt.Sprintf("<builtin %s>", builtin.Name)
 }
 
+// Defined is a function, which keeps its definition, so its type can be inferred.
+// Env resolves names used by the body.
+type Defined interface {
+	Object
+	Definition() (*ast.Fn, *Env)
+}
+
+// Callable is a function implemented outside of the tree-walking interpreter,
+// like a closure compiled to bytecode. It's called with evaluated arguments.
+type Callable interface {
+	Object
+	Call(pos ast.PosRange, args []Object) Object
+}
+
+// Array is a sequence of objects. Elements are stored in a persistent vector,
+// so clones share them and changes of a clone copy only changed paths.
+// The zero value is an empty array.
 type Array struct {
-	Elements []Object
+	elements vector.Vector[Object]
+}
+
+func NewArray(elements ...Object) *Array {
+	return &Array{elements: vector.Of(elements...)}
 }
 
 func (array *Array) Kind() Kind {
@@ -292,12 +560,62 @@ func (array *Array) Inspect() string {
 	str := &strings.Builder{}
 	str.WriteRune('[')
 
-	joinObjects(str, ", ", array.Elements)
+	for i, element := range array.All() {
+		if i > 0 {
+			str.WriteString(", ")
+		}
+		str.WriteString(element.Inspect())
+	}
 
 	str.WriteRune(']')
 	return str.String()
 }
 
+func (array *Array) Len() int {
+	return array.elements.Len()
+}
+
+// At returns an element by the index.
+func (array *Array) At(i int) (Object, bool) {
+	return array.elements.At(i)
+}
+
+// Append adds the values to the end of the array.
+func (array *Array) Append(values ...Object) {
+	array.elements = array.elements.Append(values...)
+}
+
+// Set replaces an element by the index. Indexes out of range are reported.
+func (array *Array) Set(i int, value Object) bool {
+	if i < 0 || i >= array.Len() {
+		return false
+	}
+
+	array.elements = array.elements.Set(i, value)
+	return true
+}
+
+// All iterates over indexes and elements in order.
+func (array *Array) All() iter.Seq2[int, Object] {
+	return array.elements.All()
+}
+
+// Values returns a copy of the elements.
+func (array *Array) Values() []Object {
+	values := make([]Object, 0, array.Len())
+	for _, element := range array.All() {
+		values = append(values, element)
+	}
+	return values
+}
+
+// Clone returns a copy of the array, which can be changed independently.
+// The copy shares elements with the array until one of them is changed.
+func (array *Array) Clone() *Array {
+	clone := *array
+	return &clone
+}
+
 type AST struct {
 	Node ast.Node
 }
@@ -308,15 +626,18 @@ func (a *AST) Inspect() string {
 	return a.Node.String()
 }
 
-func joinObjects[O Object](dst io.StringWriter, sep string, strs []O) {
-	if len(strs) == 0 {
-		return
-	}
+// GoValue is a Go value, which has no natural representation as an object,
+// like a struct or a pointer. Its methods can be called with (.Method value args...).
+type GoValue struct {
+	Value reflect.Value
+}
 
-	_, _ = dst.WriteString(strs[0].Inspect())
+func (*GoValue) Kind() Kind { return ObjGoValue }
 
-	for _, str := range strs[1:] {
-		_, _ = dst.WriteString(sep)
-		_, _ = dst.WriteString(str.Inspect())
+func (value *GoValue) Inspect() string {
+	if !value.Value.IsValid() {
+		return "<go invalid>"
 	}
+
+	return fmt.Sprintf("<go %s %v>", value.Value.Type(), value.Value)
 }
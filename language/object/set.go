@@ -0,0 +1,84 @@
+package object
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// Set is a hash set of objects. Elements must be hashable, like map keys,
+// and keep the insertion order. Elements are stored in a persistent map,
+// so clones share them and changes of a clone copy only changed paths.
+type Set struct {
+	elements Map
+}
+
+func NewSet() *Set {
+	return &Set{}
+}
+
+func (*Set) Kind() Kind { return ObjSet }
+
+func (set *Set) Inspect() string {
+	str := &strings.Builder{}
+	str.WriteString("#{")
+
+	first := true
+	for element := range set.All() {
+		if !first {
+			str.WriteString(", ")
+		}
+		first = false
+
+		str.WriteString(element.Inspect())
+	}
+
+	str.WriteRune('}')
+	return str.String()
+}
+
+func (set *Set) Len() int {
+	return set.elements.Len()
+}
+
+// Contains reports whether the set contains the element.
+func (set *Set) Contains(element Object) bool {
+	_, ok := set.elements.Get(element)
+	return ok
+}
+
+// Add adds the element to the set.
+func (set *Set) Add(element Object) error {
+	if set.Contains(element) {
+		return nil
+	}
+
+	if err := set.elements.Set(element, element); err != nil {
+		return fmt.Errorf("%s %q can't be used as a set element", element.Kind(), element.Inspect())
+	}
+
+	return nil
+}
+
+// Delete removes the element from the set.
+func (set *Set) Delete(element Object) {
+	set.elements.Delete(element)
+}
+
+// All iterates over elements in the insertion order.
+func (set *Set) All() iter.Seq[Object] {
+	return func(yield func(Object) bool) {
+		for element := range set.elements.All() {
+			if !yield(element) {
+				return
+			}
+		}
+	}
+}
+
+// Clone returns a copy of the set, which can be changed independently.
+// The copy shares elements with the set until one of them is changed.
+func (set *Set) Clone() *Set {
+	clone := *set
+	return &clone
+}
@@ -16,15 +16,18 @@ func _() {
 	_ = x[TokenLBrace-123]
 	_ = x[TokenRBrace-125]
 	_ = x[TokenQuote-39]
+	_ = x[TokenQuasiquote-96]
+	_ = x[TokenUnquote-126]
 	_ = x[TokenPoint-46]
-	_ = x[TokenSymbol-109]
-	_ = x[TokenKeyword-110]
-	_ = x[TokenInt-111]
-	_ = x[TokenFloat-112]
-	_ = x[TokenStr-113]
-	_ = x[TokenComment-114]
-	_ = x[TokenEOF-115]
-	_ = x[TokenMalformed-116]
+	_ = x[TokenSymbol-111]
+	_ = x[TokenKeyword-112]
+	_ = x[TokenInt-113]
+	_ = x[TokenFloat-114]
+	_ = x[TokenStr-115]
+	_ = x[TokenComment-116]
+	_ = x[TokenEOF-117]
+	_ = x[TokenMalformed-118]
+	_ = x[TokenUnquoteSplice-119]
 }
 
 const (
@@ -33,14 +36,16 @@ const (
 	_TokenKind_name_2 = "."
 	_TokenKind_name_3 = "["
 	_TokenKind_name_4 = "]"
-	_TokenKind_name_5 = "symbol:keywordintegerfloatstring; comment<eof>malformed"
-	_TokenKind_name_6 = "{"
-	_TokenKind_name_7 = "}"
+	_TokenKind_name_5 = "`"
+	_TokenKind_name_6 = "symbol:keywordintegerfloatstring; comment<eof>malformed~@"
+	_TokenKind_name_7 = "{"
+	_TokenKind_name_8 = "}~"
 )
 
 var (
 	_TokenKind_index_1 = [...]uint8{0, 1, 2, 3}
-	_TokenKind_index_5 = [...]uint8{0, 6, 14, 21, 26, 32, 41, 46, 55}
+	_TokenKind_index_6 = [...]uint8{0, 6, 14, 21, 26, 32, 41, 46, 55, 57}
+	_TokenKind_index_8 = [...]uint8{0, 1, 2}
 )
 
 func (i TokenKind) String() string {
@@ -56,13 +61,16 @@ func (i TokenKind) String() string {
 		return _TokenKind_name_3
 	case i == 93:
 		return _TokenKind_name_4
-	case 109 <= i && i <= 116:
-		i -= 109
-		return _TokenKind_name_5[_TokenKind_index_5[i]:_TokenKind_index_5[i+1]]
+	case i == 96:
+		return _TokenKind_name_5
+	case 111 <= i && i <= 119:
+		i -= 111
+		return _TokenKind_name_6[_TokenKind_index_6[i]:_TokenKind_index_6[i+1]]
 	case i == 123:
-		return _TokenKind_name_6
-	case i == 125:
 		return _TokenKind_name_7
+	case 125 <= i && i <= 126:
+		i -= 125
+		return _TokenKind_name_8[_TokenKind_index_8[i]:_TokenKind_index_8[i+1]]
 	default:
 		return "TokenKind(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
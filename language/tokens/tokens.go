@@ -10,8 +10,10 @@ type Position struct {
 	Column int
 }
 
+// String formats the position as file:line:column.
+// Lines and columns are counted from 0, but printed from 1, like editors show them.
 func (pos Position) String() string {
-	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Column)
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line+1, pos.Column+1)
 }
 
 type Token struct {
@@ -46,7 +48,9 @@ const (
 	TokenLBrace = TokenKind('{') // {
 	TokenRBrace = TokenKind('}') // }
 
-	TokenQuote = TokenKind('\'') // '
+	TokenQuote      = TokenKind('\'') // '
+	TokenQuasiquote = TokenKind('`')  // `
+	TokenUnquote    = TokenKind('~')  // ~
 
 	TokenPoint = TokenKind('.') // .
 
@@ -60,6 +64,8 @@ const (
 
 	TokenEOF       // <eof>
 	TokenMalformed // malformed
+
+	TokenUnquoteSplice // ~@
 )
 
 func (tk TokenKind) GoString() string {
@@ -78,6 +84,12 @@ func (tk TokenKind) GoString() string {
 		return "language.TokenRCurl"
 	case TokenQuote:
 		return "language.TokenQuote"
+	case TokenQuasiquote:
+		return "language.TokenQuasiquote"
+	case TokenUnquote:
+		return "language.TokenUnquote"
+	case TokenUnquoteSplice:
+		return "language.TokenUnquoteSplice"
 	case TokenSymbol:
 		return "language.TokenSymbol"
 	case TokenKeyword:
@@ -40,7 +40,7 @@ func (lexer *Lexer) Next() (*language.Token, error) {
 
 const brackets = "[](){}"
 
-func (lexer *Lexer) next() (scanned *language.Token, _ error) {
+func (lexer *Lexer) next() (*language.Token, error) {
 	ru := lexer.scanner.Current()
 
 	// skipping spaces and commas
@@ -48,6 +48,19 @@ func (lexer *Lexer) next() (scanned *language.Token, _ error) {
 		ru = lexer.scanner.Scan()
 	}
 
+	// multi rune tokens are created after reading,
+	// so they must be moved to the first rune
+	start := lexer.pos()
+
+	tok, err := lexer.readToken(ru)
+	if tok != nil {
+		tok.Pos = start
+	}
+
+	return tok, err
+}
+
+func (lexer *Lexer) readToken(ru rune) (*language.Token, error) {
 	switch {
 	case ru == eof:
 		tok := lexer.newToken(language.TokenEOF, "")
@@ -63,6 +76,19 @@ func (lexer *Lexer) next() (scanned *language.Token, _ error) {
 		tok := lexer.newToken(language.TokenQuote, "'")
 		lexer.scanner.Scan()
 		return tok, nil
+	case ru == '`':
+		tok := lexer.newToken(language.TokenQuasiquote, "`")
+		lexer.scanner.Scan()
+		return tok, nil
+	case ru == '~' && lexer.scanner.Peek() == '@':
+		tok := lexer.newToken(language.TokenUnquoteSplice, "~@")
+		lexer.scanner.Scan()
+		lexer.scanner.Scan()
+		return tok, nil
+	case ru == '~':
+		tok := lexer.newToken(language.TokenUnquote, "~")
+		lexer.scanner.Scan()
+		return tok, nil
 	case containsRune(brackets, ru):
 		kind := language.TokenKind(ru)
 		tok := lexer.newToken(kind, kind.String())
@@ -218,11 +244,7 @@ func (lexer *Lexer) readAtom(kind language.TokenKind) (*language.Token, error) {
 
 	for {
 		ru := sc.Scan()
-		if ru == eof {
-			return nil, sc.Err()
-		}
-
-		if !isAtomRune(ru) {
+		if ru == eof || !isAtomRune(ru) {
 			break
 		}
 
@@ -233,7 +255,7 @@ func (lexer *Lexer) readAtom(kind language.TokenKind) (*language.Token, error) {
 }
 
 func isAtomRune(ru rune) bool {
-	if unicode.IsSpace(ru) || containsRune(brackets, ru) || ru == '.' || ru == ',' {
+	if unicode.IsSpace(ru) || containsRune(brackets, ru) || containsRune(".,`~", ru) {
 		return false
 	}
 
@@ -168,6 +168,26 @@ func TestLex_Symbol(t *testing.T) {
 	}
 }
 
+func TestLex_AtomAtEOF(t *testing.T) {
+	t.Parallel()
+
+	tokens := readTokens(t, "a :b")
+
+	want := []language.Token{
+		{Kind: language.TokenSymbol, Value: `a`},
+		{Kind: language.TokenKeyword, Value: `:b`},
+	}
+
+	require.Len(t, tokens, len(want), "len(tokens)==len(want)")
+
+	for i, expect := range want {
+		got := tokens[i]
+
+		assert.EqualValues(t, expect.Kind, got.Kind, "[%d] %s token kind", i, got.Pos)
+		assert.EqualValues(t, expect.Value, got.Value, "[%d] %s token value", i, got.Pos)
+	}
+}
+
 func TestLex_SExp(t *testing.T) {
 	t.Parallel()
 
@@ -189,6 +209,35 @@ func TestLex_SExp(t *testing.T) {
 	}
 }
 
+func TestLex_Quotes(t *testing.T) {
+	t.Parallel()
+
+	tokens := readTokens(t, `'a `+"`"+`(b ~c ~@d)
+	`)
+
+	want := []language.Token{
+		{Kind: language.TokenQuote, Value: `'`},
+		{Kind: language.TokenSymbol, Value: `a`},
+		{Kind: language.TokenQuasiquote, Value: "`"},
+		{Kind: language.TokenLParen, Value: `(`},
+		{Kind: language.TokenSymbol, Value: `b`},
+		{Kind: language.TokenUnquote, Value: `~`},
+		{Kind: language.TokenSymbol, Value: `c`},
+		{Kind: language.TokenUnquoteSplice, Value: `~@`},
+		{Kind: language.TokenSymbol, Value: `d`},
+		{Kind: language.TokenRParen, Value: `)`},
+	}
+
+	require.Len(t, tokens, len(want), "len(tokens)==len(want)")
+
+	for i, expect := range want {
+		got := tokens[i]
+
+		assert.EqualValues(t, expect.Kind, got.Kind, "[%d] %s token kind", i, got.Pos)
+		assert.EqualValues(t, expect.Value, got.Value, "[%d] %s token value", i, got.Pos)
+	}
+}
+
 func readTokens(t *testing.T, input string) []*language.Token {
 	lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
 
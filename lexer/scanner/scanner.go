@@ -42,9 +42,10 @@ func (sc *Scanner) Scan() rune {
 		return sc.next
 	}
 
+	prev := sc.current
 	sc.current = sc.next
 
-	sc.updatePos(sc.current)
+	sc.updatePos(prev)
 
 	next, _, err := sc.re.ReadRune()
 
@@ -59,9 +60,11 @@ func (sc *Scanner) Scan() rune {
 	return sc.current
 }
 
-func (sc *Scanner) updatePos(ru rune) {
+// updatePos moves the position past the previous rune,
+// so the newline rune stays at the end of its line and columns start from 0.
+func (sc *Scanner) updatePos(prev rune) {
 	sc.column++
-	if ru == '\n' {
+	if prev == '\n' {
 		sc.line++
 		sc.column = 0
 	}
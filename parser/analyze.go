@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"errors"
+
+	"github.com/ninedraft/sulisp/language/ast"
+)
+
+// Analyze turns s-expressions with special heads into special forms.
+// It's used for code built at runtime: quoted forms and results of macro expansion.
+// Quoted forms are data and are kept as is.
+func Analyze(node ast.Node) (ast.Node, error) {
+	parser := &Parser{}
+
+	analyzed := parser.analyze(node)
+
+	return analyzed, errors.Join(parser.errs...)
+}
+
+func (parser *Parser) analyze(node ast.Node) ast.Node {
+	sexp, isSExp := node.(*ast.SExp)
+	if !isSExp {
+		return ast.MapChildren(node, parser.analyze)
+	}
+
+	sexp = ast.MapChildren(sexp, parser.analyze).(*ast.SExp)
+
+	if len(sexp.Items) == 0 {
+		return sexp
+	}
+
+	head, ok := sexp.Items[0].(*ast.Symbol)
+	switch {
+	case ok && isSpecial[head.Value]:
+		return parser.buildSpecial(sexp)
+	case ok && head.Value == "assign":
+		return parser.annotateAssign(sexp)
+	default:
+		return sexp
+	}
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ninedraft/sulisp/language/ast"
+)
+
+var errAnnotation = errors.New("malformed type annotation")
+
+// annotationMark separates a bound name and its type: (fn [x :- integer] ...).
+const annotationMark = ":-"
+
+// annotate folds annotations of names into their symbols.
+// Types are kept as written, they are resolved by the type checker.
+func annotate(items []ast.Node) ([]ast.Node, error) {
+	folded := make([]ast.Node, 0, len(items))
+
+	for i := 0; i < len(items); i++ {
+		if kw, ok := items[i].(*ast.Keyword); !ok || kw.Value != annotationMark {
+			folded = append(folded, items[i])
+			continue
+		}
+
+		if len(folded) == 0 || i == len(items)-1 {
+			return nil, fmt.Errorf("%w: %s must be between a name and a type", errAnnotation, annotationMark)
+		}
+
+		symbol, ok := folded[len(folded)-1].(*ast.Symbol)
+		if !ok || symbol.Type != nil || symbol.Value == "&" {
+			last := folded[len(folded)-1]
+			return nil, fmt.Errorf("%w: want a name before %s, got %s %q", errAnnotation, annotationMark, last.Name(), last)
+		}
+
+		annotated := *symbol
+		annotated.Type = items[i+1]
+		folded[len(folded)-1] = &annotated
+		i++
+	}
+
+	return folded, nil
+}
+
+// annotateAssign folds annotations of assigned names: (assign x :- integer 1).
+func (parser *Parser) annotateAssign(sexp *ast.SExp) ast.Node {
+	items, err := annotate(sexp.Items[1:])
+	if err != nil {
+		parser.errorf("invalid assign form: %w", err)
+		return nil
+	}
+
+	annotated := *sexp
+	annotated.Items = append([]ast.Node{sexp.Items[0]}, items...)
+
+	return &annotated
+}
@@ -26,7 +26,8 @@ func (parser *Parser) parseDotSelector(left ast.Node) ast.Node {
 
 	parser.nextTok()
 
-	right := parser.parseNode()
+	// selectors are left associative: a.b.c is (. (. a b) c)
+	right := parser.parseLeft()
 
 	if right == nil {
 		return nil
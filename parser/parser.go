@@ -18,22 +18,32 @@ type Parser struct {
 	errs      []error
 	cur, next *tokens.Token
 
+	// quoted is a depth of quote and quasiquote forms,
+	// quoted s-expressions are kept as data and are not turned into special forms.
+	quoted int
+
+	// isMacro reports if a call head can be a macro, recur and return checks
+	// in arguments of such calls are deferred until expansion
+	isMacro func(name string) bool
+
 	infixes map[tokens.TokenKind]infixOp
 }
 
 func New(lexer Lexer) *Parser {
 	parser := &Parser{
 		lexer: lexer,
+		// macros are defined at runtime, so any call can be a macro call
+		isMacro: func(string) bool { return true },
 	}
 
 	parser.addInfix(tokens.TokenPoint, parser.parseDotSelector)
 
-	cur, errCurrent := parser.lexer.Next()
+	cur, errCurrent := parser.scan()
 	if errCurrent != nil {
 		parser.errs = append(parser.errs, errCurrent)
 	}
 
-	next, errNext := parser.lexer.Next()
+	next, errNext := parser.scan()
 	if errNext != nil {
 		parser.errs = append(parser.errs, errNext)
 	}
@@ -58,6 +68,11 @@ func (parser *Parser) Parse() (*ast.Package, error) {
 
 		pkg.Nodes = append(pkg.Nodes, item)
 
+		if len(parser.errs) == 0 {
+			parser.checkRecur(item, false, nil)
+			parser.checkReturn(item, true, false)
+		}
+
 		parser.nextTok()
 	}
 
@@ -103,10 +118,16 @@ func (parser *Parser) parseLeft() ast.Node {
 	switch parser.cur.Kind {
 	case tokens.TokenLParen:
 		return parser.parseApply()
+	case tokens.TokenLBrack:
+		return parser.parseVector()
+	case tokens.TokenLBrace:
+		return parser.parseMap()
 	case tokens.TokenSymbol, tokens.TokenKeyword, tokens.TokenPoint:
 		return parser.parseAtomBoolOrDot()
 	case tokens.TokenInt, tokens.TokenFloat, tokens.TokenStr: // bool parsed in parseAtomBoolOrDot
 		return parser.parseLiteral()
+	case tokens.TokenQuote, tokens.TokenQuasiquote, tokens.TokenUnquote, tokens.TokenUnquoteSplice:
+		return parser.parseQuote()
 	default:
 		parser.errorf("unexpected token: %s", parser.cur)
 		return nil
@@ -135,6 +156,17 @@ func (parser *Parser) parseAtomBoolOrDot(expect ...tokens.TokenKind) ast.Node {
 		return &ast.Literal[bool]{PosRange: parser.posRange(), Value: b}
 	}
 
+	// method name: (.Len buf)
+	if parser.curIs(tokens.TokenPoint) && parser.nextIs(tokens.TokenSymbol) && adjacent(parser.cur, parser.next) {
+		pos := parser.cur.Pos
+		parser.nextTok()
+
+		return &ast.Symbol{
+			PosRange: ast.PosRange{From: pos, To: parser.posRange().To},
+			Value:    "." + parser.cur.Value,
+		}
+	}
+
 	var node ast.Node
 
 	switch parser.cur.Kind {
@@ -150,6 +182,13 @@ func (parser *Parser) parseAtomBoolOrDot(expect ...tokens.TokenKind) ast.Node {
 	return node
 }
 
+// adjacent reports if there are no spaces between tokens.
+func adjacent(tok, next *tokens.Token) bool {
+	return tok.Pos.File == next.Pos.File &&
+		tok.Pos.Line == next.Pos.Line &&
+		tok.Pos.Column+len(tok.Value) == next.Pos.Column
+}
+
 func (parser *Parser) parseLiteral() ast.Node {
 	if !parser.expectCurrentKind(tokens.TokenInt, tokens.TokenFloat, tokens.TokenStr) {
 		return nil
@@ -183,10 +222,50 @@ func (parser *Parser) parseLiteral() ast.Node {
 	return parsed
 }
 
+func (parser *Parser) parseQuote() ast.Node {
+	if !parser.expectCurrentKind(tokens.TokenQuote, tokens.TokenQuasiquote, tokens.TokenUnquote, tokens.TokenUnquoteSplice) {
+		return nil
+	}
+
+	pos := parser.posRange()
+	kind := parser.cur.Kind
+
+	depth := parser.quoted
+	defer func() { parser.quoted = depth }()
+
+	switch kind {
+	case tokens.TokenQuote, tokens.TokenQuasiquote:
+		parser.quoted++
+	default:
+		// unquoted forms are evaluated, so they are parsed as code
+		parser.quoted = max(0, parser.quoted-1)
+	}
+
+	parser.nextTok()
+
+	node := parser.parseNode()
+	if node == nil {
+		return nil
+	}
+
+	switch kind {
+	case tokens.TokenQuote:
+		return &ast.Quote{PosRange: pos, Node: node}
+	case tokens.TokenQuasiquote:
+		return &ast.Quasiquote{PosRange: pos, Node: node}
+	default:
+		return &ast.Unquote{
+			PosRange: pos,
+			Node:     node,
+			Splice:   kind == tokens.TokenUnquoteSplice,
+		}
+	}
+}
+
 // can return special forms
 func (parser *Parser) parseApply() ast.Node {
 	sexp := parser.parseSexp()
-	if sexp == nil || len(sexp.Items) == 0 {
+	if sexp == nil || len(sexp.Items) == 0 || parser.quoted > 0 {
 		return sexp
 	}
 
@@ -198,11 +277,14 @@ func (parser *Parser) parseApply() ast.Node {
 		return sexp
 	}
 
-	if isSpecial[symbol.Value] {
+	switch {
+	case isSpecial[symbol.Value]:
 		return parser.buildSpecial(sexp)
+	case symbol.Value == "assign":
+		return parser.annotateAssign(sexp)
+	default:
+		return sexp
 	}
-
-	return sexp
 }
 
 func (parser *Parser) parseSexp() *ast.SExp {
@@ -210,15 +292,28 @@ func (parser *Parser) parseSexp() *ast.SExp {
 		return nil
 	}
 
-	parser.nextTok()
-
 	sexp := &ast.SExp{}
+	sexp.From = parser.cur.Pos
+
+	parser.nextTok()
 
 	for !parser.curIs(tokens.TokenRParen, tokens.TokenEOF) {
+		// parameter and binding lists are parsed as data,
+		// so names like "cond" or "-" are not turned into special forms
+		raw := parser.isBindingList(sexp.Items)
+		if raw {
+			parser.quoted++
+		}
+
 		node := parser.parseNode()
 		if node != nil {
 			sexp.Items = append(sexp.Items, node)
 		}
+
+		if raw {
+			parser.quoted--
+		}
+
 		parser.nextTok()
 	}
 
@@ -226,8 +321,71 @@ func (parser *Parser) parseSexp() *ast.SExp {
 		return nil
 	}
 
+	// the range ends right after the closing paren
+	sexp.To = parser.cur.Pos
+	sexp.To.Column++
+
 	return sexp
 }
+
+// [a b c]
+func (parser *Parser) parseVector() ast.Node {
+	pos, items, ok := parser.parseItems(tokens.TokenLBrack, tokens.TokenRBrack)
+	if !ok {
+		return nil
+	}
+
+	return &ast.Vector{PosRange: pos, Items: items}
+}
+
+// {key value key value}
+func (parser *Parser) parseMap() ast.Node {
+	pos, items, ok := parser.parseItems(tokens.TokenLBrace, tokens.TokenRBrace)
+	if !ok {
+		return nil
+	}
+
+	if len(items)%2 != 0 {
+		parser.errs = append(parser.errs, &Error{
+			Pos: pos.From,
+			Err: fmt.Errorf("map literal must have an even number of items, got %d", len(items)),
+		})
+		return nil
+	}
+
+	return &ast.Map{PosRange: pos, Items: items}
+}
+
+// parseItems parses forms between open and close tokens.
+func (parser *Parser) parseItems(open, close tokens.TokenKind) (_ ast.PosRange, items []ast.Node, ok bool) {
+	if !parser.expectCurrentKind(open) {
+		return ast.PosRange{}, nil, false
+	}
+
+	pos := ast.PosRange{From: parser.cur.Pos}
+
+	parser.nextTok()
+
+	for !parser.curIs(close, tokens.TokenEOF) {
+		node := parser.parseNode()
+		if node != nil {
+			items = append(items, node)
+		}
+
+		parser.nextTok()
+	}
+
+	if !parser.expectCurrentKind(close) {
+		return ast.PosRange{}, nil, false
+	}
+
+	// the range ends right after the closing bracket
+	pos.To = parser.cur.Pos
+	pos.To.Column++
+
+	return pos, items, true
+}
+
 func (parser *Parser) curIs(kinds ...tokens.TokenKind) bool {
 	return parser.cur != nil && slices.Contains(kinds, parser.cur.Kind)
 }
@@ -300,7 +458,7 @@ func (parser *Parser) errorf(msg string, args ...any) {
 
 func (parser *Parser) nextTok() {
 	parser.cur = parser.next
-	next, err := parser.lexer.Next()
+	next, err := parser.scan()
 
 	if err != nil {
 		parser.next = &tokens.Token{Kind: tokens.TokenEOF, Pos: parser.posRange().From, Value: err.Error()}
@@ -313,6 +471,16 @@ func (parser *Parser) nextTok() {
 	parser.next = next
 }
 
+// scan reads the next token, skipping comments.
+func (parser *Parser) scan() (*tokens.Token, error) {
+	for {
+		tok, err := parser.lexer.Next()
+		if err != nil || tok.Kind != tokens.TokenComment {
+			return tok, err
+		}
+	}
+}
+
 func (parser *Parser) posRange() ast.PosRange {
 	pos := ast.PosRange{}
 
@@ -27,6 +27,25 @@ func TestParse_Symbol(t *testing.T) {
 	assertEqual(t, &ast.Symbol{Value: "applorange"}, symbol, "parsed symbol")
 }
 
+func TestParse_Comments(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		; leading comment
+		(f ; inner comment
+			x)
+		y ; trailing comment`)
+
+	want := &ast.Package{
+		Nodes: []ast.Node{
+			ast.NewSexp(&ast.Symbol{Value: "f"}, &ast.Symbol{Value: "x"}),
+			&ast.Symbol{Value: "y"},
+		},
+	}
+
+	assertEqual(t, want, pkg, "parsed package")
+}
+
 func TestParse_Keyword(t *testing.T) {
 	t.Parallel()
 
@@ -190,6 +209,8 @@ func TestParseSpecialOperator(t *testing.T) {
 
 		(- 1 2)
 		(/ 1 2)
+		(- 1)
+		(mod 1 2)
 	`)
 
 	argumens := []ast.Node{
@@ -205,6 +226,8 @@ func TestParseSpecialOperator(t *testing.T) {
 			&ast.SpecialOp{Op: "*", Items: argumens},
 			&ast.SpecialOp{Op: "-", Items: argumens[:2]},
 			&ast.SpecialOp{Op: "/", Items: argumens[:2]},
+			&ast.SpecialOp{Op: "-", Items: argumens[:1]},
+			&ast.SpecialOp{Op: "mod", Items: argumens[:2]},
 		},
 	}
 
@@ -231,6 +254,430 @@ func TestParseDotSelector(t *testing.T) {
 	assertEqual(t, want, selector, "parsed dot selector")
 }
 
+func TestParseDotSelector_Chain(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		(a.b.c (.Len buf) (. x y))
+	`)
+
+	sym := func(name string) *ast.Symbol { return &ast.Symbol{Value: name} }
+
+	want := ast.NewSexp(
+		&ast.DotSelector{
+			Left:  &ast.DotSelector{Left: sym("a"), Right: sym("b")},
+			Right: sym("c"),
+		},
+		ast.NewSexp(sym(".Len"), sym("buf")),
+		&ast.DotSelector{Left: sym("x"), Right: sym("y")},
+	)
+
+	assertEqual(t, want, pkg.Nodes[0], "parsed selectors")
+}
+
+func TestParseFn(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		(fn (x & rest) (* x 2))
+		(defn double (x) (* x 2))
+	`)
+
+	x := &ast.Symbol{Value: "x"}
+	body := &ast.SpecialOp{Op: "*", Items: []ast.Node{x, &ast.Literal[int64]{Value: 2}}}
+
+	want := &ast.Package{
+		Nodes: []ast.Node{
+			&ast.Fn{
+				Params: []*ast.Symbol{x},
+				Rest:   &ast.Symbol{Value: "rest"},
+				Body:   []ast.Node{body},
+			},
+			&ast.Defn{
+				Fn: &ast.Fn{
+					Ident:  &ast.Symbol{Value: "double"},
+					Params: []*ast.Symbol{x},
+					Body:   []ast.Node{body},
+				},
+			},
+		},
+	}
+
+	assertEqual(t, want, pkg, "parsed functions")
+}
+
+func TestParseFn_BadParams(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`(fn)`,
+		`(fn (x 1) x)`,
+		`(fn (x & a b) x)`,
+		`(defn (x) x)`,
+	} {
+		lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
+
+		_, err := parser.New(lex).Parse()
+
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseFn_SpecialNames(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		(defmacro unless (cond & do) cond)
+		(let ((if) (+ 1 2)) if)
+	`)
+
+	defmacro := requireItem[*ast.Defmacro](t, pkg.Nodes, 0, "parsed defmacro")
+	want := &ast.Fn{
+		Ident:  &ast.Symbol{Value: "unless"},
+		Params: []*ast.Symbol{{Value: "cond"}},
+		Rest:   &ast.Symbol{Value: "do"},
+		Body:   []ast.Node{&ast.Symbol{Value: "cond"}},
+	}
+	assertEqual(t, want, defmacro.Fn, "parsed macro")
+
+	let := requireItem[*ast.Let](t, pkg.Nodes, 1, "parsed let")
+	require.Len(t, let.Bindings, 1)
+	requireItem[*ast.SpecialOp](t, []ast.Node{let.Bindings[0].Value}, 0, "let binding value")
+}
+
+func TestParseCollections(t *testing.T) {
+	t.Parallel()
+
+	input := `[1 [a] {:b "c"}] {}`
+	pkg := assertParse(t, input)
+
+	want := &ast.Package{
+		Nodes: []ast.Node{
+			&ast.Vector{Items: []ast.Node{
+				&ast.Literal[int64]{Value: 1},
+				&ast.Vector{Items: []ast.Node{&ast.Symbol{Value: "a"}}},
+				&ast.Map{Items: []ast.Node{&ast.Keyword{Value: ":b"}, &ast.Literal[string]{Value: `"c"`}}},
+			}},
+			&ast.Map{},
+		},
+	}
+
+	assertEqual(t, want, pkg, "parsed collections")
+	assert.Equal(t, input, strings.ReplaceAll(pkg.String(), "\n\n", " "), "printed collections")
+
+	reparsed := assertParse(t, pkg.String())
+	assertEqual(t, pkg, reparsed, "reparsed collections")
+}
+
+func TestParseCollections_Bad(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`{:a}`,
+		`[1 2`,
+		`{:a 1 ]`,
+	} {
+		lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
+
+		_, err := parser.New(lex).Parse()
+
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseLet(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		(let (a 1
+			  (b & c) d)
+			(do a b))
+	`)
+
+	node := requireItem[*ast.Let](t, pkg.Nodes, 0, "parsed let")
+
+	b := &ast.Symbol{Value: "b"}
+	want := &ast.Let{
+		Bindings: []ast.Binding{
+			{Target: &ast.Symbol{Value: "a"}, Value: &ast.Literal[int64]{Value: 1}},
+			{
+				Target: &ast.ArrayPattern{Items: []ast.Node{b}, Rest: &ast.Symbol{Value: "c"}},
+				Value:  &ast.Symbol{Value: "d"},
+			},
+		},
+		Body: []ast.Node{
+			&ast.Do{Body: []ast.Node{&ast.Symbol{Value: "a"}, b}},
+		},
+	}
+
+	assertEqual(t, want, node, "parsed let")
+}
+
+func TestParseRecur(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		(loop (i 0)
+			(if (< i 10)
+				(do (f i) (recur (+ i 1)))
+				i))
+	`)
+
+	loop := requireItem[*ast.Loop](t, pkg.Nodes, 0, "parsed loop")
+	require.Len(t, loop.Bindings, 1)
+	requireItem[*ast.If](t, loop.Body, 0, "loop body")
+}
+
+func TestParseRecur_NotTail(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`(recur 1)`,
+		`(loop (i 0) (+ 1 (recur i)))`,
+		`(loop (i 0) (recur i) i)`,
+		`(loop (i 0) (if (recur i) 1 2))`,
+		`(loop (i 0) (recur i 1))`,
+		`(fn (x) (recur))`,
+		`(loop (i 0) (while true (recur i)))`,
+	} {
+		lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
+
+		_, err := parser.New(lex).Parse()
+
+		assert.Error(t, err, input)
+	}
+}
+
+func TestCheckCalls(t *testing.T) {
+	t.Parallel()
+
+	isMacro := func(name string) bool { return name == "unless" }
+
+	for input, wantErr := range map[string]bool{
+		`(loop (i 0) (array (recur 1)))`:              true,
+		`(fn (x) (array (return x)))`:                 true,
+		`(loop (i 0) (unless (> i 9) (recur 1)))`:     false,
+		`(fn (x) (unless x (return 1)) 2)`:            false,
+		`(loop (i 0) (if (> i 9) i (recur (+ i 1))))`: false,
+	} {
+		lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
+
+		pkg, err := parser.New(lex).Parse()
+		require.NoError(t, err, input)
+
+		err = parser.CheckCalls(pkg, isMacro)
+		assert.Equal(t, wantErr, err != nil, "%s: %v", input, err)
+	}
+}
+
+func TestParseReturn(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		(fn (x)
+			(if x (return) (do (return x))))
+	`)
+
+	x := &ast.Symbol{Value: "x"}
+	want := &ast.Fn{
+		Params: []*ast.Symbol{x},
+		Body: []ast.Node{
+			&ast.If{
+				Cond: x,
+				Then: &ast.Return{},
+				Else: &ast.Do{Body: []ast.Node{&ast.Return{Value: x}}},
+			},
+		},
+	}
+
+	assertEqual(t, want, requireItem[*ast.Fn](t, pkg.Nodes, 0, "parsed fn"), "parsed fn")
+}
+
+func TestParseReturn_Bad(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`(return 1)`,
+		`(do (return 1))`,
+		`(fn (x) (return 1 2))`,
+		`(fn (x) (if (return x) 1 2))`,
+		`(fn (x) (let (y (return x)) y))`,
+		`(fn (x) [(return x)])`,
+		`(fn (x) (+ 1 (return x)))`,
+	} {
+		lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
+
+		_, err := parser.New(lex).Parse()
+
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		(defn f [x :- integer & rest :- (array string)]
+			(let (y :- float64 (* x 1.5)
+				  (a b :- boolean) rest)
+				y))
+	`)
+
+	integer := &ast.Symbol{Value: "integer"}
+	x := &ast.Symbol{Value: "x", Type: integer}
+	y := &ast.Symbol{Value: "y", Type: &ast.Symbol{Value: "float64"}}
+	rest := &ast.Symbol{Value: "rest", Type: &ast.SExp{Items: []ast.Node{
+		&ast.Symbol{Value: "array"}, &ast.Symbol{Value: "string"},
+	}}}
+
+	want := &ast.Defn{Fn: &ast.Fn{
+		Ident:  &ast.Symbol{Value: "f"},
+		Params: []*ast.Symbol{x},
+		Rest:   rest,
+		Body: []ast.Node{&ast.Let{
+			Bindings: []ast.Binding{
+				{
+					Target: y,
+					Value: &ast.SpecialOp{Op: "*", Items: []ast.Node{
+						&ast.Symbol{Value: "x"}, &ast.Literal[float64]{Value: 1.5},
+					}},
+				},
+				{
+					Target: &ast.ArrayPattern{Items: []ast.Node{
+						&ast.Symbol{Value: "a"},
+						&ast.Symbol{Value: "b", Type: &ast.Symbol{Value: "boolean"}},
+					}},
+					Value: &ast.Symbol{Value: "rest"},
+				},
+			},
+			Body: []ast.Node{&ast.Symbol{Value: "y"}},
+		}},
+	}}
+
+	got := requireItem[*ast.Defn](t, pkg.Nodes, 0, "parsed defn")
+	assertEqual(t, want, got, "parsed defn")
+	assert.Equal(t, "x :- integer", got.Fn.Params[0].String(), "annotated param")
+}
+
+func TestParseAnnotations_Assign(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `(assign x :- integer 1 y 2)`)
+
+	want := &ast.SExp{Items: []ast.Node{
+		&ast.Symbol{Value: "assign"},
+		&ast.Symbol{Value: "x", Type: &ast.Symbol{Value: "integer"}},
+		&ast.Literal[int64]{Value: 1},
+		&ast.Symbol{Value: "y"},
+		&ast.Literal[int64]{Value: 2},
+	}}
+
+	got := requireItem[*ast.SExp](t, pkg.Nodes, 0, "parsed assign")
+	assertEqual(t, want, got, "parsed assign")
+}
+
+func TestParseAnnotations_Bad(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`(fn [:- integer] 1)`,
+		`(fn [x :-] x)`,
+		`(fn [x :- integer :- string] x)`,
+		`(fn [x & :- integer] x)`,
+		`(let (x :- integer) x)`,
+		`(let (1 :- integer 2) 1)`,
+		`(assign x :-)`,
+		`(assign :- integer 1)`,
+	} {
+		lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
+
+		_, err := parser.New(lex).Parse()
+
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseTry(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		(try
+			(f 1)
+			(catch :type-error e (g e))
+			(catch ex-info? e)
+			(finally (h)))
+	`)
+
+	e := &ast.Symbol{Value: "e"}
+	want := &ast.Try{
+		Body: []ast.Node{
+			&ast.SExp{Items: []ast.Node{&ast.Symbol{Value: "f"}, &ast.Literal[int64]{Value: 1}}},
+		},
+		Catches: []ast.Catch{
+			{
+				Match: &ast.Keyword{Value: ":type-error"},
+				Ident: e,
+				Body:  []ast.Node{&ast.SExp{Items: []ast.Node{&ast.Symbol{Value: "g"}, e}}},
+			},
+			{
+				Match: &ast.Symbol{Value: "ex-info?"},
+				Ident: e,
+			},
+		},
+		Finally: []ast.Node{
+			&ast.SExp{Items: []ast.Node{&ast.Symbol{Value: "h"}}},
+		},
+	}
+
+	assertEqual(t, want, requireItem[*ast.Try](t, pkg.Nodes, 0, "parsed try"), "parsed try")
+}
+
+func TestParseTry_Bad(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`(try 1 (catch :default))`,
+		`(try 1 (catch :default 2 3))`,
+		`(try 1 (catch :default e) 2)`,
+		`(try 1 (finally 2) (catch :default e))`,
+		`(loop (i 0) (try (recur i)))`,
+	} {
+		lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
+
+		_, err := parser.New(lex).Parse()
+
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseQuote(t *testing.T) {
+	t.Parallel()
+
+	pkg := assertParse(t, `
+		'(if a b)
+		`+"`"+`(x ~(+ 1 2) ~@y)
+	`)
+
+	a, b := &ast.Symbol{Value: "a"}, &ast.Symbol{Value: "b"}
+
+	want := &ast.Package{
+		Nodes: []ast.Node{
+			// quoted forms are data, so they are not turned into special forms
+			&ast.Quote{Node: ast.NewSexp(&ast.Symbol{Value: "if"}, a, b)},
+			&ast.Quasiquote{Node: ast.NewSexp(
+				&ast.Symbol{Value: "x"},
+				&ast.Unquote{Node: &ast.SpecialOp{Op: "+", Items: []ast.Node{
+					&ast.Literal[int64]{Value: 1},
+					&ast.Literal[int64]{Value: 2},
+				}}},
+				&ast.Unquote{Node: &ast.Symbol{Value: "y"}, Splice: true},
+			)},
+		},
+	}
+
+	assertEqual(t, want, pkg, "parsed quotes")
+}
+
 func assertParse(t *testing.T, input string) *ast.Package {
 	t.Helper()
 
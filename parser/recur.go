@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ninedraft/sulisp/language/ast"
+)
+
+var errRecur = errors.New("invalid recur")
+
+// recurTarget is the innermost loop or fn which recur jumps to.
+type recurTarget struct {
+	form     string
+	want     int
+	variadic bool
+}
+
+func (target *recurTarget) checkArgs(n int) error {
+	switch {
+	case !target.variadic && n != target.want:
+		return fmt.Errorf("%w: %s expects %d arguments, got %d", errRecur, target.form, target.want, n)
+	case target.variadic && n < target.want:
+		return fmt.Errorf("%w: %s expects at least %d arguments, got %d", errRecur, target.form, target.want, n)
+	}
+
+	return nil
+}
+
+// CheckRecur verifies that every recur form in the node is in a tail position of a loop or fn.
+// Parse skips arguments of calls, which can be macro calls,
+// so expanded code must be checked again.
+func CheckRecur(node ast.Node) error {
+	parser := &Parser{}
+	parser.checkRecur(node, false, nil)
+	return errors.Join(parser.errs...)
+}
+
+// CheckCalls verifies recur and return forms like CheckRecur and CheckReturn
+// in code, which can contain macro calls. Arguments of calls of the macros are skipped,
+// they're checked after expansion.
+func CheckCalls(node ast.Node, isMacro func(name string) bool) error {
+	parser := &Parser{isMacro: isMacro}
+	parser.checkRecur(node, false, nil)
+	parser.checkReturn(node, true, false)
+	return errors.Join(parser.errs...)
+}
+
+// isMacroCall reports if the node is a call, which can be a macro call.
+func (parser *Parser) isMacroCall(sexp *ast.SExp) bool {
+	if parser.isMacro == nil || len(sexp.Items) == 0 {
+		return false
+	}
+
+	head, ok := sexp.Items[0].(*ast.Symbol)
+	return ok && parser.isMacro(head.Value)
+}
+
+// checkRecur verifies that every recur form is in a tail position of a loop or fn
+// and has a matching number of arguments.
+func (parser *Parser) checkRecur(node ast.Node, tail bool, target *recurTarget) {
+	switch node := node.(type) {
+	case *ast.Recur:
+		var err error
+		switch {
+		case target == nil:
+			err = fmt.Errorf("%w: recur outside of loop or fn", errRecur)
+		case !tail:
+			err = fmt.Errorf("%w: recur can be used only in a tail position", errRecur)
+		default:
+			err = target.checkArgs(len(node.Args))
+		}
+
+		if err != nil {
+			parser.errs = append(parser.errs, &Error{Pos: node.From, Err: err})
+		}
+
+		parser.checkRecurAll(node.Args, target)
+	case *ast.Loop:
+		for _, binding := range node.Bindings {
+			parser.checkRecur(binding.Value, false, target)
+		}
+
+		parser.checkRecurBody(node.Body, true, &recurTarget{
+			form: "loop",
+			want: len(node.Bindings),
+		})
+	case *ast.Fn:
+		parser.checkRecurBody(node.Body, true, &recurTarget{
+			form:     "fn",
+			want:     len(node.Params),
+			variadic: node.Rest != nil,
+		})
+	case *ast.Defn:
+		parser.checkRecur(node.Fn, tail, target)
+	case *ast.Defmacro:
+		parser.checkRecur(node.Fn, tail, target)
+	case *ast.If:
+		parser.checkRecur(node.Cond, false, target)
+		parser.checkRecur(node.Then, tail, target)
+		if node.Else != nil {
+			parser.checkRecur(node.Else, tail, target)
+		}
+	case *ast.Do:
+		parser.checkRecurBody(node.Body, tail, target)
+	case *ast.Package:
+		parser.checkRecurAll(node.Nodes, target)
+	case *ast.Let:
+		for _, binding := range node.Bindings {
+			parser.checkRecur(binding.Value, false, target)
+		}
+		parser.checkRecurBody(node.Body, tail, target)
+	case *ast.While:
+		parser.checkRecur(node.Cond, false, target)
+		parser.checkRecurAll(node.Body, target)
+	case *ast.Return:
+		if node.Value != nil {
+			parser.checkRecur(node.Value, false, target)
+		}
+	case *ast.Try:
+		// errors are handled after the body, so recur can't jump out of it
+		parser.checkRecurAll(node.Body, target)
+		for _, catch := range node.Catches {
+			parser.checkRecur(catch.Match, false, target)
+			parser.checkRecurAll(catch.Body, target)
+		}
+		parser.checkRecurAll(node.Finally, target)
+	case *ast.SExp:
+		if parser.isMacroCall(node) {
+			// will be checked after expansion
+			return
+		}
+		parser.checkRecurAll(node.Items, target)
+	case *ast.SpecialOp:
+		parser.checkRecurAll(node.Items, target)
+	case *ast.Vector:
+		parser.checkRecurAll(node.Items, target)
+	case *ast.Map:
+		parser.checkRecurAll(node.Items, target)
+	case *ast.DotSelector:
+		parser.checkRecur(node.Left, false, target)
+		parser.checkRecur(node.Right, false, target)
+	case *ast.Quasiquote:
+		// quoted s-expressions can't contain recur nodes, but unquoted forms can
+		parser.checkRecur(node.Node, false, target)
+	case *ast.Unquote:
+		parser.checkRecur(node.Node, false, target)
+	}
+}
+
+// checkRecurBody checks a sequence of forms, only the last one inherits the tail position.
+func (parser *Parser) checkRecurBody(body []ast.Node, tail bool, target *recurTarget) {
+	for i, node := range body {
+		parser.checkRecur(node, tail && i == len(body)-1, target)
+	}
+}
+
+// checkRecurAll checks forms, none of which are in a tail position.
+func (parser *Parser) checkRecurAll(nodes []ast.Node, target *recurTarget) {
+	for _, node := range nodes {
+		parser.checkRecur(node, false, target)
+	}
+}
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ninedraft/sulisp/language/ast"
+)
+
+var errReturn = errors.New("invalid return")
+
+// CheckReturn verifies that every return form is in a body of a fn.
+// Parse skips arguments of calls, which can be macro calls,
+// so expanded code must be checked again.
+func CheckReturn(node ast.Node) error {
+	parser := &Parser{}
+	parser.checkReturn(node, true, false)
+	return errors.Join(parser.errs...)
+}
+
+// checkReturn verifies that every return form is in a body position of a fn:
+// a body form of fn, do, let, loop, while and try or a branch of if.
+// Values of return forms can't be used, so arguments and conditions can't contain them.
+func (parser *Parser) checkReturn(node ast.Node, body, inFn bool) {
+	switch node := node.(type) {
+	case *ast.Return:
+		var err error
+		switch {
+		case !inFn:
+			err = fmt.Errorf("%w: return outside of fn", errReturn)
+		case !body:
+			err = fmt.Errorf("%w: return can be used only in a body of fn", errReturn)
+		}
+
+		if err != nil {
+			parser.errs = append(parser.errs, &Error{Pos: node.From, Err: err})
+		}
+
+		if node.Value != nil {
+			parser.checkReturn(node.Value, false, inFn)
+		}
+	case *ast.Fn:
+		parser.checkReturnBody(node.Body, true, true)
+	case *ast.Defn:
+		parser.checkReturn(node.Fn, body, inFn)
+	case *ast.Defmacro:
+		parser.checkReturn(node.Fn, body, inFn)
+	case *ast.If:
+		parser.checkReturn(node.Cond, false, inFn)
+		parser.checkReturn(node.Then, body, inFn)
+		if node.Else != nil {
+			parser.checkReturn(node.Else, body, inFn)
+		}
+	case *ast.Do:
+		parser.checkReturnBody(node.Body, body, inFn)
+	case *ast.Package:
+		parser.checkReturnBody(node.Nodes, body, inFn)
+	case *ast.Let:
+		parser.checkReturnBindings(node.Bindings, inFn)
+		parser.checkReturnBody(node.Body, body, inFn)
+	case *ast.Loop:
+		parser.checkReturnBindings(node.Bindings, inFn)
+		parser.checkReturnBody(node.Body, body, inFn)
+	case *ast.While:
+		parser.checkReturn(node.Cond, false, inFn)
+		parser.checkReturnBody(node.Body, body, inFn)
+	case *ast.Try:
+		parser.checkReturnBody(node.Body, body, inFn)
+		for _, catch := range node.Catches {
+			parser.checkReturn(catch.Match, false, inFn)
+			parser.checkReturnBody(catch.Body, body, inFn)
+		}
+		parser.checkReturnBody(node.Finally, body, inFn)
+	case *ast.Recur:
+		parser.checkReturnBody(node.Args, false, inFn)
+	case *ast.SExp:
+		if parser.isMacroCall(node) {
+			// will be checked after expansion
+			return
+		}
+		parser.checkReturnBody(node.Items, false, inFn)
+	case *ast.SpecialOp:
+		parser.checkReturnBody(node.Items, false, inFn)
+	case *ast.Vector:
+		parser.checkReturnBody(node.Items, false, inFn)
+	case *ast.Map:
+		parser.checkReturnBody(node.Items, false, inFn)
+	case *ast.DotSelector:
+		parser.checkReturn(node.Left, false, inFn)
+		parser.checkReturn(node.Right, false, inFn)
+	case *ast.Quasiquote:
+		parser.checkReturn(node.Node, false, inFn)
+	case *ast.Unquote:
+		parser.checkReturn(node.Node, false, inFn)
+	}
+}
+
+// checkReturnBody checks a sequence of forms, which are all in the same position.
+func (parser *Parser) checkReturnBody(nodes []ast.Node, body, inFn bool) {
+	for _, node := range nodes {
+		parser.checkReturn(node, body, inFn)
+	}
+}
+
+func (parser *Parser) checkReturnBindings(bindings []ast.Binding, inFn bool) {
+	for _, binding := range bindings {
+		parser.checkReturn(binding.Value, false, inFn)
+	}
+}
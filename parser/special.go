@@ -1,7 +1,12 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
+
+	"github.com/ninedraft/sulisp/internal/seq"
 	"github.com/ninedraft/sulisp/language/ast"
+	"github.com/ninedraft/sulisp/language/tokens"
 	"golang.org/x/exp/maps"
 )
 
@@ -9,11 +14,17 @@ var isSpecial = map[string]bool{
 	"import-go": true,
 	"if":        true, "cond": true,
 	"+": true, "-": true, "*": true, "/": true,
-	".": true,
+	".":  true,
+	"fn": true, "defn": true, "defmacro": true,
+	"let": true, "do": true,
+	"loop": true, "recur": true, "while": true, "return": true,
+	"try": true,
 }
 
 var specialOperators = map[string]bool{
 	"+": true, "-": true, "*": true, "/": true,
+	"mod": true, "rem": true,
+	"and": true, "or": true,
 }
 
 func init() {
@@ -34,6 +45,32 @@ func (parser *Parser) buildSpecial(sexp *ast.SExp) ast.Node {
 		return parser.buildIf(sexp)
 	case ".":
 		return parser.buildDotSelector(sexp)
+	case "fn":
+		return parser.buildFn(sexp)
+	case "defn":
+		return parser.buildDefn(sexp)
+	case "defmacro":
+		return parser.buildDefmacro(sexp)
+	case "let":
+		return parser.buildLet(sexp)
+	case "loop":
+		return parser.buildLoop(sexp)
+	case "recur":
+		return &ast.Recur{
+			PosRange: sexp.PosRange,
+			Args:     sexp.Items[1:],
+		}
+	case "while":
+		return parser.buildWhile(sexp)
+	case "return":
+		return parser.buildReturn(sexp)
+	case "try":
+		return parser.buildTry(sexp)
+	case "do":
+		return &ast.Do{
+			PosRange: sexp.PosRange,
+			Body:     sexp.Items[1:],
+		}
 	}
 
 	if specialOperators[head.Value] {
@@ -44,6 +81,29 @@ func (parser *Parser) buildSpecial(sexp *ast.SExp) ast.Node {
 	return nil
 }
 
+// isBindingList reports if the next item of a special form is a parameter or binding list.
+func (parser *Parser) isBindingList(items []ast.Node) bool {
+	if parser.quoted > 0 || len(items) == 0 || !parser.curIs(tokens.TokenLParen, tokens.TokenLBrack) {
+		return false
+	}
+
+	head, ok := items[0].(*ast.Symbol)
+	if !ok {
+		return false
+	}
+
+	switch head.Value {
+	case "let", "loop":
+		return len(items) == 1
+	case "fn":
+		return len(items) == 1 || (len(items) == 2 && isNode[*ast.Symbol](items[1]))
+	case "defn", "defmacro":
+		return len(items) == 2
+	default:
+		return false
+	}
+}
+
 func (parser *Parser) buildIf(sexp *ast.SExp) *ast.If {
 	var head *ast.Symbol // 'if or 'cond
 	var cond ast.Node
@@ -67,7 +127,7 @@ func (parser *Parser) buildIf(sexp *ast.SExp) *ast.If {
 	}
 
 	return &ast.If{
-		PosRange: parser.posRange(),
+		PosRange: sexp.PosRange,
 		Cond:     cond,
 		Then:     then_,
 		Else:     else_,
@@ -90,7 +150,7 @@ var matchImportGoAliasItem = pSexp(
 
 func (parser *Parser) buildImportGo(sexp *ast.SExp) *ast.ImportGo {
 	importgo := &ast.ImportGo{
-		PosRange: parser.posRange(),
+		PosRange: sexp.PosRange,
 	}
 
 	if len(sexp.Items) == 1 {
@@ -110,25 +170,38 @@ func (parser *Parser) buildImportGo(sexp *ast.SExp) *ast.ImportGo {
 }
 
 func (parser *Parser) buildSpecialOperator(sexp *ast.SExp) *ast.SpecialOp {
-	if len(sexp.Items) < 2 {
-		parser.errorf("operator must have at least 1 operand")
-		return nil
-	}
-
 	head := sexp.Items[0].(*ast.Symbol)
 	if !specialOperators[head.Value] {
 		parser.errorf("expected an operator, got %s", head.Value)
 		return nil
 	}
 
-	// only commutative operators can have more than 2 operands
-	if (head.Value != "+" && head.Value != "*") && len(sexp.Items) != 3 {
-		parser.errorf("operator %s must have 2 operands, got %d", head.Value, len(sexp.Items)-1)
-		return nil
+	// only commutative operators can have more than 2 operands,
+	// minus with a single operand is a negation,
+	// logical operators can have no operands at all: (and) is true, (or) is false
+	n := len(sexp.Items) - 1
+	switch head.Value {
+	case "and", "or":
+		// pass
+	case "+", "*":
+		if n < 1 {
+			parser.errorf("operator must have at least 1 operand")
+			return nil
+		}
+	case "-":
+		if n < 1 || n > 2 {
+			parser.errorf("operator %s must have 1 or 2 operands, got %d", head.Value, n)
+			return nil
+		}
+	default:
+		if n != 2 {
+			parser.errorf("operator %s must have 2 operands, got %d", head.Value, n)
+			return nil
+		}
 	}
 
 	return &ast.SpecialOp{
-		PosRange: parser.posRange(),
+		PosRange: sexp.PosRange,
 		Op:       head.Value,
 		Items:    sexp.Items[1:],
 	}
@@ -146,8 +219,351 @@ func (parser *Parser) buildDotSelector(sexp *ast.SExp) *ast.DotSelector {
 	}
 
 	return &ast.DotSelector{
-		PosRange: parser.posRange(),
+		PosRange: sexp.PosRange,
 		Left:     left,
 		Right:    right,
 	}
 }
+
+// (fn name? (params... & rest) body...)
+func (parser *Parser) buildFn(sexp *ast.SExp) *ast.Fn {
+	items := sexp.Items[1:]
+
+	fn := &ast.Fn{
+		PosRange: sexp.PosRange,
+	}
+
+	if len(items) > 0 {
+		if ident, ok := items[0].(*ast.Symbol); ok {
+			fn.Ident = ident
+			items = items[1:]
+		}
+	}
+
+	if len(items) == 0 {
+		parser.errorf("invalid fn form: %w", errNoParams)
+		return nil
+	}
+
+	params, rest, err := fnParams(items[0])
+	if err != nil {
+		parser.errorf("invalid fn form: %w", err)
+		return nil
+	}
+
+	fn.Params = params
+	fn.Rest = rest
+	fn.Body = items[1:]
+
+	return fn
+}
+
+// (defn name (params... & rest) body...)
+func (parser *Parser) buildDefn(sexp *ast.SExp) *ast.Defn {
+	fn := parser.buildNamedFn(sexp)
+	if fn == nil {
+		return nil
+	}
+
+	return &ast.Defn{
+		PosRange: sexp.PosRange,
+		Fn:       fn,
+	}
+}
+
+// (defmacro name (params... & rest) body...)
+func (parser *Parser) buildDefmacro(sexp *ast.SExp) *ast.Defmacro {
+	fn := parser.buildNamedFn(sexp)
+	if fn == nil {
+		return nil
+	}
+
+	return &ast.Defmacro{
+		PosRange: sexp.PosRange,
+		Fn:       fn,
+	}
+}
+
+func (parser *Parser) buildNamedFn(sexp *ast.SExp) *ast.Fn {
+	if len(sexp.Items) < 2 || !isNode[*ast.Symbol](sexp.Items[1]) {
+		parser.errorf("invalid %s form: want a name as a first argument", sexp.Items[0])
+		return nil
+	}
+
+	return parser.buildFn(sexp)
+}
+
+var (
+	errNoParams  = errors.New("missing parameter list")
+	errBadParams = errors.New("malformed parameter list")
+)
+
+func fnParams(node ast.Node) (params []*ast.Symbol, rest *ast.Symbol, _ error) {
+	items, ok := listItems(node)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: want a list of symbols, got %s %q", errBadParams, node.Name(), node)
+	}
+
+	items, err := annotate(items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := 0; i < len(items); i++ {
+		param, ok := items[i].(*ast.Symbol)
+		if !ok {
+			item := items[i]
+			return nil, nil, fmt.Errorf("%w: parameter %d must be a symbol, got %s %q", errBadParams, i, item.Name(), item)
+		}
+
+		if param.Value != "&" {
+			params = append(params, param)
+			continue
+		}
+
+		if i != len(items)-2 {
+			return nil, nil, fmt.Errorf("%w: & must be followed by exactly one symbol", errBadParams)
+		}
+
+		rest, ok = items[i+1].(*ast.Symbol)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: & must be followed by a symbol", errBadParams)
+		}
+
+		break
+	}
+
+	return params, rest, nil
+}
+
+// (let (name value (a b & rest) value) body...)
+func (parser *Parser) buildLet(sexp *ast.SExp) *ast.Let {
+	bindings, ok := parser.buildBindings(sexp)
+	if !ok {
+		return nil
+	}
+
+	return &ast.Let{
+		PosRange: sexp.PosRange,
+		Bindings: bindings,
+		Body:     sexp.Items[2:],
+	}
+}
+
+// (loop (name value (a b & rest) value) body...)
+func (parser *Parser) buildLoop(sexp *ast.SExp) *ast.Loop {
+	bindings, ok := parser.buildBindings(sexp)
+	if !ok {
+		return nil
+	}
+
+	return &ast.Loop{
+		PosRange: sexp.PosRange,
+		Bindings: bindings,
+		Body:     sexp.Items[2:],
+	}
+}
+
+// buildBindings parses a binding list of let-like forms.
+func (parser *Parser) buildBindings(sexp *ast.SExp) ([]ast.Binding, bool) {
+	form := sexp.Items[0].String()
+
+	if len(sexp.Items) < 2 {
+		parser.errorf("invalid %s form: missing bindings", form)
+		return nil, false
+	}
+
+	items, ok := listItems(sexp.Items[1])
+	if !ok {
+		item := sexp.Items[1]
+		parser.errorf("invalid %s form: want a list of bindings, got %s %q", form, item.Name(), item)
+		return nil, false
+	}
+
+	items, err := annotate(items)
+	if err != nil {
+		parser.errorf("invalid %s form: %w", form, err)
+		return nil, false
+	}
+
+	if len(items)%2 != 0 {
+		parser.errorf("invalid %s form: want an even number of binding items, got %d", form, len(items))
+		return nil, false
+	}
+
+	bindings := make([]ast.Binding, 0, len(items)/2)
+
+	for target, value := range seq.SlicePairs(items) {
+		target, err := bindingTarget(target)
+		if err != nil {
+			parser.errorf("invalid %s form: %w", form, err)
+			return nil, false
+		}
+
+		bindings = append(bindings, ast.Binding{
+			Target: target,
+			Value:  parser.analyze(value),
+		})
+	}
+
+	return bindings, true
+}
+
+// (while cond body...)
+func (parser *Parser) buildWhile(sexp *ast.SExp) *ast.While {
+	if len(sexp.Items) < 2 {
+		parser.errorf("invalid while form: missing condition")
+		return nil
+	}
+
+	return &ast.While{
+		PosRange: sexp.PosRange,
+		Cond:     sexp.Items[1],
+		Body:     sexp.Items[2:],
+	}
+}
+
+// (return) or (return value)
+func (parser *Parser) buildReturn(sexp *ast.SExp) *ast.Return {
+	ret := &ast.Return{PosRange: sexp.PosRange}
+
+	switch len(sexp.Items) {
+	case 1:
+		// pass
+	case 2:
+		ret.Value = sexp.Items[1]
+	default:
+		parser.errorf("invalid return form: want at most 1 value, got %d", len(sexp.Items)-1)
+		return nil
+	}
+
+	return ret
+}
+
+// (try body... (catch match name body...)... (finally body...))
+// Catch and finally clauses must follow the body, finally must be the last one.
+func (parser *Parser) buildTry(sexp *ast.SExp) *ast.Try {
+	try := &ast.Try{PosRange: sexp.PosRange}
+
+	for _, item := range sexp.Items[1:] {
+		clause, isClause := tryClause(item)
+
+		switch {
+		case try.Finally != nil:
+			parser.errorf("invalid try form: finally must be the last clause")
+			return nil
+		case !isClause && len(try.Catches) > 0:
+			parser.errorf("invalid try form: unexpected %s %q after catch clauses", item.Name(), item)
+			return nil
+		case !isClause:
+			try.Body = append(try.Body, item)
+		case isSymbolName(clause.Items[0], "finally"):
+			try.Finally = clause.Items[1:]
+		default:
+			catch, ok := parser.buildCatch(clause)
+			if !ok {
+				return nil
+			}
+			try.Catches = append(try.Catches, catch)
+		}
+	}
+
+	return try
+}
+
+// (catch match name body...)
+func (parser *Parser) buildCatch(sexp *ast.SExp) (ast.Catch, bool) {
+	if len(sexp.Items) < 3 {
+		parser.errorf("invalid catch clause: want an error kind or a predicate and a name")
+		return ast.Catch{}, false
+	}
+
+	ident, ok := sexp.Items[2].(*ast.Symbol)
+	if !ok {
+		item := sexp.Items[2]
+		parser.errorf("invalid catch clause: want a name, got %s %q", item.Name(), item)
+		return ast.Catch{}, false
+	}
+
+	return ast.Catch{
+		PosRange: sexp.PosRange,
+		Match:    sexp.Items[1],
+		Ident:    ident,
+		Body:     sexp.Items[3:],
+	}, true
+}
+
+// tryClause reports if the node is a catch or a finally clause.
+func tryClause(node ast.Node) (*ast.SExp, bool) {
+	sexp, ok := node.(*ast.SExp)
+	if !ok || len(sexp.Items) == 0 {
+		return nil, false
+	}
+
+	head := sexp.Items[0]
+	return sexp, isSymbolName(head, "catch") || isSymbolName(head, "finally")
+}
+
+var errBadBinding = errors.New("malformed binding")
+
+// bindingTarget returns a symbol or an array destructuring pattern.
+func bindingTarget(node ast.Node) (ast.Node, error) {
+	if symbol, ok := node.(*ast.Symbol); ok {
+		return symbol, nil
+	}
+
+	if items, ok := listItems(node); ok {
+		items, err := annotate(items)
+		if err != nil {
+			return nil, err
+		}
+
+		pattern := &ast.ArrayPattern{PosRange: node.Pos()}
+
+		for i := 0; i < len(items); i++ {
+			item := items[i]
+
+			if isSymbolName(item, "&") {
+				if i != len(items)-2 || !isNode[*ast.Symbol](items[i+1]) {
+					return nil, fmt.Errorf("%w: & must be followed by exactly one symbol", errBadBinding)
+				}
+
+				pattern.Rest = items[i+1].(*ast.Symbol)
+				break
+			}
+
+			target, err := bindingTarget(item)
+			if err != nil {
+				return nil, err
+			}
+
+			pattern.Items = append(pattern.Items, target)
+		}
+
+		return pattern, nil
+	}
+
+	return nil, fmt.Errorf("%w: want a symbol or a list of symbols, got %s %q", errBadBinding, node.Name(), node)
+}
+
+// listItems returns items of a list or a vector.
+func listItems(node ast.Node) ([]ast.Node, bool) {
+	switch node := node.(type) {
+	case *ast.SExp:
+		return node.Items, true
+	case *ast.Vector:
+		return node.Items, true
+	default:
+		return nil, false
+	}
+}
+
+func isSymbolName(node ast.Node, name string) bool {
+	symbol, ok := node.(*ast.Symbol)
+	return ok && symbol.Value == name
+}
+
+func isNode[N ast.Node](node ast.Node) bool {
+	_, ok := node.(N)
+	return ok
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/ninedraft/sulisp/interpreter/astwalk"
+	"github.com/ninedraft/sulisp/language/object"
 	"github.com/ninedraft/sulisp/lexer"
 	"github.com/ninedraft/sulisp/parser"
 )
@@ -24,6 +25,9 @@ const (
 	SignalBackspace              // delete last char
 )
 
+// replFile is a file name of positions in the input.
+const replFile = "repl"
+
 func Run(out io.Writer, in io.Reader, signals <-chan Signal) error {
 	buf := &bytes.Buffer{}
 
@@ -68,7 +72,7 @@ func Run(out io.Writer, in io.Reader, signals <-chan Signal) error {
 
 		buf.Write(sc.Bytes())
 
-		lex := lexer.NewLexer("repl", bufReader(buf))
+		lex := lexer.NewLexer(replFile, bufReader(buf))
 		par := parser.New(lex)
 
 		pkg, errParse := par.Parse()
@@ -83,7 +87,21 @@ func Run(out io.Writer, in io.Reader, signals <-chan Signal) error {
 		case errParse != nil:
 			fmt.Fprintf(out, "ERROR:\n%s\n", errParse)
 		default:
-			fmt.Fprintf(out, "\n\n%s\n", astwalk.Eval(pkg, env).Inspect())
+			expanded, errExpand := astwalk.Expand(pkg, env)
+			if errExpand != nil {
+				fmt.Fprintf(out, "ERROR:\n%s\n", errExpand)
+				break
+			}
+
+			result := astwalk.Eval(expanded, env)
+
+			var errRuntime *object.RuntimeError
+			if err, isErr := result.(*object.Error); isErr && errors.As(err.Err, &errRuntime) {
+				fmt.Fprintf(out, "ERROR:\n%s", errRuntime.Pretty(inputSource(errRuntime, buf.Bytes())))
+				break
+			}
+
+			fmt.Fprintf(out, "\n\n%s\n", result.Inspect())
 		}
 
 		buf.Reset()
@@ -92,6 +110,16 @@ func Run(out io.Writer, in io.Reader, signals <-chan Signal) error {
 	return sc.Err()
 }
 
+// inputSource returns the input if the error is raised by its forms.
+// Errors in functions defined by previous inputs have positions in other sources.
+func inputSource(err *object.RuntimeError, input []byte) []byte {
+	if len(err.Stack) > 0 || err.Pos.From.File != replFile {
+		return nil
+	}
+
+	return input
+}
+
 func bufReader(buf *bytes.Buffer) *bytes.Reader {
 	return bytes.NewReader(buf.Bytes())
 }
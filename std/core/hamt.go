@@ -0,0 +1,231 @@
+package core
+
+import (
+	"iter"
+
+	"github.com/ninedraft/sulisp/internal/collections/hamt"
+)
+
+// Map is a persistent hash map: a hash array mapped trie.
+// Unlike HashMap, updates return new maps, which share the most of their nodes with the old one.
+type Map[K Hashable, V Value] struct {
+	entries hamt.Map[K, V]
+}
+
+func MapNew[K Hashable, V Value]() *Map[K, V] {
+	return &Map[K, V]{}
+}
+
+func (*Map[K, V]) Kind() Value {
+	var key K
+	var value V
+	return newTypeSpec("core.Map", map[Keyword]Value{
+		":key":   key.Kind(),
+		":value": value.Kind(),
+	})
+}
+
+// Seq returns key-value pairs.
+func (m *Map[K, V]) Seq() Seq[*List[Value]] {
+	pairs := make([]*List[Value], 0, m.Len())
+	for key, value := range m.persistent().All() {
+		pairs = append(pairs, ListNew[Value](key, value))
+	}
+	return &sliceSeq[*List[Value]]{values: pairs}
+}
+
+func (m *Map[K, V]) String() string {
+	return formString("map", m.all())
+}
+
+func (m *Map[K, V]) MarshalText() ([]byte, error) {
+	return marshalForm("map", m.all())
+}
+
+func (m *Map[K, V]) Len() int {
+	return m.persistent().Len()
+}
+
+func (m *Map[K, V]) Get(key K) (V, bool) {
+	return m.persistent().Get(key)
+}
+
+func (m *Map[K, V]) Contains(key K) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Assoc returns a map with the key bound to the value.
+func (m *Map[K, V]) Assoc(key K, value V) *Map[K, V] {
+	return &Map[K, V]{entries: m.persistent().Set(key, value)}
+}
+
+// Dissoc returns a map without the keys.
+func (m *Map[K, V]) Dissoc(keys ...K) *Map[K, V] {
+	entries := m.persistent()
+	for _, key := range keys {
+		entries = entries.Delete(key)
+	}
+	return &Map[K, V]{entries: entries}
+}
+
+func (m *Map[K, V]) Keys() *List[K] {
+	keys := make([]K, 0, m.Len())
+	for key := range m.persistent().All() {
+		keys = append(keys, key)
+	}
+	return ListNew(keys...)
+}
+
+func (m *Map[K, V]) Values() *List[V] {
+	values := make([]V, 0, m.Len())
+	for _, value := range m.persistent().All() {
+		values = append(values, value)
+	}
+	return ListNew(values...)
+}
+
+func (m *Map[K, V]) Eq(other *Map[K, V]) bool {
+	if m.Len() != other.Len() {
+		return false
+	}
+
+	for key, value := range m.persistent().All() {
+		if o, ok := other.Get(key); !ok || !Eq(value, o) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *Map[K, V]) persistent() hamt.Map[K, V] {
+	if m == nil {
+		return hamt.Map[K, V]{}
+	}
+	return m.entries
+}
+
+func (m *Map[K, V]) all() iter.Seq[Value] {
+	return func(yield func(Value) bool) {
+		for key, value := range m.persistent().All() {
+			if !yield(key) || !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Set is a persistent hash set, it's a Map without values.
+type Set[E Hashable] struct {
+	elems hamt.Map[E, struct{}]
+}
+
+func SetNew[E Hashable](values ...E) *Set[E] {
+	return (*Set[E])(nil).Conj(values...)
+}
+
+func (*Set[E]) Kind() Value {
+	var elem E
+	return newTypeSpec("core.Set", map[Keyword]Value{
+		":elem": elem.Kind(),
+	})
+}
+
+func (set *Set[E]) Seq() Seq[E] {
+	values := make([]E, 0, set.Len())
+	for value := range set.persistent().All() {
+		values = append(values, value)
+	}
+	return &sliceSeq[E]{values: values}
+}
+
+func (set *Set[E]) String() string {
+	return formString("set", set.all())
+}
+
+func (set *Set[E]) MarshalText() ([]byte, error) {
+	return marshalForm("set", set.all())
+}
+
+func (set *Set[E]) Len() int {
+	return set.persistent().Len()
+}
+
+func (set *Set[E]) Contains(value E) bool {
+	_, ok := set.persistent().Get(value)
+	return ok
+}
+
+// Conj returns a set with the values added.
+func (set *Set[E]) Conj(values ...E) *Set[E] {
+	elems := set.persistent()
+	for _, value := range values {
+		elems = elems.Set(value, struct{}{})
+	}
+	return &Set[E]{elems: elems}
+}
+
+// Disj returns a set without the values.
+func (set *Set[E]) Disj(values ...E) *Set[E] {
+	elems := set.persistent()
+	for _, value := range values {
+		elems = elems.Delete(value)
+	}
+	return &Set[E]{elems: elems}
+}
+
+func (set *Set[E]) Eq(other *Set[E]) bool {
+	if set.Len() != other.Len() {
+		return false
+	}
+
+	for value := range set.persistent().All() {
+		if !other.Contains(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (set *Set[E]) persistent() hamt.Map[E, struct{}] {
+	if set == nil {
+		return hamt.Map[E, struct{}]{}
+	}
+	return set.elems
+}
+
+func (set *Set[E]) all() iter.Seq[Value] {
+	return func(yield func(Value) bool) {
+		for value := range set.persistent().All() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// sliceSeq is a sequence of values collected from an unordered collection.
+type sliceSeq[E Value] struct {
+	values []E
+}
+
+func (seq *sliceSeq[E]) First() (E, bool) {
+	if seq.Empty() {
+		var empty E
+		return empty, false
+	}
+	return seq.values[0], true
+}
+
+func (seq *sliceSeq[E]) Next() Seq[E] {
+	if seq.Empty() {
+		return nil
+	}
+	return &sliceSeq[E]{values: seq.values[1:]}
+}
+
+func (seq *sliceSeq[E]) Empty() bool {
+	return seq == nil || len(seq.values) == 0
+}
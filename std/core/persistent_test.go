@@ -0,0 +1,90 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/ninedraft/sulisp/std/core"
+)
+
+func TestVector(test *testing.T) {
+	var vec core.Collection[core.Int] = core.VectorNew[core.Int](1, 2, 3)
+
+	base := vec.(*core.Vector[core.Int])
+	grown := base.Conj(4).Assoc(0, 10)
+
+	if got := base.String(); got != "(vector 1 2 3)" {
+		test.Errorf("base vector is changed: %s", got)
+	}
+	if text, err := grown.MarshalText(); err != nil || string(text) != "(vector 10 2 3 4)" {
+		test.Errorf("text of the grown vector: %s, %v", text, err)
+	}
+
+	if !base.Eq(grown.Pop().Assoc(0, 1)) || base.Eq(grown) {
+		test.Errorf("vectors are compared by values")
+	}
+
+	if !core.Contains[core.Int](grown, 4) || core.Contains[core.Int](base, 4) {
+		test.Errorf("contains")
+	}
+
+	sum := core.Int(0)
+	for seq := grown.Seq(); !seq.Empty(); seq = seq.Next() {
+		value, _ := seq.First()
+		sum += value
+	}
+	if sum != 19 {
+		test.Errorf("sum of the sequence %d", sum)
+	}
+}
+
+func TestMap(test *testing.T) {
+	m := core.MapNew[core.Keyword, core.Int]().Assoc(":a", 1).Assoc(":b", 2)
+	changed := m.Assoc(":a", 10).Dissoc(":b")
+
+	if got, _ := m.Get(":a"); got != 1 || m.Len() != 2 {
+		test.Errorf("base map is changed: %s", m)
+	}
+	if text, err := changed.MarshalText(); err != nil || string(text) != "(map :a 10)" {
+		test.Errorf("text of the changed map: %s, %v", text, err)
+	}
+
+	if !m.Eq(changed.Assoc(":a", 1).Assoc(":b", 2)) || m.Eq(changed) {
+		test.Errorf("maps are compared by values")
+	}
+
+	var pairs core.Collection[*core.List[core.Value]] = m
+	n := 0
+	for seq := pairs.Seq(); !seq.Empty(); seq = seq.Next() {
+		pair, _ := seq.First()
+		key, _ := pair.First()
+		value, _ := pair.Next().First()
+		if got, _ := m.Get(key.(core.Keyword)); got != value {
+			test.Errorf("pair %s", pair)
+		}
+		n++
+	}
+	if n != m.Len() {
+		test.Errorf("got %d pairs", n)
+	}
+}
+
+func TestSet(test *testing.T) {
+	var set core.Collection[core.String] = core.SetNew[core.String]("a", "b", "a")
+
+	base := set.(*core.Set[core.String])
+	changed := base.Disj("a").Conj("c")
+
+	if base.Len() != 2 || !base.Contains("a") || base.Contains("c") {
+		test.Errorf("base set is changed: %s", base)
+	}
+	if !changed.Contains("c") || changed.Contains("a") {
+		test.Errorf("changed set %s", changed)
+	}
+	if text, err := core.SetNew[core.String]("x").MarshalText(); err != nil || string(text) != "(set x)" {
+		test.Errorf("text of a set: %s, %v", text, err)
+	}
+
+	if !base.Eq(core.SetNew[core.String]("b", "a")) || base.Eq(changed) {
+		test.Errorf("sets are compared by values")
+	}
+}
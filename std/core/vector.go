@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bytes"
+	"iter"
+	"strings"
+
+	"github.com/ninedraft/sulisp/internal/collections/vector"
+)
+
+// Vector is a persistent vector: a 32-way trie of values.
+// Updates return new vectors, which share the most of their nodes with the old one.
+type Vector[E Value] struct {
+	values vector.Vector[E]
+}
+
+func VectorNew[E Value](values ...E) *Vector[E] {
+	return &Vector[E]{values: vector.Of(values...)}
+}
+
+func (*Vector[E]) Kind() Value {
+	var elem E
+	return newTypeSpec("core.Vector", map[Keyword]Value{
+		":elem": elem.Kind(),
+	})
+}
+
+func (vec *Vector[E]) Seq() Seq[E] {
+	return &vectorSeq[E]{vec: vec}
+}
+
+func (vec *Vector[E]) String() string {
+	return formString("vector", vec.all())
+}
+
+func (vec *Vector[E]) MarshalText() ([]byte, error) {
+	return marshalForm("vector", vec.all())
+}
+
+func (vec *Vector[E]) Len() int {
+	if vec == nil {
+		return 0
+	}
+	return vec.values.Len()
+}
+
+// At returns the value by the index.
+func (vec *Vector[E]) At(i int) (E, bool) {
+	if vec == nil {
+		var empty E
+		return empty, false
+	}
+	return vec.values.At(i)
+}
+
+// Conj returns a vector with the values added to the end.
+func (vec *Vector[E]) Conj(values ...E) *Vector[E] {
+	result := vec.persistent()
+	for _, value := range values {
+		result = result.Append(value)
+	}
+	return &Vector[E]{values: result}
+}
+
+// Assoc returns a vector with the value at the index replaced.
+func (vec *Vector[E]) Assoc(i int, value E) *Vector[E] {
+	return &Vector[E]{values: vec.persistent().Set(i, value)}
+}
+
+// Pop returns a vector without the last value.
+func (vec *Vector[E]) Pop() *Vector[E] {
+	return &Vector[E]{values: vec.persistent().Pop()}
+}
+
+func (vec *Vector[E]) Contains(value E) bool {
+	for _, v := range vec.persistent().All() {
+		if Eq(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (vec *Vector[E]) Eq(other *Vector[E]) bool {
+	if vec.Len() != other.Len() {
+		return false
+	}
+
+	for i, value := range vec.persistent().All() {
+		if o, _ := other.At(i); !Eq(value, o) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (vec *Vector[E]) persistent() vector.Vector[E] {
+	if vec == nil {
+		return vector.Vector[E]{}
+	}
+	return vec.values
+}
+
+func (vec *Vector[E]) all() iter.Seq[Value] {
+	return func(yield func(Value) bool) {
+		for _, value := range vec.persistent().All() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+type vectorSeq[E Value] struct {
+	vec *Vector[E]
+	i   int
+}
+
+func (seq *vectorSeq[E]) First() (E, bool) {
+	return seq.vec.At(seq.i)
+}
+
+func (seq *vectorSeq[E]) Next() Seq[E] {
+	if seq.Empty() {
+		return nil
+	}
+	return &vectorSeq[E]{vec: seq.vec, i: seq.i + 1}
+}
+
+func (seq *vectorSeq[E]) Empty() bool {
+	return seq == nil || seq.i >= seq.vec.Len()
+}
+
+// formString writes values as a form: (name a b c).
+func formString(name string, values iter.Seq[Value]) string {
+	str := &strings.Builder{}
+	str.WriteString("(" + name)
+
+	for value := range values {
+		str.WriteByte(' ')
+		str.WriteString(value.String())
+	}
+
+	str.WriteByte(')')
+	return str.String()
+}
+
+// marshalForm marshals values as a form: (name a b c).
+func marshalForm(name string, values iter.Seq[Value]) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("(" + name)
+
+	for value := range values {
+		buf.WriteByte(' ')
+
+		bb, err := value.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(bb)
+	}
+
+	buf.WriteByte(')')
+	return buf.Bytes(), nil
+}
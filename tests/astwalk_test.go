@@ -1,15 +1,26 @@
 package tests
 
 import (
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
 	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ninedraft/sulisp/interpreter/astwalk"
+	"github.com/ninedraft/sulisp/interpreter/gopkg"
+	"github.com/ninedraft/sulisp/interpreter/vm"
 	"github.com/ninedraft/sulisp/language/ast"
 	"github.com/ninedraft/sulisp/language/object"
+	"github.com/ninedraft/sulisp/language/tokens"
 	"github.com/ninedraft/sulisp/lexer"
 	"github.com/ninedraft/sulisp/parser"
+	"github.com/stretchr/testify/require"
 )
 
 func TestASTWalk(t *testing.T) {
@@ -37,13 +48,470 @@ func TestASTWalk(t *testing.T) {
 		`, &object.Primitive[bool]{Value: true})
 	})
 
+	t.Run("chained comparisons", func(t *testing.T) {
+		testASTWalk(t, `
+			(array
+				(< 1 2 3)
+				(< 1 3 2)
+				(<= 1 1 2.5)
+				(>= 3 3 1)
+				(> 2 1.5))
+		`, object.NewArray(
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(false),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(true),
+		))
+	})
+
+	t.Run("incomparable", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(< 1 "a")
+		`, "unable to compare integer and string")
+	})
+
+	t.Run("=", func(t *testing.T) {
+		testASTWalk(t, `
+			(array
+				(= 1 1.0 1)
+				(= "a" "b")
+				(= (array 1 (array 2)) (array 1.0 (array 2)))
+				(= (namespace a 1) (namespace a 1))
+				(= (if false 1) (if false 2))
+				(not= 1 2))
+		`, object.NewArray(
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(false),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(true),
+		))
+	})
+
+	t.Run("if with comparison", func(t *testing.T) {
+		testASTWalk(t, `
+			(if (<= 2 1) "le" "gt")
+		`, object.PrimitiveOf(`"gt"`))
+	})
+
+	t.Run("logical", func(t *testing.T) {
+		testASTWalk(t, `
+			(array
+				(and true (< 1 2))
+				(and true false)
+				(or false (= 1 1))
+				(or false false)
+				(and)
+				(or)
+				(not (and true false)))
+		`, object.NewArray(
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(false),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(false),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(false),
+			object.PrimitiveOf(true),
+		))
+	})
+
+	t.Run("short-circuit", func(t *testing.T) {
+		testASTWalk(t, `
+			(array
+				(and false (/ 1 0))
+				(or true (/ 1 0)))
+		`, object.NewArray(
+			object.PrimitiveOf(false),
+			object.PrimitiveOf(true),
+		))
+	})
+
+	t.Run("logical type error", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(and true 1)
+		`, "argument 1 must be a boolean")
+	})
+
+	t.Run("type-of logical", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of (if (and true (or false true)) 1 2))
+		`, object.TypeFor(object.ObjInteger))
+	})
+
 	t.Run("array constructor", func(t *testing.T) {
 		testASTWalk(t, `
 			(array 1 2)
-		`, &object.Array{Elements: []object.Object{
+		`, object.NewArray(
 			object.PrimitiveOf[int64](1),
 			object.PrimitiveOf[int64](2),
-		}})
+		))
+	})
+
+	t.Run("vector literal", func(t *testing.T) {
+		testASTWalk(t, `
+			(let (x 2)
+				[1 x [(+ x 1)]])
+		`, object.NewArray(
+			object.PrimitiveOf[int64](1),
+			object.PrimitiveOf[int64](2),
+			object.NewArray(object.PrimitiveOf[int64](3)),
+		))
+	})
+
+	t.Run("map literal", func(t *testing.T) {
+		want := object.NewMap()
+		_ = want.Set(object.Keyword(":a"), object.PrimitiveOf[int64](1))
+		_ = want.Set(object.PrimitiveOf("\"b\""), object.PrimitiveOf[int64](2))
+
+		testASTWalk(t, `
+			{:a 1 "b" (+ 1 1)}
+		`, want)
+	})
+
+	t.Run("keyword", func(t *testing.T) {
+		testASTWalk(t, `
+			:key
+		`, object.Keyword(":key"))
+	})
+
+	t.Run("map lookup", func(t *testing.T) {
+		testASTWalk(t, `
+			(let (m {:a 1 :b {:c 2}})
+				[(m :a) (:c (:b m)) (:d m 3) (get m :x) (get m :x 4) (contains? m :a) (contains? m :x)])
+		`, object.NewArray(
+			object.PrimitiveOf[int64](1),
+			object.PrimitiveOf[int64](2),
+			object.PrimitiveOf[int64](3),
+			object.Null{},
+			object.PrimitiveOf[int64](4),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(false),
+		))
+	})
+
+	t.Run("map builtins", func(t *testing.T) {
+		testASTWalk(t, `
+			(let (m {:a 1 :b 2}
+				  n (assoc m :c 3 :a 0)
+				  d (dissoc n :b))
+				[(keys n) (vals n) (keys d) (= m {:b 2 :a 1})])
+		`, object.NewArray(
+			object.NewArray(object.Keyword(":a"), object.Keyword(":b"), object.Keyword(":c")),
+			object.NewArray(
+				object.PrimitiveOf[int64](0),
+				object.PrimitiveOf[int64](2),
+				object.PrimitiveOf[int64](3),
+			),
+			object.NewArray(object.Keyword(":a"), object.Keyword(":c")),
+			object.PrimitiveOf(true),
+		))
+	})
+
+	t.Run("persistent maps", func(t *testing.T) {
+		// versions of a large map share entries, but changes of one don't leak into others
+		testASTWalk(t, `
+			(let (m (loop (m {} i 0)
+						(if (< i 100)
+							(recur (assoc m i (* i i)) (+ i 1))
+							m))
+				  d (dissoc m 0 50)
+				  n (assoc d 99 :last))
+				[(get m 50) (get d 50) (get d 99) (get n 99) (get m 99) ((keys d) 49) ((keys n) 97)])
+		`, object.NewArray(
+			object.PrimitiveOf[int64](2500),
+			object.Null{},
+			object.PrimitiveOf[int64](9801),
+			object.Keyword(":last"),
+			object.PrimitiveOf[int64](9801),
+			object.PrimitiveOf[int64](51),
+			object.PrimitiveOf[int64](99),
+		))
+	})
+
+	t.Run("dissoc of the most of keys", func(t *testing.T) {
+		// deleted entries are compacted, the order of the rest stays
+		testASTWalk(t, `
+			(let (m (loop (m {} i 0)
+						(if (< i 100)
+							(recur (assoc m i i) (+ i 1))
+							m))
+				  d (loop (d m i 0)
+						(if (< i 97)
+							(recur (dissoc d (+ i 2)) (+ i 1))
+							d)))
+				[(keys d) (get (assoc d 5 5) 5) (keys (assoc d 0 :zero)) (get m 50)])
+		`, object.NewArray(
+			object.NewArray(
+				object.PrimitiveOf[int64](0), object.PrimitiveOf[int64](1), object.PrimitiveOf[int64](99),
+			),
+			object.PrimitiveOf[int64](5),
+			object.NewArray(
+				object.PrimitiveOf[int64](0), object.PrimitiveOf[int64](1), object.PrimitiveOf[int64](99),
+			),
+			object.PrimitiveOf[int64](50),
+		))
+	})
+
+	t.Run("persistent arrays", func(t *testing.T) {
+		// versions of a large array share elements, but changes of one don't leak into others
+		testASTWalk(t, `
+			(let (a (loop (a [] i 0)
+						(if (< i 100)
+							(recur (conj a i) (+ i 1))
+							a))
+				  b (conj a :last)
+				  [x y & rest] b)
+				[(a 99) (b 100) (conj [] 1 2) x y (rest 98)])
+		`, object.NewArray(
+			object.PrimitiveOf[int64](99),
+			object.Keyword(":last"),
+			object.NewArray(object.PrimitiveOf[int64](1), object.PrimitiveOf[int64](2)),
+			object.PrimitiveOf[int64](0),
+			object.PrimitiveOf[int64](1),
+			object.Keyword(":last"),
+		))
+	})
+
+	t.Run("sets", func(t *testing.T) {
+		testASTWalk(t, `
+			(let (s (hash-set 1 :a "b" 1)
+				  c (conj s 2 :a)
+				  d (disj c 1 :x))
+				[s (contains? s :a) (contains? s 2) (contains? c 2) (contains? d 1) (= d (hash-set "b" :a 2))])
+		`, object.NewArray(
+			setOf(t, object.PrimitiveOf[int64](1), object.Keyword(":a"), object.PrimitiveOf(`"b"`)),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(false),
+			object.PrimitiveOf(true),
+			object.PrimitiveOf(false),
+			object.PrimitiveOf(true),
+		))
+	})
+
+	t.Run("set lookups", func(t *testing.T) {
+		testASTWalk(t, `
+			(assign s (hash-set 1 :a))
+			[(s 1) (s 2) (s 2 :none) (:a s)]
+		`, object.NewArray(
+			object.PrimitiveOf[int64](1),
+			object.Null{},
+			object.Keyword(":none"),
+			object.Keyword(":a"),
+		))
+	})
+
+	t.Run("empty list", func(t *testing.T) {
+		for _, input := range []string{
+			`()`,
+			`(do ())`,
+			`(if () 1 2)`,
+			`(defmacro nothing () '()) (nothing)`,
+		} {
+			testASTWalkErr(t, input, "empty list can't be evaluated")
+		}
+	})
+
+	t.Run("apply of not callable value", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(1 2)
+		`, "unexpected apply argument integer 1")
+	})
+
+	t.Run("set of not hashable values", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(hash-set [1])
+		`, "can't be used as a set element")
+	})
+
+	t.Run("map lookup of not a map", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(:a 1)
+		`, "keyword lookup want a map")
+	})
+
+	t.Run("import-go", func(t *testing.T) {
+		testASTWalk(t, `
+			(import-go "strings" (str strings) math)
+			[(strings.ToUpper "abc") (str.Split "a,b" ",") (math.Sqrt 4) math.MaxInt]
+		`, object.NewArray(
+			object.PrimitiveOf(`"ABC"`),
+			object.NewArray(object.PrimitiveOf(`"a"`), object.PrimitiveOf(`"b"`)),
+			object.PrimitiveOf(2.0),
+			object.PrimitiveOf[int64](math.MaxInt),
+		))
+	})
+
+	t.Run("import-go in fn", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn f []
+				(import-go "strings")
+				(strings.ToUpper "a"))
+			(defn g [x]
+				(try (import-go "strings"))
+				((fn [] (strings.Repeat x 2))))
+			[(f) (g "b")]
+		`, object.NewArray(
+			object.PrimitiveOf(`"A"`),
+			object.PrimitiveOf(`"bb"`),
+		))
+	})
+
+	t.Run("import-go errors", func(t *testing.T) {
+		testRuntimeError(t, read(t, `
+			(import-go strconv)
+			(strconv.Atoi "x")
+		`), func(t *testing.T, err *object.RuntimeError) {
+			require.Equal(t, object.ErrGo, err.Kind, "kind of %v", err)
+			require.Equal(t, `strconv.Atoi: parsing "x": invalid syntax`, err.Error())
+		})
+	})
+
+	t.Run("import-go panics", func(t *testing.T) {
+		testASTWalk(t, `
+			(import-go strings)
+			(array
+				(try (strings.Repeat "a" -1) (catch :go-error e (ex-message e)))
+				(try (.Grow (strings.Builder) -1) (catch :default e (ex-kind e))))
+		`, object.NewArray(
+			object.PrimitiveOf(`"strings.Repeat: panic: strings: negative Repeat count"`),
+			object.Keyword(":go-error"),
+		))
+	})
+
+	t.Run("import-go type conversion", func(t *testing.T) {
+		testASTWalk(t, `
+			(import-go time)
+			[(time.Duration 5) (time.Duration) (time.ParseDuration "1s")]
+		`, object.NewArray(
+			&object.GoValue{Value: reflect.ValueOf(time.Duration(5))},
+			&object.GoValue{Value: reflect.ValueOf(time.Duration(0))},
+			&object.GoValue{Value: reflect.ValueOf(time.Second)},
+		))
+	})
+
+	t.Run("import-go methods of named numbers", func(t *testing.T) {
+		testASTWalk(t, `
+			(import-go time)
+			[(.Seconds (time.ParseDuration "1s"))
+			 (.String (time.Duration 5))
+			 (from-go (time.Duration 5))
+			 (time.Duration time.Minute)]
+		`, object.NewArray(
+			object.PrimitiveOf(1.0),
+			object.PrimitiveOf(`"5ns"`),
+			object.PrimitiveOf[int64](5),
+			&object.GoValue{Value: reflect.ValueOf(time.Minute)},
+		))
+	})
+
+	t.Run("chained dot selector", func(t *testing.T) {
+		testASTWalk(t, `
+			(assign ns (namespace
+				sub (namespace value 42)))
+			ns.sub.value
+		`, object.PrimitiveOf[int64](42))
+	})
+
+	t.Run("dot selector of a missing member", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(import-go strings)
+			strings.NoSuchFunc
+		`, "namespace strings has no member NoSuchFunc")
+	})
+
+	t.Run("go method call", func(t *testing.T) {
+		testASTWalk(t, `
+			(import-go strings)
+			(let (buf (strings.Builder))
+				(.WriteString buf "abc")
+				(.WriteString buf "de")
+				[(.Len buf) (.String buf)])
+		`, object.NewArray(
+			object.PrimitiveOf[int64](5),
+			object.PrimitiveOf(`"abcde"`),
+		))
+	})
+
+	t.Run("method call of a value without methods", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(.Len "abc")
+		`, `.Len: string "\"abc\"" has no methods`)
+	})
+
+	t.Run("go method call of a missing method", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(import-go strings)
+			(.Nope (strings.Builder))
+		`, "Go *strings.Builder has no method Nope")
+	})
+
+	t.Run("go struct fields and methods", func(t *testing.T) {
+		testASTWalk(t, `
+			(import-go (geo "example.com/geo"))
+			(let (p (geo.NewPoint 3 4)
+				  dist p.Dist)
+				[p.X p.Y (.Dist p) (dist) ((geo.Point) :X)])
+		`, object.NewArray(
+			object.PrimitiveOf[int64](3),
+			object.PrimitiveOf[int64](4),
+			object.PrimitiveOf(5.0),
+			object.PrimitiveOf(5.0),
+			object.PrimitiveOf[int64](0),
+		))
+	})
+
+	t.Run("go value indexing", func(t *testing.T) {
+		testASTWalk(t, `
+			(import-go (geo "example.com/geo"))
+			(let (points (geo.Points 2)
+				  nums (go-value [1 2 3])
+				  m (go-value {:a 1}))
+				[((points 1) :X) (nums 2) (m ":a") (m ":b") (from-go nums)])
+		`, object.NewArray(
+			object.PrimitiveOf[int64](1),
+			object.PrimitiveOf[int64](3),
+			object.PrimitiveOf[int64](1),
+			object.Null{},
+			object.NewArray(
+				object.PrimitiveOf[int64](1),
+				object.PrimitiveOf[int64](2),
+				object.PrimitiveOf[int64](3),
+			),
+		))
+	})
+
+	t.Run("go error result", func(t *testing.T) {
+		pkg := read(t, `
+			(import-go strconv)
+			(strconv.ParseInt "x" 10 64)
+		`)
+
+		forEachBackend(t, func(t *testing.T, backend object.Eval) {
+			got, _ := eval(pkg, backend)
+
+			err, isErr := got.(*object.Error)
+			require.True(t, isErr, "want an error, got %s", got.Inspect())
+
+			var numErr *strconv.NumError
+			require.ErrorAs(t, err.Err, &numErr)
+			require.ErrorIs(t, err.Err, strconv.ErrSyntax)
+		})
+	})
+
+	t.Run("import-go unknown package", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(import-go "net/http")
+		`, `go package "net/http" is not registered`)
+	})
+
+	t.Run("vector bindings", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn f [a & rest]
+				(let [[b c] rest] (+ a b c)))
+			(let [x 1 [y & z] [2 3]] (f x y (z 0)))
+		`, object.PrimitiveOf[int64](6))
 	})
 
 	t.Run("empty array", func(t *testing.T) {
@@ -75,20 +543,1207 @@ func TestASTWalk(t *testing.T) {
 			(if false 1)
 		`, object.Null{})
 	})
+
+	t.Run("-", func(t *testing.T) {
+		testASTWalk(t, `
+			(- 5 3)
+		`, object.PrimitiveOf[int64](2))
+	})
+
+	t.Run("negate", func(t *testing.T) {
+		testASTWalk(t, `
+			(- (+ 1 2.5))
+		`, object.PrimitiveOf(-3.5))
+	})
+
+	t.Run("/", func(t *testing.T) {
+		testASTWalk(t, `
+			(/ 7 2)
+		`, object.PrimitiveOf[int64](3))
+	})
+
+	t.Run("float /", func(t *testing.T) {
+		testASTWalk(t, `
+			(/ 7 2.0)
+		`, object.PrimitiveOf(3.5))
+	})
+
+	t.Run("/ by zero", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(/ 7 0)
+		`, "division by zero")
+	})
+
+	t.Run("mod", func(t *testing.T) {
+		testASTWalk(t, `
+			(mod -7 2)
+		`, object.PrimitiveOf[int64](1))
+	})
+
+	t.Run("rem", func(t *testing.T) {
+		testASTWalk(t, `
+			(rem -7 2)
+		`, object.PrimitiveOf[int64](-1))
+	})
+
+	t.Run("type-of arithmetic", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of (/ (- 5 3) 2.0))
+		`, object.TypeFor(object.ObjFloat64))
+	})
+
+	t.Run("do", func(t *testing.T) {
+		testASTWalk(t, `
+			(do
+				(assign a 1)
+				(+ a 1))
+		`, object.PrimitiveOf[int64](2))
+	})
+
+	t.Run("let", func(t *testing.T) {
+		testASTWalk(t, `
+			(let (a 1
+				  b (+ a 1))
+				(* a b 10))
+		`, object.PrimitiveOf[int64](20))
+	})
+
+	t.Run("let destructuring", func(t *testing.T) {
+		testASTWalk(t, `
+			(let ((a (b c) & rest) (array 1 (array 2 3) 4 5))
+				(array a b c rest))
+		`, object.NewArray(
+			object.PrimitiveOf[int64](1),
+			object.PrimitiveOf[int64](2),
+			object.PrimitiveOf[int64](3),
+			object.NewArray(
+				object.PrimitiveOf[int64](4),
+				object.PrimitiveOf[int64](5),
+			),
+		))
+	})
+
+	t.Run("strict mode", func(t *testing.T) {
+		file := t.Name()
+		pkg := read(t, `
+			(let (count 1)
+				(+ coutn 2))`)
+
+		forEachBackend(t, func(t *testing.T, backend object.Eval) {
+			env := astwalk.DefaultEnv().SetStrict(true)
+
+			got := backend(pkg, env.Child())
+
+			err, isErr := got.(*object.Error)
+			require.True(t, isErr, "want an error, got %s", got.Inspect())
+
+			var runtime *object.RuntimeError
+			require.ErrorAs(t, err.Err, &runtime)
+			require.Equal(t, object.ErrUndefined, runtime.Kind, "kind of %v", runtime)
+			require.Equal(t, tokens.Position{File: file, Line: 2, Column: 7}, runtime.Pos.From, "error position")
+
+			var undefined *object.UndefinedError
+			require.ErrorAs(t, err.Err, &undefined)
+			require.Equal(t, "coutn", undefined.Name)
+			require.Equal(t, []string{"count"}, undefined.Suggestions)
+			require.ErrorContains(t, err.Err, "undefined symbol coutn, did you mean count?")
+		})
+	})
+
+	t.Run("strict mode null", func(t *testing.T) {
+		pkg := read(t, `(array null (try (undefined) (catch :undefined-symbol e (ex-kind e))))`)
+
+		forEachBackend(t, func(t *testing.T, backend object.Eval) {
+			got := backend(pkg, astwalk.DefaultEnv().SetStrict(true))
+
+			assertEq(t, object.NewArray(
+				object.Null{}, object.Keyword(":undefined-symbol"),
+			), got, "evaluation result")
+		})
+	})
+
+	t.Run("recur in arguments without expansion", func(t *testing.T) {
+		// the parser skips arguments of calls, which can be macro calls
+		pkg := read(t, `(loop (i 0) (array (recur 1)))`)
+
+		forEachBackend(t, func(t *testing.T, backend object.Eval) {
+			got := backend(pkg, astwalk.DefaultEnv())
+
+			err, isErr := got.(*object.Error)
+			require.True(t, isErr, "want an error, got %s", got.Inspect())
+			require.ErrorContains(t, err.Err, "recur can be used only in a tail position")
+		})
+	})
+
+	t.Run("let doesn't leak", func(t *testing.T) {
+		testASTWalk(t, `
+			(assign a 1)
+			(let (a 2)
+				(assign b 3))
+			(array a b)
+		`, object.NewArray(
+			object.PrimitiveOf[int64](1),
+			object.Null{},
+		))
+	})
+
+	t.Run("type-of let", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of (let (a 1.5) (+ a 1)))
+		`, object.TypeFor(object.ObjFloat64))
+	})
+
+	t.Run("type-of fn", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of (fn [f x] (f (f x))))
+		`, object.FuncType(object.VarType("a"),
+			*object.FuncType(object.VarType("a"), *object.VarType("a")),
+			*object.VarType("a")))
+	})
+
+	t.Run("type-of variadic fn", func(t *testing.T) {
+		number := object.UnionType(object.TypeFor(object.ObjInteger), object.TypeFor(object.ObjFloat64))
+		want := object.FuncType(number,
+			*number,
+			object.Type{ObjKind: object.ObjArray, Params: []object.Type{*object.VarType("a")}})
+		want.Variadic = true
+
+		testASTWalk(t, `
+			(type-of (fn [x & rest] (+ x 1)))
+		`, want)
+	})
+
+	t.Run("type-of rest arguments", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn f [& xs] xs)
+			(type-of (f 1 2))
+		`, object.TypeFor(object.ObjArray, object.ObjInteger))
+
+		testASTWalk(t, `
+			(defn f [& xs] xs)
+			(type-of (f 1 "a"))
+		`, object.TypeFor(object.ObjArray, object.ObjAny))
+
+		pkg := read(t, `
+			(defn f (& xs) xs)
+			(f 1 "a")
+		`)
+		require.NoError(t, astwalk.NewTypeInferencer(astwalk.DefaultEnv()).Check(pkg))
+	})
+
+	t.Run("type-of recursive defn", func(t *testing.T) {
+		number := object.UnionType(object.TypeFor(object.ObjInteger), object.TypeFor(object.ObjFloat64))
+
+		testASTWalk(t, `
+			(type-of
+				(defn fact [n]
+					(if (< n 2) 1 (* n (fact (- n 1))))))
+		`, object.FuncType(number, *number))
+	})
+
+	t.Run("type-of arithmetic of parameters", func(t *testing.T) {
+		number := object.UnionType(object.TypeFor(object.ObjInteger), object.TypeFor(object.ObjFloat64))
+
+		// integer literals don't force parameters to be integers
+		pkg := read(t, `
+			(defn f (x) (+ x 1))
+			(f 1.5)
+		`)
+		require.NoError(t, astwalk.NewTypeInferencer(astwalk.DefaultEnv()).Check(pkg))
+		testEval(t, pkg, object.PrimitiveOf(2.5))
+
+		testASTWalk(t, `
+			(type-of (fn [x] (* (+ x 1) 2.0)))
+		`, object.FuncType(object.TypeFor(object.ObjFloat64), *number))
+	})
+
+	t.Run("type-of call", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn half [x] (/ x 2.0))
+			(type-of (half 1.5))
+		`, object.TypeFor(object.ObjFloat64))
+	})
+
+	t.Run("type-of let generalization", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of
+				(let (id (fn [x] x))
+					(if (id true) (id "a") "b")))
+		`, object.TypeFor(object.ObjString))
+	})
+
+	t.Run("type-of assign generalization", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of
+				(do (assign id (fn [x] x))
+					[(id 1) (id 2)]
+					(id :a)))
+		`, object.TypeFor(object.ObjKeyword))
+	})
+
+	t.Run("type-of return and recur", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of
+				(fn [n]
+					(loop (i 0)
+						(if (< i n)
+							(recur (+ i 1))
+							(return "done")))))
+		`, object.FuncType(object.TypeFor(object.ObjString), *object.VarType("a")))
+	})
+
+	t.Run("type-of arithmetic of ast", func(t *testing.T) {
+		file := t.Name()
+
+		testRuntimeError(t, read(t, `(type-of (+ '1 2))`), func(t *testing.T, err *object.RuntimeError) {
+			require.Equal(t, object.ErrType, err.Kind, "kind of %v", err)
+			require.ErrorContains(t, err, "+ argument 0 is not a number: want (or integer float64)")
+
+			var mismatch *astwalk.MismatchError
+			require.ErrorAs(t, err, &mismatch)
+
+			assertEq(t, object.TypeFor(object.ObjAST), mismatch.Got, "got type")
+			require.Equal(t, tokens.Position{File: file, Column: 9}, mismatch.WantPos.From, "operator position")
+			require.Equal(t, tokens.Position{File: file, Column: 12}, mismatch.GotPos.From, "operand position")
+		})
+	})
+
+	t.Run("type-of mismatch", func(t *testing.T) {
+		file := t.Name()
+
+		testRuntimeError(t, read(t, `(type-of (let (x :- integer "a") x))`), func(t *testing.T, err *object.RuntimeError) {
+			require.Equal(t, object.ErrType, err.Kind, "kind of %v", err)
+			require.ErrorContains(t, err, "let binding x :- integer")
+
+			var mismatch *astwalk.MismatchError
+			require.ErrorAs(t, err, &mismatch)
+
+			assertEq(t, object.TypeFor(object.ObjInteger), mismatch.Want, "want type")
+			assertEq(t, object.TypeFor(object.ObjString), mismatch.Got, "got type")
+			require.Equal(t, tokens.Position{File: file, Column: 20}, mismatch.WantPos.From, "want position")
+			require.Equal(t, tokens.Position{File: file, Column: 28}, mismatch.GotPos.From, "got position")
+			require.Equal(t, mismatch.GotPos, err.Pos, "error position")
+		})
+	})
+
+	t.Run("type-of if branches", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of (if true 1 "a"))
+		`, object.UnionType(object.TypeFor(object.ObjInteger), object.TypeFor(object.ObjString)))
+	})
+
+	t.Run("type-of if without else", func(t *testing.T) {
+		nullable := object.NullableType(object.TypeFor(object.ObjInteger))
+		require.Equal(t, "(type (or integer null))", nullable.Inspect())
+
+		testASTWalk(t, `
+			(type-of (if true 1))
+		`, nullable)
+	})
+
+	t.Run("type-of if with null branch", func(t *testing.T) {
+		// a null branch makes the other one nullable, it doesn't bind types to null
+		testASTWalk(t, `
+			(type-of (fn [x] (if (< x 0) null x)))
+		`, object.FuncType(object.NullableType(object.VarType("a")), *object.VarType("a")))
+
+		pkg := read(t, `
+			(defn f (x) (if (< x 0) null x))
+			(f 1)
+			(if false (f 2) "a")
+		`)
+		require.NoError(t, astwalk.NewTypeInferencer(astwalk.DefaultEnv()).Check(pkg))
+	})
+
+	t.Run("type-of unbound name", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of (fn [] unbound))
+		`, object.FuncType(object.NullableType(object.VarType("a"))))
+	})
+
+	t.Run("type-of nullable argument", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(type-of (fn [x] (+ (if x 1) 2)))
+		`, "got (or integer null) at")
+	})
+
+	t.Run("type-of union annotation", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of (fn [x :- (or integer null)] x))
+		`, object.FuncType(object.NullableType(object.TypeFor(object.ObjInteger)),
+			*object.NullableType(object.TypeFor(object.ObjInteger))))
+
+		testASTWalkErr(t, `
+			(type-of (let (x :- integer (if true 1)) x))
+		`, "let binding x :- integer: want integer at")
+	})
+
+	t.Run("union types", func(t *testing.T) {
+		var (
+			integer = object.TypeFor(object.ObjInteger)
+			str     = object.TypeFor(object.ObjString)
+			null    = object.TypeFor(object.ObjNull)
+		)
+
+		union := object.UnionType(integer, object.UnionType(str, integer), null)
+		require.Equal(t, "(or integer string null)", union.String())
+		require.True(t, union.Equal(object.UnionType(null, str, integer)), "unions are equal in any order")
+		require.True(t, union.IsNullable())
+		require.False(t, integer.IsNullable())
+		assertEq(t, integer, object.UnionType(integer, integer), "union of a type")
+		assertEq(t, object.TypeFor(object.ObjAny), object.UnionType(integer, object.TypeFor(object.ObjAny)), "union with any")
+
+		require.True(t, integer.IsSubtype(union))
+		require.True(t, object.NullableType(str).IsSubtype(union))
+		require.False(t, union.IsSubtype(integer))
+		require.False(t, union.IsSubtype(object.NullableType(integer)))
+
+		takesInteger := object.FuncType(null, *integer)
+		takesNullable := object.FuncType(null, *object.NullableType(integer))
+		require.True(t, takesNullable.IsSubtype(takesInteger), "parameters are contravariant")
+		require.False(t, takesInteger.IsSubtype(takesNullable), "parameters are contravariant")
+	})
+
+	t.Run("type-of argument mismatch", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(type-of
+				(let (inc (fn [x] (+ x 1)))
+					(inc "a")))
+		`, `argument 0 of inc: want (or integer float64) at`)
+	})
+
+	t.Run("type-of infinite type", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(type-of (fn [x] (x x)))
+		`, "infinite type")
+	})
+
+	t.Run("type-of annotated fn", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of (fn [f :- (fn [integer] string) x :- any] (f x)))
+		`, object.FuncType(object.TypeFor(object.ObjString),
+			*object.FuncType(object.TypeFor(object.ObjString), *object.TypeFor(object.ObjInteger)),
+			*object.TypeFor(object.ObjAny)))
+	})
+
+	t.Run("type-of annotated let", func(t *testing.T) {
+		testASTWalk(t, `
+			(type-of (let (x :- any 1) x))
+		`, object.TypeFor(object.ObjAny))
+	})
+
+	t.Run("type-of annotation mismatch", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(type-of (let (x :- string 1) x))
+		`, "let binding x :- string: want string at")
+	})
+
+	t.Run("annotated assign", func(t *testing.T) {
+		testASTWalk(t, `
+			(assign x :- integer 1)
+			(type-of x)
+		`, object.TypeFor(object.ObjInteger))
+
+		testASTWalkErr(t, `
+			(type-of (assign x :- integer "s"))
+		`, "assign x :- integer: want integer at")
+	})
+
+	t.Run("type-of unknown annotation", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(type-of (fn [x :- int] x))
+		`, "parameter x: unknown type int")
+	})
+
+	t.Run("annotations are ignored by evaluation", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn add [x :- integer & rest :- (array integer)]
+				(let ((y :- integer) rest)
+					(+ x y)))
+			(add 1 2)
+		`, object.PrimitiveOf[int64](3))
+	})
+
+	t.Run("check package", func(t *testing.T) {
+		pkg := read(t, `
+			(defn inc [x :- integer] (+ x 1))
+			(assign n (inc 1))
+			(inc "a")
+			(if n 1 2)
+		`)
+
+		err := astwalk.NewTypeInferencer(astwalk.DefaultEnv()).Check(pkg)
+		require.ErrorContains(t, err, "argument 0 of inc: want integer")
+		require.NotContains(t, err.Error(), "if condition")
+
+		err = astwalk.NewTypeInferencer(astwalk.DefaultEnv()).ReportAll().Check(pkg)
+
+		joined, ok := err.(interface{ Unwrap() []error })
+		require.True(t, ok, "want joined errors, got %v", err)
+
+		errs := joined.Unwrap()
+		require.Len(t, errs, 2, "errors %v", err)
+		require.ErrorContains(t, errs[0], "argument 0 of inc")
+		require.ErrorContains(t, errs[1], "if condition is not boolean")
+
+		var runtime *object.RuntimeError
+		require.ErrorAs(t, errs[1], &runtime)
+		require.Equal(t, 4, runtime.Pos.From.Line, "error line")
+	})
+
+	t.Run("eval with inference", func(t *testing.T) {
+		result := astwalk.EvalWithInference(read(t, `
+			(defn twice [x :- float64] (* x 2))
+			(twice 1.5)
+		`), astwalk.DefaultEnv())
+		assertEq(t, object.PrimitiveOf(3.0), result, "result")
+
+		result = astwalk.EvalWithInference(read(t, `
+			(defn twice [x :- float64] (* x 2))
+			(twice "a")
+		`), astwalk.DefaultEnv())
+		err, isErr := result.(*object.Error)
+		require.True(t, isErr, "want an error, got %s", result.Inspect())
+		require.ErrorContains(t, err.Err, "argument 0 of twice: want float64")
+	})
+
+	t.Run("while", func(t *testing.T) {
+		testASTWalk(t, `
+			(assign i 0 acc 0)
+			(while (< i 5)
+				(assign acc (+ acc i))
+				(assign i (+ i 1)))
+			acc
+		`, object.PrimitiveOf[int64](10))
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		testASTWalk(t, `
+			(loop (i 0 acc 1)
+				(if (< i 10)
+					(recur (+ i 1) (* acc 2))
+					acc))
+		`, object.PrimitiveOf[int64](1024))
+	})
+
+	t.Run("long loop", func(t *testing.T) {
+		testASTWalk(t, `
+			(loop (i 0)
+				(if (< i 100000)
+					(recur (+ i 1))
+					i))
+		`, object.PrimitiveOf[int64](100000))
+	})
+
+	t.Run("fn recur", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn count-down (n acc)
+				(if (= n 0)
+					acc
+					(let (next (- n 1))
+						(recur next (+ acc 1)))))
+			(count-down 100000 0)
+		`, object.PrimitiveOf[int64](100000))
+	})
+
+	t.Run("quote", func(t *testing.T) {
+		testASTWalk(t, `
+			'(if a (+ b 1))
+		`, &object.AST{Node: ast.NewSexp(
+			&ast.Symbol{Value: "if"},
+			&ast.Symbol{Value: "a"},
+			ast.NewSexp(&ast.Symbol{Value: "+"}, &ast.Symbol{Value: "b"}, &ast.Literal[int64]{Value: 1}),
+		)})
+	})
+
+	t.Run("quasiquote", func(t *testing.T) {
+		testASTWalk(t, `
+			(let (a 1
+				  b (array 2 3)
+				  c '(x y))
+				`+"`"+`(f ~a ~(+ a 1) ~@b ~@c ~b))
+		`, &object.AST{Node: ast.NewSexp(
+			&ast.Symbol{Value: "f"},
+			&ast.Literal[int64]{Value: 1},
+			&ast.Literal[int64]{Value: 2},
+			&ast.Literal[int64]{Value: 2},
+			&ast.Literal[int64]{Value: 3},
+			&ast.Symbol{Value: "x"},
+			&ast.Symbol{Value: "y"},
+			&ast.Vector{Items: []ast.Node{&ast.Literal[int64]{Value: 2}, &ast.Literal[int64]{Value: 3}}},
+		)})
+	})
+
+	t.Run("unquote outside of quasiquote", func(t *testing.T) {
+		testASTWalkErr(t, `
+			~a
+		`, "unquote outside of quasiquote")
+	})
+
+	t.Run("defmacro", func(t *testing.T) {
+		testASTWalk(t, `
+			(defmacro unless (cond & body)
+				`+"`"+`(if ~cond null (do ~@body)))
+			(unless (> 1 2) 1 2)
+		`, object.PrimitiveOf[int64](2))
+	})
+
+	t.Run("macro arguments are not evaluated", func(t *testing.T) {
+		testASTWalk(t, `
+			(defmacro unless (cond & body)
+				`+"`"+`(if ~cond null (do ~@body)))
+			(unless true (/ 1 0))
+		`, object.Null{})
+	})
+
+	t.Run("macro with recur", func(t *testing.T) {
+		testASTWalk(t, `
+			(defmacro unless (cond & body)
+				`+"`"+`(if ~cond null (do ~@body)))
+			(loop (i 0)
+				(unless (> i 9)
+					(recur (+ i 1))))
+		`, object.Null{})
+	})
+
+	t.Run("macro hygiene", func(t *testing.T) {
+		testASTWalk(t, `
+			(defmacro add-one (x)
+				`+"`"+`(let (n# 1) (+ n# ~x)))
+			(let (n 41)
+				(add-one n))
+		`, object.PrimitiveOf[int64](42))
+	})
+
+	t.Run("macroexpand-1", func(t *testing.T) {
+		testASTWalk(t, `
+			(defmacro unless (cond & body)
+				`+"`"+`(if ~cond null (do ~@body)))
+			(defmacro unless-not (cond & body)
+				`+"`"+`(unless (not ~cond) ~@body))
+			(array
+				(macroexpand-1 '(unless-not a b))
+				(macroexpand '(unless-not a b)))
+		`, object.NewArray(
+			&object.AST{Node: ast.NewSexp(
+				&ast.Symbol{Value: "unless"},
+				ast.NewSexp(&ast.Symbol{Value: "not"}, &ast.Symbol{Value: "a"}),
+				&ast.Symbol{Value: "b"},
+			)},
+			&object.AST{Node: ast.NewSexp(
+				&ast.Symbol{Value: "if"},
+				ast.NewSexp(&ast.Symbol{Value: "not"}, &ast.Symbol{Value: "a"}),
+				&ast.Symbol{Value: "null"},
+				ast.NewSexp(&ast.Symbol{Value: "do"}, &ast.Symbol{Value: "b"}),
+			)},
+		))
+	})
+
+	t.Run("fn", func(t *testing.T) {
+		testASTWalk(t, `
+			((fn (x y) (* x y)) 3 4)
+		`, object.PrimitiveOf[int64](12))
+	})
+
+	t.Run("defn", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn double (x) (+ x x))
+			(double 21)
+		`, object.PrimitiveOf[int64](42))
+	})
+
+	t.Run("closure", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn adder (x)
+				(fn (y) (+ x y)))
+			(assign add2 (adder 2))
+			(add2 40)
+		`, object.PrimitiveOf[int64](42))
+	})
+
+	t.Run("closures capture loop iterations", func(t *testing.T) {
+		// every iteration binds loop and let locals anew
+		testASTWalk(t, `
+			(assign fs (loop (i 0 acc [])
+				(if (< i 3)
+					(let (j (* i 10))
+						(recur (+ i 1) [(fn () (+ i j)) acc]))
+					acc)))
+			(defn g (n acc)
+				(if (< n 2) (recur (+ n 1) [(fn () n) acc]) acc))
+			[((fs 0)) (((fs 1) 0)) (((g 0 []) 0))]
+		`, object.NewArray(
+			object.PrimitiveOf[int64](22),
+			object.PrimitiveOf[int64](11),
+			object.PrimitiveOf[int64](1),
+		))
+	})
+
+	t.Run("assignments in forms without bytecode", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn f [x] (try (assign x 2)) x)
+			(defn g [x] (array (assign x 2)) x)
+			(defn h [] (try (assign y 3)) y)
+			(defn k [x]
+				(let (get (fn [] x))
+					(try (assign x 4))
+					(get)))
+			[(f 1) (g 1) (h) (k 1)]
+		`, object.NewArray(
+			object.PrimitiveOf[int64](2),
+			object.PrimitiveOf[int64](2),
+			object.PrimitiveOf[int64](3),
+			object.PrimitiveOf[int64](4),
+		))
+	})
+
+	t.Run("closures see assignments", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn f (x)
+				(let (g (fn () x))
+					(assign x 5)
+					(g)))
+			(defn h (x)
+				(let (g (fn () x))
+					(while (< x 3) (assign x (+ x 1)))
+					(g)))
+			[(f 1) (h 0)]
+		`, object.NewArray(
+			object.PrimitiveOf[int64](5),
+			object.PrimitiveOf[int64](3),
+		))
+	})
+
+	t.Run("fn rest", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn tail (x & rest) rest)
+			(tail 1 2 3)
+		`, object.NewArray(
+			object.PrimitiveOf[int64](2),
+			object.PrimitiveOf[int64](3),
+		))
+	})
+
+	t.Run("fn arity", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(defn double (x) (+ x x))
+			(double 1 2)
+		`, "double expects 1 arguments, got 2")
+	})
+
+	t.Run("require", func(t *testing.T) {
+		testEval(t, readModule(t, `
+			(require "math")
+			(array (math.square 3) (math answer))
+		`), object.NewArray(
+			object.PrimitiveOf[int64](9),
+			object.PrimitiveOf[int64](42),
+		))
+	})
+
+	t.Run("require in fn", func(t *testing.T) {
+		testEval(t, readModule(t, `
+			(defn f [x]
+				(require "math")
+				(math.square x))
+			(f 3)
+		`), object.PrimitiveOf[int64](9))
+	})
+
+	t.Run("require only", func(t *testing.T) {
+		testEval(t, readModule(t, `
+			(require "math" :only [square])
+			(array (square 4) answer)
+		`), object.NewArray(
+			object.PrimitiveOf[int64](16),
+			object.Null{},
+		))
+	})
+
+	t.Run("require missing member", func(t *testing.T) {
+		testEvalErr(t, readModule(t, `
+			(require "math" :only [cube])
+		`), "has no member cube")
+	})
+
+	t.Run("require relative paths", func(t *testing.T) {
+		testEval(t, readModule(t, `
+			(require "nested.sl" :only [greet])
+			(greet "hi")
+		`), object.PrimitiveOf(`"HI"`))
+	})
+
+	t.Run("require evaluates a module once", func(t *testing.T) {
+		testEval(t, readModule(t, `
+			(require "counted")
+			(assign first (counted n))
+			(require "counted")
+			(= first counted.n)
+		`), object.PrimitiveOf(true))
+	})
+
+	t.Run("require macros", func(t *testing.T) {
+		testEval(t, readModule(t, `
+			(require "macros" :only [unless])
+			(unless false 1)
+		`), object.PrimitiveOf[int64](1))
+	})
+
+	t.Run("require cycle", func(t *testing.T) {
+		testEvalErr(t, readModule(t, `
+			(require "cycle_a")
+		`), "import cycle:\n\t"+
+			filepath.Join(modulesDir(t), "cycle_a.sl")+":1:2: requires "+filepath.Join(modulesDir(t), "cycle_b.sl")+"\n\t"+
+			filepath.Join(modulesDir(t), "cycle_b.sl")+":1:2: requires "+filepath.Join(modulesDir(t), "cycle_a.sl"))
+	})
+
+	t.Run("require missing file", func(t *testing.T) {
+		testEvalErr(t, readModule(t, `
+			(require "missing")
+		`), "no such file or directory")
+	})
+
+	t.Run("load", func(t *testing.T) {
+		testEval(t, readModule(t, `
+			(array (load "prelude") loaded)
+		`), object.NewArray(
+			object.PrimitiveOf[int64](2),
+			object.PrimitiveOf[int64](1),
+		))
+	})
+
+	t.Run("runtime error", func(t *testing.T) {
+		pkg := read(t, "(defn divide [x y] (/ x y))\n"+
+			"(defn half [x] (divide x 0))\n"+
+			"(+ 1 (half 10))")
+
+		testRuntimeError(t, pkg, func(t *testing.T, err *object.RuntimeError) {
+			require.Equal(t, object.ErrArithmetic, err.Kind, "kind")
+			require.True(t, strings.HasSuffix(err.Error(), "/: division by zero"), "message %q", err)
+			require.NotContains(t, err.Error(), "TestASTWalk", "message must not contain positions")
+			require.Equal(t, 0, err.Pos.From.Line, "line")
+			require.Equal(t, 19, err.Pos.From.Column, "column")
+
+			require.Len(t, err.Stack, 2, "stack")
+			require.Equal(t, "divide", err.Stack[0].Name)
+			require.Equal(t, 1, err.Stack[0].Pos.From.Line)
+			require.Equal(t, "half", err.Stack[1].Name)
+			require.Equal(t, 2, err.Stack[1].Pos.From.Line)
+		})
+	})
+
+	t.Run("runtime error of recursion", func(t *testing.T) {
+		// contexts of arguments aren't repeated by every call
+		pkg := read(t, `
+			(defn f [n] (if (= n 0) (/ 1 0) (+ 1 (array (f (- n 1))))))
+			(f 40)
+		`)
+
+		testRuntimeError(t, pkg, func(t *testing.T, err *object.RuntimeError) {
+			require.Equal(t, "/: division by zero", err.Error())
+			require.Len(t, err.Stack, 41, "stack")
+		})
+	})
+
+	t.Run("runtime error of deep recursion", func(t *testing.T) {
+		pkg := read(t, `
+			(defn f [n] (if (= n 0) (/ 1 0) (+ 1 (f (- n 1)))))
+			(f 20000)
+		`)
+
+		testRuntimeError(t, pkg, func(t *testing.T, err *object.RuntimeError) {
+			require.Len(t, err.Stack, 20001, "stack")
+		})
+	})
+
+	t.Run("runtime error rethrown twice", func(t *testing.T) {
+		pkg := read(t, `
+			(defn fail [] (/ 1 0))
+			(assign e (try (fail) (catch :default e e)))
+			(defn a [] (throw e))
+			(defn b [] (a))
+			(try (b) (catch :default x null))
+			(defn c [] (throw e))
+			(c)
+		`)
+
+		testRuntimeError(t, pkg, func(t *testing.T, err *object.RuntimeError) {
+			names := []string{}
+			for _, frame := range err.Stack {
+				names = append(names, frame.Name)
+			}
+			require.Equal(t, []string{"fail", "c"}, names, "stack")
+		})
+	})
+
+	t.Run("runtime error kinds", func(t *testing.T) {
+		for input, kind := range map[string]object.ErrorKind{
+			`(+ 1 "a")`:           object.ErrType,
+			`(if 1 2 3)`:          object.ErrType,
+			`((fn [x] x) 1 2)`:    object.ErrArity,
+			`([1 2] 5)`:           object.ErrIndex,
+			`(require "missing")`: object.ErrImport,
+			`(* 2 (mod 1 0))`:     object.ErrArithmetic,
+			`(< 1 (array 2))`:     object.ErrType,
+			`(let [[a] 1] a)`:     object.ErrType,
+		} {
+			t.Run(input, func(t *testing.T) {
+				testRuntimeError(t, read(t, input), func(t *testing.T, err *object.RuntimeError) {
+					require.Equal(t, kind, err.Kind, "kind of %v", err)
+				})
+			})
+		}
+	})
+
+	t.Run("runtime error pretty", func(t *testing.T) {
+		err := &object.RuntimeError{
+			Kind: object.ErrArithmetic,
+			Pos:  posRange("main.sl", 1, 2, 1, 9),
+			Err:  errors.New("/: division by zero"),
+			Stack: []object.StackFrame{
+				{Name: "divide", Pos: posRange("main.sl", 3, 0, 3, 12)},
+				{Pos: posRange("main.sl", 4, 0, 4, 5)},
+			},
+		}
+
+		source := "(defn divide [x y]\n\t (/ x y))\n\n(divide 1 0)\n"
+
+		require.Equal(t, "main.sl:2:3: arithmetic-error: /: division by zero\n"+
+			"  \t (/ x y))\n"+
+			"  \t ^^^^^^^\n"+
+			"  in divide called at main.sl:4:1\n"+
+			"  in anonymous function called at main.sl:5:1\n",
+			err.Pretty([]byte(source)))
+
+		require.Equal(t, "main.sl:2:3: arithmetic-error: /: division by zero\n"+
+			"  in divide called at main.sl:4:1\n"+
+			"  in anonymous function called at main.sl:5:1\n",
+			err.Pretty(nil), "without source")
+	})
+
+	t.Run("try catch kind", func(t *testing.T) {
+		testASTWalk(t, `
+			(try
+				(/ 1 0)
+				(catch :type-error e 1)
+				(catch :arithmetic-error e (ex-message e)))
+		`, object.PrimitiveOf(`"/: division by zero"`))
+	})
+
+	t.Run("try without errors", func(t *testing.T) {
+		testASTWalk(t, `
+			(try
+				(+ 1 2)
+				(catch :default e 0))
+		`, object.PrimitiveOf[int64](3))
+	})
+
+	t.Run("try catch predicate", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn big? [x] (> x 10))
+			(try
+				(throw 42)
+				(catch (fn [x] (< x 10)) x :small)
+				(catch big? x (+ x 1)))
+		`, object.PrimitiveOf[int64](43))
+	})
+
+	t.Run("try catch from function", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn divide [x y] (/ x y))
+			(defn safe-divide [x y]
+				(try (divide x y)
+					(catch :arithmetic-error e 0)))
+			(array (safe-divide 6 2) (safe-divide 1 0))
+		`, object.NewArray(
+			object.PrimitiveOf[int64](3),
+			object.PrimitiveOf[int64](0),
+		))
+	})
+
+	t.Run("try unhandled", func(t *testing.T) {
+		testASTWalkErr(t, `
+			(try
+				(throw :boom)
+				(catch :type-error e 0))
+		`, "uncaught :boom")
+	})
+
+	t.Run("try finally", func(t *testing.T) {
+		testASTWalk(t, `
+			(import-go "strings")
+			(assign log (strings.Builder))
+			(try
+				(try
+					(/ 1 0)
+					(finally (.WriteString log "inner ")))
+				(catch :default e (.WriteString log "caught "))
+				(finally (.WriteString log "outer")))
+			(.String log)
+		`, object.PrimitiveOf(`"inner caught outer"`))
+	})
+
+	t.Run("try finally result", func(t *testing.T) {
+		testASTWalk(t, `
+			(try 1 (finally 2))
+		`, object.PrimitiveOf[int64](1))
+
+		testASTWalkErr(t, `
+			(try 1 (finally (/ 1 0)))
+		`, "division by zero")
+	})
+
+	t.Run("ex-info", func(t *testing.T) {
+		testASTWalk(t, `
+			(try
+				(throw (ex-info "not found" {:code 404}))
+				(catch :ex-info e
+					(array (ex-kind e) (ex-message e) (get (ex-data e) :code))))
+		`, object.NewArray(
+			object.Keyword(":ex-info"),
+			object.PrimitiveOf(`"not found"`),
+			object.PrimitiveOf[int64](404),
+		))
+	})
+
+	t.Run("ex-info as value", func(t *testing.T) {
+		data := object.NewMap()
+		_ = data.Set(object.Keyword(":a"), object.PrimitiveOf[int64](1))
+
+		testASTWalk(t, `
+			(ex-info "boom" {:a 1})
+		`, &object.Exception{Err: &object.RuntimeError{
+			Kind: object.ErrInfo,
+			Err:  &object.InfoError{Message: "boom", Data: data},
+		}})
+	})
+
+	t.Run("rethrow", func(t *testing.T) {
+		testRuntimeError(t, read(t, "(try\n"+
+			"  (throw (ex-info \"boom\"))\n"+
+			"  (catch :default e (throw e)))"), func(t *testing.T, err *object.RuntimeError) {
+			require.Equal(t, object.ErrInfo, err.Kind)
+			require.Equal(t, "boom", err.Error())
+			require.Equal(t, 1, err.Pos.From.Line, "position of the original error")
+		})
+	})
+
+	t.Run("return", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn sign [x]
+				(if (< x 0) (return -1))
+				(if (= x 0) (do (return 0) 100))
+				1)
+			(array (sign -5) (sign 0) (sign 5))
+		`, object.NewArray(
+			object.PrimitiveOf[int64](-1),
+			object.PrimitiveOf[int64](0),
+			object.PrimitiveOf[int64](1),
+		))
+	})
+
+	t.Run("return from loop", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn index-of [items x]
+				(loop [i 0]
+					(if (< i 3)
+						(do
+							(if (= (items i) x) (return i))
+							(recur (+ i 1)))))
+				-1)
+			(array (index-of [1 2 3] 2) (index-of [1 2 3] 5))
+		`, object.NewArray(
+			object.PrimitiveOf[int64](1),
+			object.PrimitiveOf[int64](-1),
+		))
+	})
+
+	t.Run("return from while", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn first-even []
+				(while true
+					(return 2))
+				:unreachable)
+			(first-even)
+		`, object.PrimitiveOf[int64](2))
+	})
+
+	t.Run("return from try", func(t *testing.T) {
+		testASTWalk(t, `
+			(import-go "strings")
+			(assign log (strings.Builder))
+			(defn f []
+				(try
+					(return :body)
+					(finally (.WriteString log "finally")))
+				:unreachable)
+			(array (f) (.String log))
+		`, object.NewArray(
+			object.Keyword(":body"),
+			object.PrimitiveOf(`"finally"`),
+		))
+	})
+
+	t.Run("return from nested fn", func(t *testing.T) {
+		testASTWalk(t, `
+			(defn outer []
+				(assign inner (fn [] (return 1) 2))
+				(+ (inner) 10))
+			(outer)
+		`, object.PrimitiveOf[int64](11))
+	})
+
+	t.Run("return without value", func(t *testing.T) {
+		testASTWalk(t, `
+			((fn [] (return) 1))
+		`, object.Null{})
+	})
+
+	t.Run("return outside of fn", func(t *testing.T) {
+		testEvalErr(t, &ast.Package{Nodes: []ast.Node{
+			&ast.Return{Value: &ast.Literal[int64]{Value: 1}},
+		}}, "return outside of fn")
+	})
 }
 
-func testASTWalk(t *testing.T, input string, want object.Object) {
-	pkg := read(t, input)
+// testRuntimeError evaluates the package by each backend and checks the resulting runtime error.
+func testRuntimeError(t *testing.T, pkg *ast.Package, check func(t *testing.T, err *object.RuntimeError)) {
+	t.Helper()
+
+	forEachBackend(t, func(t *testing.T, backend object.Eval) {
+		got, _ := eval(pkg, backend)
+
+		result, isErr := got.(*object.Error)
+		require.True(t, isErr, "want an error, got %s", got.Inspect())
 
-	got, env := eval(t, pkg)
+		var err *object.RuntimeError
+		require.ErrorAs(t, result.Err, &err)
 
-	t.Log("names", slices.Sorted(env.Names))
+		check(t, err)
+	})
+}
 
-	if err, isErr := got.(*object.Error); isErr {
-		t.Fatal("unexpected error", err.Err)
+func posRange(file string, fromLine, fromColumn, toLine, toColumn int) ast.PosRange {
+	return ast.PosRange{
+		From: tokens.Position{File: file, Line: fromLine, Column: fromColumn},
+		To:   tokens.Position{File: file, Line: toLine, Column: toColumn},
 	}
+}
+
+// readModule parses the input as a file in the directory of test modules.
+func readModule(t *testing.T, input string) *ast.Package {
+	t.Helper()
+
+	return readAs(t, filepath.Join(modulesDir(t), "main.sl"), input)
+}
+
+func modulesDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := filepath.Abs(filepath.Join("testdata", "modules"))
+	require.NoError(t, err)
+
+	return dir
+}
 
-	assertEq(t, want, got, "evaluation result")
+// geo is an example of a Go package registered by an embedder
+type geoPoint struct {
+	X, Y   int
+	hidden int
+}
+
+func (p *geoPoint) Dist() float64 {
+	return math.Hypot(float64(p.X), float64(p.Y))
+}
+
+func init() {
+	gopkg.Register("example.com/geo", gopkg.Members{
+		"Point":    reflect.TypeFor[geoPoint](),
+		"NewPoint": func(x, y int) *geoPoint { return &geoPoint{X: x, Y: y} },
+		"Points": func(n int) []geoPoint {
+			points := make([]geoPoint, n)
+			for i := range points {
+				points[i].X = i
+			}
+			return points
+		},
+	})
+
+	var counter atomic.Int64
+	gopkg.Register("example.com/counter", gopkg.Members{
+		"Next": func() int64 { return counter.Add(1) },
+	})
+}
+
+// backends must give the same results for all cases
+var backends = []struct {
+	name string
+	eval object.Eval
+}{
+	{name: "astwalk", eval: astwalk.Eval},
+	{name: "vm", eval: vm.Eval},
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, backend object.Eval)) {
+	t.Helper()
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.eval)
+		})
+	}
+}
+
+func setOf(t *testing.T, elements ...object.Object) *object.Set {
+	t.Helper()
+
+	set := object.NewSet()
+	for _, element := range elements {
+		require.NoError(t, set.Add(element))
+	}
+
+	return set
+}
+
+func testASTWalk(t *testing.T, input string, want object.Object) {
+	testEval(t, read(t, input), want)
+}
+
+func testEval(t *testing.T, pkg *ast.Package, want object.Object) {
+	forEachBackend(t, func(t *testing.T, backend object.Eval) {
+		got, env := eval(pkg, backend)
+
+		t.Log("names", slices.Sorted(env.Names))
+
+		if err, isErr := got.(*object.Error); isErr {
+			t.Fatal("unexpected error", err.Err)
+		}
+
+		assertEq(t, want, got, "evaluation result")
+	})
+}
+
+func testASTWalkErr(t *testing.T, input string, wantErr string) {
+	t.Helper()
+
+	testEvalErr(t, read(t, input), wantErr)
+}
+
+func testEvalErr(t *testing.T, pkg *ast.Package, wantErr string) {
+	t.Helper()
+
+	forEachBackend(t, func(t *testing.T, backend object.Eval) {
+		got, _ := eval(pkg, backend)
+
+		err, isErr := got.(*object.Error)
+		if !isErr {
+			t.Fatalf("want an error, got %s %s", got.Kind(), got.Inspect())
+		}
+
+		if !strings.Contains(err.Err.Error(), wantErr) {
+			t.Errorf("want error containing %q, got %q", wantErr, err.Err)
+		}
+	})
 }
 
 func assertEq(t *testing.T, want, got object.Object, msg string, args ...any) {
@@ -103,16 +1758,28 @@ func assertEq(t *testing.T, want, got object.Object, msg string, args ...any) {
 	}
 }
 
-func eval(_ *testing.T, pkg *ast.Package) (object.Object, *object.Env) {
+func eval(pkg *ast.Package, backend object.Eval) (object.Object, *object.Env) {
 	env := astwalk.DefaultEnv()
 
-	return astwalk.Eval(pkg, env), env
+	expanded, err := astwalk.Expand(pkg, env)
+	if err != nil {
+		return &object.Error{Err: err}, env
+	}
+
+	return backend(expanded, env), env
 }
 
 func read(t *testing.T, input string) *ast.Package {
 	t.Helper()
 
-	lex := lexer.NewLexer(t.Name(), strings.NewReader(input))
+	return readAs(t, t.Name(), input)
+}
+
+// readAs parses the input as a content of the file.
+func readAs(t *testing.T, file, input string) *ast.Package {
+	t.Helper()
+
+	lex := lexer.NewLexer(file, strings.NewReader(input))
 	pkg, err := parser.New(lex).Parse()
 
 	if err != nil {